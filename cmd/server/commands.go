@@ -0,0 +1,469 @@
+package main
+
+import (
+	"blog-platform/config"
+	dbmongo "blog-platform/database/mongo"
+	"blog-platform/internal/app/repositories/apikey"
+	"blog-platform/internal/app/repositories/bookmark"
+	"blog-platform/internal/app/repositories/category"
+	"blog-platform/internal/app/repositories/comment"
+	"blog-platform/internal/app/repositories/follow"
+	"blog-platform/internal/app/repositories/media"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/app/repositories/oidclogin"
+	"blog-platform/internal/app/repositories/post"
+	"blog-platform/internal/app/repositories/reaction"
+	"blog-platform/internal/app/repositories/session"
+	"blog-platform/internal/app/repositories/token"
+	"blog-platform/internal/app/repositories/user"
+	srvFeed "blog-platform/internal/app/service/feed"
+	srvImporter "blog-platform/internal/app/service/importer"
+	srvPost "blog-platform/internal/app/service/post"
+	srvSEO "blog-platform/internal/app/service/seo"
+	"blog-platform/internal/app/service/staticsite"
+	srvWeb "blog-platform/internal/app/service/web"
+	"blog-platform/internal/importer"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/render"
+	"blog-platform/internal/site"
+	"blog-platform/internal/storage"
+	"blog-platform/internal/theme"
+	"blog-platform/internal/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type command struct {
+	help string
+	run  func(cfg config.AppConfig, db *mongo.Database, args []string) error
+}
+
+var commands = map[string]command{
+	"serve":          {"start the HTTP server (default)", serve},
+	"create-admin":   {"create a user with the admin role", createAdmin},
+	"reset-password": {"set a new password for a user", resetPassword},
+	"seed":           {"insert demo users and posts, existing usernames are skipped", seed},
+	"migrate":        {"apply pending data migrations", migrate},
+	"reindex":        {"create the MongoDB indexes and rebuild the search index", reindex},
+	"export":         {"write users and posts as JSON", export},
+	"export-static":  {"write the reading site as static files, updating a previous export", exportStatic},
+	"import":         {"import posts from a WordPress, Ghost or Markdown export", importPosts},
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commands[name].help)
+	}
+}
+
+func createAdmin(_ config.AppConfig, db *mongo.Database, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	username := fs.String("username", "", "admin username (required)")
+	password := fs.String("password", "", "admin password (required)")
+	_ = fs.Parse(args)
+	if *username == "" || *password == "" {
+		fs.Usage()
+		return errors.New("username and password are required")
+	}
+
+	repo := user.New(db)
+	if _, err := repo.GetUserByUsername(*username); err == nil {
+		return fmt.Errorf("user %q already exists", *username)
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	admin := repoModels.User{
+		ID:        primitive.NewObjectID(),
+		Username:  *username,
+		Password:  *password,
+		Role:      repoModels.RoleAdmin,
+		Status:    repoModels.UserStatusActive,
+		CreatedAt: time.Now(),
+	}
+	if err := repo.CreateUser(admin); err != nil {
+		return err
+	}
+
+	fmt.Printf("created admin %s (%s)\n", admin.Username, admin.ID.Hex())
+	return nil
+}
+
+func resetPassword(_ config.AppConfig, db *mongo.Database, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	username := fs.String("username", "", "username (required)")
+	password := fs.String("password", "", "new password (required)")
+	mustChange := fs.Bool("must-change", false, "require the user to change the password on next use")
+	_ = fs.Parse(args)
+	if *username == "" || *password == "" {
+		fs.Usage()
+		return errors.New("username and password are required")
+	}
+
+	repo := user.New(db)
+	u, err := repo.GetUserByUsername(*username)
+	if err != nil {
+		return fmt.Errorf("user %q: %w", *username, err)
+	}
+
+	set := bson.M{"password": *password}
+	var unset bson.M
+	if *mustChange {
+		set["must_change_password"] = true
+	} else {
+		unset = bson.M{"must_change_password": ""}
+	}
+	if err = repo.UpdateUserFields(u.ID, set, unset); err != nil {
+		return err
+	}
+
+	fmt.Printf("password of %s reset\n", u.Username)
+	return nil
+}
+
+func seed(_ config.AppConfig, db *mongo.Database, _ []string) error {
+	users := user.New(db)
+	posts := post.New(db)
+	now := time.Now()
+	renderer := render.New()
+
+	demo := []repoModels.User{
+		{Username: "JohnDoe", Password: "password1", Role: repoModels.RoleUser},
+		{Username: "JaneDoe", Password: "password2", Role: repoModels.RoleUser},
+		{Username: "admin", Password: "password3", Role: repoModels.RoleAdmin},
+	}
+	for _, u := range demo {
+		if _, err := users.GetUserByUsername(u.Username); err == nil {
+			fmt.Printf("skipping existing user %s\n", u.Username)
+			continue
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+
+		u.ID = primitive.NewObjectID()
+		u.Status = repoModels.UserStatusActive
+		u.CreatedAt = now
+		if err := users.CreateUser(u); err != nil {
+			return err
+		}
+
+		for i := 1; i <= 3; i++ {
+			created := now.AddDate(0, 0, -i)
+			title := fmt.Sprintf("%s's post #%d", u.Username, i)
+			p := repoModels.Post{
+				ID:            primitive.NewObjectID(),
+				Title:         title,
+				Slug:          utils.Slugify(title),
+				Content:       fmt.Sprintf("Demo content number %d written by **%s**.", i, u.Username),
+				ContentFormat: render.FormatMarkdown,
+				Author:        repoModels.BasicUser{ID: u.ID, Username: u.Username},
+				CreatedAt:     created,
+				UpdatedAt:     created,
+				PublishedAt:   created,
+			}
+			if err := srvPost.RenderContent(renderer, &p); err != nil {
+				return err
+			}
+			if err := posts.CreatePost(p); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("created user %s with 3 posts\n", u.Username)
+	}
+
+	return nil
+}
+
+func migrate(_ config.AppConfig, db *mongo.Database, _ []string) error {
+	applied, err := dbmongo.Migrate(context.Background(), db, dbmongo.Migrations)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d migration(s) applied\n", len(applied))
+	return nil
+}
+
+// reindex also rebuilds the search index, the embedded one can only be opened while the server is stopped.
+func reindex(cfg config.AppConfig, db *mongo.Database, _ []string) error {
+	ctx := context.Background()
+	if err := ensureIndexes(ctx, db); err != nil {
+		return err
+	}
+
+	rendered, err := rerenderPosts(ctx, db)
+	if err != nil {
+		return fmt.Errorf("rendering posts: %w", err)
+	}
+
+	searchSrv, searchIndex, _, err := newSearchService(cfg, db)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	defer searchIndex.Close()
+	n, err := searchSrv.Rebuild(ctx)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+
+	fmt.Printf("indexes created, %d posts rendered, %d posts indexed for search\n", rendered, n)
+	return nil
+}
+
+// ensureIndexes creates the indexes of all collections, the server does so on startup as well.
+func ensureIndexes(ctx context.Context, db *mongo.Database) error {
+	if err := user.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("users: %w", err)
+	}
+	if err := post.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("posts: %w", err)
+	}
+	if err := comment.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("comments: %w", err)
+	}
+	if err := reaction.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("reactions: %w", err)
+	}
+	if err := bookmark.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("bookmarks: %w", err)
+	}
+	if err := follow.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("follows: %w", err)
+	}
+	if err := media.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("media: %w", err)
+	}
+	if err := category.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("categories: %w", err)
+	}
+	if err := token.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("user tokens: %w", err)
+	}
+	if err := apikey.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("api keys: %w", err)
+	}
+	if err := oidclogin.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("oidc logins: %w", err)
+	}
+	if err := session.New(db).EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("sessions: %w", err)
+	}
+	return nil
+}
+
+// rerenderPosts renders the content of the posts that were rendered by an older render.Version, or never.
+func rerenderPosts(ctx context.Context, db *mongo.Database) (int, error) {
+	posts := post.New(db)
+	renderer := render.New()
+	n := 0
+	err := posts.EachPost(ctx, bson.M{"render_version": bson.M{"$not": bson.M{"$gte": render.Version}}}, func(p repoModels.Post) error {
+		if p.ContentFormat == "" {
+			p.ContentFormat = render.FormatMarkdown
+		}
+		if err := srvPost.RenderContent(renderer, &p); err != nil {
+			return fmt.Errorf("post %s: %w", p.ID.Hex(), err)
+		}
+		n++
+		return posts.SetRendered(p)
+	})
+	return n, err
+}
+
+type exportData struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Users      []repoModels.User `json:"users"`
+	Posts      []repoModels.Post `json:"posts"`
+}
+
+// export writes every user and post, including soft deleted ones, passwords are never part of the output.
+func export(_ config.AppConfig, db *mongo.Database, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "-", "output file, - for stdout")
+	_ = fs.Parse(args)
+
+	ctx := context.Background()
+	data := exportData{ExportedAt: time.Now()}
+	if err := findAll(ctx, db.Collection("users"), &data.Users); err != nil {
+		return err
+	}
+	if err := findAll(ctx, db.Collection(post.CollName), &data.Posts); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+// exportStatic renders the reading site into a directory, with the theme and site configured for the server. Links
+// point at base-url, where the directory is going to be served.
+func exportStatic(cfg config.AppConfig, db *mongo.Database, args []string) error {
+	fs := flag.NewFlagSet("export-static", flag.ExitOnError)
+	out := fs.String("out", "", "output directory (required)")
+	baseURL := fs.String("base-url", cfg.BaseURL, "URL the exported site is served at")
+	full := fs.Bool("full", false, "render every page again, instead of only those of updated posts")
+	_ = fs.Parse(args)
+	if *out == "" {
+		fs.Usage()
+		return errors.New("out is required")
+	}
+
+	var store storage.Storage
+	if cfg.Storage.S3.DevServer {
+		log.Print("the development S3 server keeps media in the memory of the server, media are not exported")
+	} else {
+		var err error
+		if store, err = storage.New(cfg.Storage); err != nil {
+			return err
+		}
+	}
+	siteTheme, err := theme.New(theme.Options{Dir: cfg.Web.ThemeDir, Static: true, Site: cfg.Site})
+	if err != nil {
+		return err
+	}
+
+	// The post service only reads, it needs neither the search index nor the media service.
+	postSrv := srvPost.New(post.New(db), newCategoryService(db), nil, render.New(), nil, pagination.NewCodec(cfg.CursorSecret))
+	base := strings.TrimRight(*baseURL, "/")
+	exporter := staticsite.New(
+		post.New(db),
+		media.New(db),
+		store,
+		srvWeb.New(postSrv, nil, user.New(db), cfg.Site, cfg.Web.PageSize, base),
+		srvFeed.New(postSrv, user.New(db), cfg.Site, base),
+		srvSEO.New(post.New(db), cfg.SEO, base),
+		siteTheme,
+		cfg.Site,
+		cfg.Web.PageSize,
+		base,
+		cfg.BaseURL,
+	)
+	report, err := exporter.Export(context.Background(), *out, *full)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d files written, %d unchanged, %d removed\n", report.Written, report.Unchanged, report.Removed)
+	return nil
+}
+
+// authorMap collects the repeated -author key=username flags.
+type authorMap map[string]string
+
+func (m authorMap) String() string {
+	pairs := make([]string, 0, len(m))
+	for key, username := range m {
+		pairs = append(pairs, key+"="+username)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m authorMap) Set(value string) error {
+	key, username, ok := strings.Cut(value, "=")
+	if !ok || key == "" || username == "" {
+		return errors.New("expected key=username")
+	}
+	m[key] = username
+	return nil
+}
+
+// importPosts imports the posts of another blog, see srvImporter.Service. Running it again with the same export
+// only updates the posts that changed there.
+func importPosts(cfg config.AppConfig, db *mongo.Database, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	format := fs.String("format", "", "format of the export: wordpress, ghost or markdown (required)")
+	in := fs.String("in", "", "WXR or JSON file, or directory of Markdown files (required)")
+	authors := authorMap{}
+	fs.Var(authors, "author", "map an author of the export to a username, as key=username (repeatable)")
+	defaultAuthor := fs.String("default-author", "", "username of the author of posts that name none")
+	createAuthors := fs.Bool("create-authors", false, "create users for the authors that match no user")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without writing anything")
+	_ = fs.Parse(args)
+	if *format == "" || *in == "" {
+		fs.Usage()
+		return errors.New("format and in are required")
+	}
+
+	source, err := importer.Read(*format, *in)
+	if err != nil {
+		return err
+	}
+	var indexer srvImporter.Indexer
+	if !*dryRun {
+		searchSrv, searchIndex, _, err := newSearchService(cfg, db)
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		defer searchIndex.Close()
+		indexer = searchSrv
+	}
+
+	importSrv := srvImporter.New(user.New(db), post.New(db), indexer, render.New())
+	report, err := importSrv.Import(context.Background(), source, srvImporter.Options{
+		Authors:       authors,
+		CreateAuthors: *createAuthors,
+		DefaultAuthor: *defaultAuthor,
+		DryRun:        *dryRun,
+	})
+	for _, a := range report.Authors {
+		fmt.Printf("author %-20s %-9s %s %s\n", a.Key, a.Action, a.Username, a.Reason)
+	}
+	for _, p := range report.Posts {
+		where := p.Title
+		if p.Author != "" && p.Slug != "" {
+			where = site.PostPath(p.Author, p.Slug)
+		}
+		fmt.Printf("post   %-20s %-9s %s %s\n", p.ID, p.Action, where, p.Reason)
+	}
+	if err != nil {
+		return err
+	}
+
+	verb := "imported"
+	if report.DryRun {
+		verb = "would be imported (dry run)"
+	}
+	fmt.Printf("%s: %d created, %d updated, %d unchanged, %d skipped, %d failed\n", verb,
+		report.Count(srvImporter.ActionCreated), report.Count(srvImporter.ActionUpdated), report.Count(srvImporter.ActionUnchanged),
+		report.Count(srvImporter.ActionSkipped), report.Count(srvImporter.ActionFailed))
+	return nil
+}
+
+func findAll(ctx context.Context, coll *mongo.Collection, results interface{}) error {
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
@@ -0,0 +1,59 @@
+//go:build dev
+
+package main
+
+import (
+	"blog-platform/config"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/oidc/oidctest"
+	"blog-platform/internal/storage"
+	"blog-platform/internal/storage/s3test"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// The stand-ins for the services the server depends on are only part of builds with the dev tag:
+//
+//	go run -tags dev ./cmd/server
+
+// mountDevOIDCProvider serves the in-process OIDC provider at /dev/oidc, signing in alice (admin) and bob, and
+// points oidcCfg at it.
+func mountDevOIDCProvider(baseURL string, oidcCfg *config.OIDCConfig, server *gin.Engine) error {
+	if oidcCfg.ClientID == "" {
+		oidcCfg.ClientID, oidcCfg.ClientSecret = "blog", "dev-secret"
+	}
+	if len(oidcCfg.RoleMapping) == 0 {
+		oidcCfg.RoleMapping = map[string]string{"blog-admins": repoModels.RoleAdmin}
+	}
+
+	provider, err := oidctest.New(strings.TrimRight(baseURL, "/")+"/dev/oidc", oidcCfg.ClientID, oidcCfg.ClientSecret)
+	if err != nil {
+		return err
+	}
+	provider.AddUser(oidctest.User{Subject: "dev-alice", PreferredUsername: "alice", Email: "alice@example.com",
+		EmailVerified: true, Name: "Alice", Groups: []string{"blog-admins"}})
+	provider.AddUser(oidctest.User{Subject: "dev-bob", PreferredUsername: "bob", Email: "bob@example.com",
+		EmailVerified: true, Name: "Bob", Groups: []string{"staff"}})
+	server.Any("/dev/oidc/*path", gin.WrapH(provider.Handler()))
+	oidcCfg.IssuerURL = provider.Issuer()
+	return nil
+}
+
+// mountDevS3Server serves the in-memory S3 bucket at /dev/s3 and points storageCfg at it in place of the configured
+// bucket.
+func mountDevS3Server(baseURL string, storageCfg *config.StorageConfig, server *gin.Engine) error {
+	s3Cfg := &storageCfg.S3
+	if s3Cfg.Bucket == "" {
+		s3Cfg.Bucket = "media"
+	}
+	if s3Cfg.AccessKey == "" {
+		s3Cfg.AccessKey, s3Cfg.SecretKey = "dev", "dev-secret"
+	}
+	s3Cfg.Endpoint = strings.TrimRight(baseURL, "/") + "/dev/s3"
+	s3Cfg.PathStyle = true
+	storageCfg.Driver = "s3"
+
+	bucket := s3test.New("/dev/s3", s3Cfg.Bucket, storage.Credentials{AccessKey: s3Cfg.AccessKey, SecretKey: s3Cfg.SecretKey, Region: s3Cfg.Region})
+	server.Any("/dev/s3/*path", gin.WrapH(bucket.Handler()))
+	return nil
+}
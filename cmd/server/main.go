@@ -2,16 +2,26 @@ package main
 
 import (
 	dbmongo "blog-platform/database/mongo"
+	"blog-platform/internal/imaging"
+	"blog-platform/internal/mailer"
 	"blog-platform/internal/middleware"
+	"blog-platform/internal/pagination"
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"os"
 
 	"blog-platform/config"
 	_ "blog-platform/docs"
 	"strconv"
 )
 
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.3 init -d ../.. -g cmd/server/main.go -o ../../docs --parseInternal --parseDependency
+
 // @title Blog Platform API
 // @version 1.0
 // @description API documentation for the Blog Platform.
@@ -24,31 +34,117 @@ import (
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 
 func main() {
-	// Load configuration
-	cfg := config.Constcfg
+	// Load configuration, environment variables and .env override the defaults in config.Constcfg
+	cfg := *config.Config()
+
+	name := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
+	}
 
 	// Initialize MongoDB connection
 	dbConn := dbmongo.InitDB(cfg.DB.URI)
 
+	if err := cmd.run(cfg, dbConn, args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func serve(cfg config.AppConfig, dbConn *mongo.Database, _ []string) error {
+	if err := ensureIndexes(context.Background(), dbConn); err != nil {
+		return fmt.Errorf("indexes: %w", err)
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		return err
+	}
+
+	searchSrv, searchIndex, fresh, err := newSearchService(cfg, dbConn)
+	if err != nil {
+		return err
+	}
+	defer searchIndex.Close()
+	if fresh {
+		n, err := searchSrv.Rebuild(context.Background())
+		if err != nil {
+			return err
+		}
+		log.Printf("search index created with %d posts", n)
+	}
+
+	if cfg.CursorSecret == "" {
+		log.Print("CURSOR_SECRET is not set, pagination cursors will not survive a restart")
+	}
+	cursors := pagination.NewCodec(cfg.CursorSecret)
+
+	classifier, err := newSpamClassifier(dbConn)
+	if err != nil {
+		return err
+	}
+
 	// Create a new Gin router
 	server := gin.Default()
 
-	router := server.RouterGroup
-	// Swagger documentation endpoint
-	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	store, err := newStorage(cfg, server)
+	if err != nil {
+		return err
+	}
+	if cfg.Storage.URLSecret == "" {
+		log.Print("MEDIA_URL_SECRET is not set, signed download links will not survive a restart")
+	}
+	for _, format := range cfg.Images.Formats {
+		if !imaging.IsValidFormat(format) {
+			return fmt.Errorf("unknown image format %q in MEDIA_IMAGE_FORMATS", format)
+		}
+	}
+	mediaSrv, imageProcessor := newMediaService(cfg, dbConn, store, cursors)
+	imageProcessor.Start(context.Background())
 
-	// Apply authentication middleware
-	server.Use(middleware.AuthMiddleware(dbConn))
+	// Swagger documentation endpoint
+	server.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Define API routes
-	v1 := router.Group("/api/v1")
-	// Setup API routes for users and posts
-	setupV1UserRoutes(dbConn, v1)
-	setupV1PostRoutes(dbConn, v1)
+	// Define API routes, everything but the public routes goes through the authentication middleware
+	v1 := server.Group("/api/v1")
+	authed := v1.Group("", middleware.AuthMiddleware(dbConn, cfg.Session))
+	// Setup API routes for users, posts and administration
+	setupV1UserRoutes(cfg, dbConn, mail, searchSrv, cursors, v1, authed)
+	setupV1AuthRoutes(cfg, dbConn, mail, v1, authed)
+	setupV1SessionRoutes(cfg, dbConn, v1, authed)
+	if err = setupV1OIDCRoutes(cfg, dbConn, server, v1); err != nil {
+		return err
+	}
+	setupV1TwoFactorRoutes(dbConn, authed)
+	setupV1APIKeyRoutes(dbConn, authed)
+	postSrv := newPostService(dbConn, searchSrv, mediaSrv, cursors)
+	setupV1PostRoutes(postSrv, authed)
+	setupV1MediaRoutes(mediaSrv, v1, authed)
+	setupV1CommentRoutes(dbConn, classifier, cursors, authed)
+	setupV1ReactionRoutes(dbConn, cursors, authed)
+	setupV1FollowRoutes(dbConn, cursors, authed)
+	setupV1SearchRoutes(searchSrv, authed)
+	setupV1CategoryRoutes(dbConn, authed)
+	setupV1TagRoutes(dbConn, searchSrv, authed)
+	setupV1AdminRoutes(dbConn, searchSrv, cursors, authed)
+	setupFeedRoutes(cfg, dbConn, postSrv, server)
+	setupSEORoutes(cfg, dbConn, server)
+	if err = setupWebRoutes(cfg, dbConn, postSrv, searchSrv, server); err != nil {
+		return err
+	}
 
 	// Start the server
-	err := server.Run(":" + strconv.Itoa(int(cfg.App.Port)))
-	if err != nil {
-		return
-	}
+	return server.Run(":" + strconv.Itoa(int(cfg.App.Port)))
 }
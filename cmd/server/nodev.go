@@ -0,0 +1,20 @@
+//go:build !dev
+
+package main
+
+import (
+	"blog-platform/config"
+	"errors"
+	"github.com/gin-gonic/gin"
+)
+
+// errNotDevBuild is returned when a development stand-in is configured on a server built without the dev tag.
+var errNotDevBuild = errors.New("the development stand-ins are only part of builds with the dev tag, run go run -tags dev ./cmd/server")
+
+func mountDevOIDCProvider(string, *config.OIDCConfig, *gin.Engine) error {
+	return errNotDevBuild
+}
+
+func mountDevS3Server(string, *config.StorageConfig, *gin.Engine) error {
+	return errNotDevBuild
+}
@@ -1,30 +1,203 @@
 package main
 
 import (
+	"blog-platform/config"
+	ctrlAdmin "blog-platform/internal/app/controller/admin"
+	ctrlAPIKey "blog-platform/internal/app/controller/apikey"
+	ctrlAuth "blog-platform/internal/app/controller/auth"
+	ctrlBookmark "blog-platform/internal/app/controller/bookmark"
+	ctrlCategory "blog-platform/internal/app/controller/category"
+	ctrlComment "blog-platform/internal/app/controller/comment"
+	ctrlFeed "blog-platform/internal/app/controller/feed"
+	ctrlFollow "blog-platform/internal/app/controller/follow"
+	ctrlMedia "blog-platform/internal/app/controller/media"
+	ctrlOIDC "blog-platform/internal/app/controller/oidc"
 	ctrlPost "blog-platform/internal/app/controller/post"
+	ctrlReaction "blog-platform/internal/app/controller/reaction"
+	ctrlSearch "blog-platform/internal/app/controller/search"
+	ctrlSEO "blog-platform/internal/app/controller/seo"
+	ctrlSession "blog-platform/internal/app/controller/session"
+	ctrlTag "blog-platform/internal/app/controller/tag"
+	ctrlTwoFactor "blog-platform/internal/app/controller/twofactor"
 	ctrlUser "blog-platform/internal/app/controller/user"
+	ctrlWeb "blog-platform/internal/app/controller/web"
+	"blog-platform/internal/app/repositories/apikey"
+	"blog-platform/internal/app/repositories/bookmark"
+	"blog-platform/internal/app/repositories/category"
+	"blog-platform/internal/app/repositories/comment"
+	"blog-platform/internal/app/repositories/follow"
+	"blog-platform/internal/app/repositories/media"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/app/repositories/oidclogin"
 	"blog-platform/internal/app/repositories/post"
+	"blog-platform/internal/app/repositories/reaction"
+	"blog-platform/internal/app/repositories/session"
+	"blog-platform/internal/app/repositories/settings"
+	"blog-platform/internal/app/repositories/spamtoken"
+	"blog-platform/internal/app/repositories/token"
 	"blog-platform/internal/app/repositories/user"
+	srvAPIKey "blog-platform/internal/app/service/apikey"
+	srvAuth "blog-platform/internal/app/service/auth"
+	srvBookmark "blog-platform/internal/app/service/bookmark"
+	srvCategory "blog-platform/internal/app/service/category"
+	srvComment "blog-platform/internal/app/service/comment"
+	srvFeed "blog-platform/internal/app/service/feed"
+	srvFollow "blog-platform/internal/app/service/follow"
+	srvMedia "blog-platform/internal/app/service/media"
+	srvOIDC "blog-platform/internal/app/service/oidc"
 	srvPost "blog-platform/internal/app/service/post"
+	srvReaction "blog-platform/internal/app/service/reaction"
+	srvSearch "blog-platform/internal/app/service/search"
+	srvSEO "blog-platform/internal/app/service/seo"
+	srvSession "blog-platform/internal/app/service/session"
+	srvTag "blog-platform/internal/app/service/tag"
+	srvTwoFactor "blog-platform/internal/app/service/twofactor"
 	srvUser "blog-platform/internal/app/service/user"
+	srvWeb "blog-platform/internal/app/service/web"
+	"blog-platform/internal/mailer"
+	"blog-platform/internal/middleware"
+	"blog-platform/internal/oidc"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/render"
+	"blog-platform/internal/search"
+	"blog-platform/internal/search/blevesearch"
+	"blog-platform/internal/search/mongosearch"
+	"blog-platform/internal/site"
+	"blog-platform/internal/spam"
+	"blog-platform/internal/storage"
+	"blog-platform/internal/theme"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
+	"log"
 )
 
-func setupV1UserRoutes(db *mongo.Database, routerGroup *gin.RouterGroup) {
-	userCtrl := ctrlUser.New(srvUser.New(user.New(db)))
-	userGroup := routerGroup.Group("/user")
+func newAuthService(cfg config.AppConfig, db *mongo.Database, mail mailer.Mailer) *srvAuth.Service {
+	return srvAuth.New(user.New(db), token.New(db), session.New(db), mail, cfg.BaseURL, cfg.PasswordResetURL)
+}
+
+// newSearchService opens the configured search backend, fresh is set when the index was just created and is
+// still empty. The caller closes the returned index.
+func newSearchService(cfg config.AppConfig, db *mongo.Database) (service *srvSearch.Service, index search.Index, fresh bool, err error) {
+	boosts := search.Boosts{Title: cfg.Search.TitleBoost, Tags: cfg.Search.TagBoost}
+	switch cfg.Search.Backend {
+	case "mongo":
+		mongoIndex := mongosearch.New(db, boosts)
+		if err = mongoIndex.EnsureIndexes(context.Background()); err != nil {
+			log.Printf("creating the text index failed, run the reindex command: %v", err)
+		}
+		return srvSearch.New(mongoIndex, post.New(db)), mongoIndex, false, nil
+	case "bleve":
+		bleveIndex, err := blevesearch.Open(cfg.Search.IndexPath, boosts)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		return srvSearch.New(bleveIndex, post.New(db)), bleveIndex, bleveIndex.Created, nil
+	default:
+		return nil, nil, false, fmt.Errorf("unknown search backend %q", cfg.Search.Backend)
+	}
+}
+
+func newSessionService(cfg config.AppConfig, db *mongo.Database) *srvSession.Service {
+	return srvSession.New(session.New(db), user.New(db), srvTwoFactor.New(user.New(db), settings.New(db), session.New(db)), cfg.Session)
+}
+
+func newUserService(db *mongo.Database, searchSrv *srvSearch.Service, cursors *pagination.Codec) *srvUser.Service {
+	return srvUser.New(user.New(db), session.New(db), post.New(db), comment.New(db), searchSrv, cursors)
+}
+
+// setupV1UserRoutes registers the user routes, public is reachable without credentials while authed runs AuthMiddleware.
+func setupV1UserRoutes(cfg config.AppConfig, db *mongo.Database, mail mailer.Mailer, searchSrv *srvSearch.Service, cursors *pagination.Codec, public, authed *gin.RouterGroup) {
+	userCtrl := ctrlUser.New(newUserService(db, searchSrv, cursors), newAuthService(cfg, db, mail))
+	public.POST("/user", userCtrl.CreateUser)
+
+	userGroup := authed.Group("/user", middleware.RejectAPIKeys())
 	{
-		userGroup.POST("", userCtrl.CreateUser)
 		userGroup.GET("", userCtrl.GetUsers)
+		userGroup.PUT("/password", userCtrl.ChangePassword)
 		userGroup.GET("/:id", userCtrl.GetUser)
 		userGroup.PUT("/:id", userCtrl.UpdateUser)
 		userGroup.DELETE("/:id", userCtrl.DeleteUser)
 	}
 }
-func setupV1PostRoutes(db *mongo.Database, routerGroup *gin.RouterGroup) {
-	postController := ctrlPost.New(srvPost.New(post.New(db)))
-	postGroup := routerGroup.Group("/posts")
+
+func setupV1AuthRoutes(cfg config.AppConfig, db *mongo.Database, mail mailer.Mailer, public, authed *gin.RouterGroup) {
+	authCtrl := ctrlAuth.New(newAuthService(cfg, db, mail))
+	authGroup := public.Group("/auth")
+	{
+		authGroup.POST("/password/forgot", authCtrl.ForgotPassword)
+		authGroup.POST("/password/reset", authCtrl.ResetPassword)
+		authGroup.GET("/email/verify", authCtrl.VerifyEmail)
+		authGroup.POST("/email/verify", authCtrl.VerifyEmail)
+	}
+	authed.POST("/auth/email/resend", middleware.RejectAPIKeys(), authCtrl.ResendEmailVerification)
+}
+
+// setupV1OIDCRoutes registers single sign-on when enabled, with cfg.OIDC.DevProvider the in-process provider is
+// mounted on server at /dev/oidc and used in place of the configured one, which needs a build with the dev tag.
+func setupV1OIDCRoutes(cfg config.AppConfig, db *mongo.Database, server *gin.Engine, public *gin.RouterGroup) error {
+	oidcCfg := cfg.OIDC
+	if !oidcCfg.Enabled && !oidcCfg.DevProvider {
+		return nil
+	}
+
+	if oidcCfg.DevProvider {
+		if cfg.App.Env == "production" {
+			return errors.New("the development OIDC provider can not be used in production")
+		}
+		if err := mountDevOIDCProvider(cfg.BaseURL, &oidcCfg, server); err != nil {
+			return err
+		}
+	}
+
+	client := oidc.NewClient(oidc.Config{
+		IssuerURL:    oidcCfg.IssuerURL,
+		ClientID:     oidcCfg.ClientID,
+		ClientSecret: oidcCfg.ClientSecret,
+		RedirectURL:  oidcCfg.RedirectURL,
+	}, oidcCfg.GroupsClaim, nil)
+	oidcCtrl := ctrlOIDC.New(srvOIDC.New(client, user.New(db), oidclogin.New(db), newSessionService(cfg, db), oidcCfg.RoleMapping), cfg.Session)
+
+	public.GET("/auth/oidc/login", oidcCtrl.Login)
+	public.GET("/auth/oidc/callback", oidcCtrl.Callback)
+	return nil
+}
+
+// setupV1SessionRoutes registers cookie sign-in for browsers and the session management of the signed in user.
+func setupV1SessionRoutes(cfg config.AppConfig, db *mongo.Database, public, authed *gin.RouterGroup) {
+	sessionCtrl := ctrlSession.New(newSessionService(cfg, db), cfg.Session)
+	public.POST("/auth/login", sessionCtrl.Login)
+	authed.POST("/auth/logout", sessionCtrl.Logout)
+	authed.GET("/auth/session", sessionCtrl.GetSession)
+
+	sessionGroup := authed.Group("/user/sessions", middleware.RejectAPIKeys())
+	{
+		sessionGroup.GET("", sessionCtrl.GetSessions)
+		sessionGroup.DELETE("", sessionCtrl.RevokeOtherSessions)
+		sessionGroup.DELETE("/:id", sessionCtrl.RevokeSession)
+	}
+}
+
+func newCategoryService(db *mongo.Database) *srvCategory.Service {
+	return srvCategory.New(category.New(db), post.New(db))
+}
+
+// adminOnly guards administration routes registered outside of setupV1AdminRoutes.
+func adminOnly() []gin.HandlerFunc {
+	return []gin.HandlerFunc{middleware.RequireScope(repoModels.ScopeUsersAdmin), middleware.RequireRole(repoModels.RoleAdmin)}
+}
+
+func newPostService(db *mongo.Database, searchSrv *srvSearch.Service, mediaSrv *srvMedia.Service, cursors *pagination.Codec) *srvPost.Service {
+	return srvPost.New(post.New(db), newCategoryService(db), searchSrv, render.New(), mediaSrv, cursors)
+}
+
+func setupV1PostRoutes(postSrv *srvPost.Service, routerGroup *gin.RouterGroup) {
+	postController := ctrlPost.New(postSrv)
+	routerGroup.GET("/authors/:username/posts/:slug", middleware.RequireScope(repoModels.ScopePostsRead), postController.GetPostBySlug)
+
+	postGroup := routerGroup.Group("/posts", middleware.RequireScopeByMethod(repoModels.ScopePostsRead, repoModels.ScopePostsWrite))
 	{
 		postGroup.POST("", postController.CreatePost)
 		postGroup.GET("", postController.GetPosts)
@@ -33,3 +206,224 @@ func setupV1PostRoutes(db *mongo.Database, routerGroup *gin.RouterGroup) {
 		postGroup.DELETE("/:id", postController.DeletePost)
 	}
 }
+
+// newStorage opens the configured blob storage, with cfg.Storage.S3.DevServer the in-memory stand-in is mounted
+// on server at /dev/s3 and used in place of the configured bucket.
+func newStorage(cfg config.AppConfig, server *gin.Engine) (storage.Storage, error) {
+	storageCfg := cfg.Storage
+	if storageCfg.S3.DevServer {
+		if cfg.App.Env == "production" {
+			return nil, errors.New("the development S3 server can not be used in production")
+		}
+		if err := mountDevS3Server(cfg.BaseURL, &storageCfg, server); err != nil {
+			return nil, err
+		}
+	}
+	return storage.New(storageCfg)
+}
+
+// newMediaService creates the media service with the image processing, the caller starts the processor.
+func newMediaService(cfg config.AppConfig, db *mongo.Database, store storage.Storage, cursors *pagination.Codec) (*srvMedia.Service, *srvMedia.Processor) {
+	processor := srvMedia.NewProcessor(media.New(db), store, cfg.Images)
+	return srvMedia.New(media.New(db), store, processor, cfg.Storage, cfg.BaseURL, cursors), processor
+}
+
+// setupV1MediaRoutes registers uploads, covered by the post scopes, and the public download of the files.
+func setupV1MediaRoutes(mediaSrv *srvMedia.Service, public, authed *gin.RouterGroup) {
+	mediaCtrl := ctrlMedia.New(mediaSrv)
+	public.GET("/media/:id/file", mediaCtrl.Download)
+	public.GET("/media/:id/file/:variant", mediaCtrl.DownloadVariant)
+
+	mediaGroup := authed.Group("/media", middleware.RequireScopeByMethod(repoModels.ScopePostsRead, repoModels.ScopePostsWrite))
+	{
+		mediaGroup.POST("", mediaCtrl.Upload)
+		mediaGroup.GET("", mediaCtrl.ListMedia)
+		mediaGroup.GET("/:id", mediaCtrl.GetMedia)
+		mediaGroup.DELETE("/:id", mediaCtrl.DeleteMedia)
+	}
+}
+
+// setupFeedRoutes registers the RSS, Atom and JSON feeds of the published posts at /feeds, outside of the API as
+// feed readers can not authenticate.
+func setupFeedRoutes(cfg config.AppConfig, db *mongo.Database, postSrv *srvPost.Service, server *gin.Engine) {
+	feedController := ctrlFeed.New(srvFeed.New(postSrv, user.New(db), cfg.Site, cfg.BaseURL))
+	feedGroup := server.Group("/feeds")
+	{
+		feedGroup.GET("/:file", feedController.Posts)
+		feedGroup.GET("/authors/:username/:file", feedController.AuthorPosts)
+		feedGroup.GET("/tags/:tag/:file", feedController.TagPosts)
+	}
+}
+
+// setupSEORoutes registers robots.txt and the sitemaps at the root of the server, where crawlers look for them.
+func setupSEORoutes(cfg config.AppConfig, db *mongo.Database, server *gin.Engine) {
+	seoController := ctrlSEO.New(srvSEO.New(post.New(db), cfg.SEO, cfg.BaseURL))
+	server.GET("/robots.txt", seoController.Robots)
+	server.GET(site.SitemapIndexPath, seoController.SitemapIndex)
+	server.GET("/sitemaps/:file", seoController.Sitemap)
+}
+
+// setupWebRoutes registers the pages of the reading site at the root of the server, when it is enabled.
+func setupWebRoutes(cfg config.AppConfig, db *mongo.Database, postSrv *srvPost.Service, searchSrv *srvSearch.Service, server *gin.Engine) error {
+	if !cfg.Web.Enabled {
+		return nil
+	}
+	siteTheme, err := theme.New(theme.Options{Dir: cfg.Web.ThemeDir, Reload: cfg.Web.ReloadTemplates, Site: cfg.Site})
+	if err != nil {
+		return err
+	}
+	webController := ctrlWeb.New(srvWeb.New(postSrv, searchSrv, user.New(db), cfg.Site, cfg.Web.PageSize, cfg.BaseURL), siteTheme)
+	server.GET(site.HomePath, webController.Home)
+	server.GET(site.SearchPath, webController.Search)
+	server.GET("/authors/:username", webController.Author)
+	server.GET("/authors/:username/:slug", webController.Post)
+	server.GET("/tags/:tag", webController.Tag)
+	server.GET("/theme/*filepath", webController.Asset)
+	return nil
+}
+
+// newSpamClassifier loads the spam classifier trained by the moderation decisions so far.
+func newSpamClassifier(db *mongo.Database) (spam.Classifier, error) {
+	return spam.NewBayes(context.Background(), spamtoken.New(db))
+}
+
+// setupV1CommentRoutes registers the comment threads of posts and their moderation, comments are covered by
+// the post scopes.
+func setupV1CommentRoutes(db *mongo.Database, classifier spam.Classifier, cursors *pagination.Codec, routerGroup *gin.RouterGroup) {
+	commentSrv := srvComment.New(comment.New(db), post.New(db), user.New(db), settings.New(db), classifier, cursors)
+	commentCtrl := ctrlComment.New(commentSrv)
+	postScopes := middleware.RequireScopeByMethod(repoModels.ScopePostsRead, repoModels.ScopePostsWrite)
+
+	threadGroup := routerGroup.Group("/posts/:id/comments", postScopes)
+	{
+		threadGroup.GET("", commentCtrl.GetComments)
+		threadGroup.POST("", commentCtrl.CreateComment)
+		threadGroup.PUT("/closed", commentCtrl.SetCommentsClosed)
+	}
+
+	commentGroup := routerGroup.Group("/comments", postScopes)
+	{
+		commentGroup.PUT("/:id", commentCtrl.UpdateComment)
+		commentGroup.DELETE("/:id", commentCtrl.DeleteComment)
+	}
+
+	moderationGroup := routerGroup.Group("/moderation/comments", middleware.RequireScope(repoModels.ScopePostsWrite))
+	{
+		moderationGroup.GET("", commentCtrl.ModerationQueue)
+		moderationGroup.POST("/:id/:decision", commentCtrl.ModerateComment)
+	}
+
+	adminGroup := routerGroup.Group("/admin", adminOnly()...)
+	{
+		adminGroup.GET("/settings/comments", commentCtrl.GetModerationSettings)
+		adminGroup.PUT("/settings/comments", commentCtrl.SetModerationSettings)
+		adminGroup.POST("/users/:id/shadow-hide", commentCtrl.ShadowHide)
+		adminGroup.POST("/users/:id/shadow-unhide", commentCtrl.ShadowUnhide)
+	}
+}
+
+// setupV1ReactionRoutes registers reactions and bookmarks, they are covered by the post scopes.
+func setupV1ReactionRoutes(db *mongo.Database, cursors *pagination.Codec, routerGroup *gin.RouterGroup) {
+	reactionCtrl := ctrlReaction.New(srvReaction.New(reaction.New(db), post.New(db), settings.New(db)))
+	bookmarkCtrl := ctrlBookmark.New(srvBookmark.New(bookmark.New(db), post.New(db), cursors))
+	postScopes := middleware.RequireScopeByMethod(repoModels.ScopePostsRead, repoModels.ScopePostsWrite)
+
+	postGroup := routerGroup.Group("/posts/:id", postScopes)
+	{
+		postGroup.GET("/reactions", reactionCtrl.GetReactions)
+		postGroup.PUT("/reactions/:emoji", reactionCtrl.React)
+		postGroup.DELETE("/reactions/:emoji", reactionCtrl.Unreact)
+		postGroup.PUT("/bookmark", bookmarkCtrl.Bookmark)
+		postGroup.DELETE("/bookmark", bookmarkCtrl.Unbookmark)
+	}
+	routerGroup.GET("/user/bookmarks", middleware.RequireScope(repoModels.ScopePostsRead), bookmarkCtrl.GetBookmarks)
+
+	adminGroup := routerGroup.Group("/admin/settings", adminOnly()...)
+	{
+		adminGroup.GET("/reactions", reactionCtrl.GetReactionSettings)
+		adminGroup.PUT("/reactions", reactionCtrl.SetReactionSettings)
+	}
+}
+
+// setupV1FollowRoutes registers following authors and the feed of the posts of followed authors.
+func setupV1FollowRoutes(db *mongo.Database, cursors *pagination.Codec, routerGroup *gin.RouterGroup) {
+	followCtrl := ctrlFollow.New(srvFollow.New(follow.New(db), user.New(db), post.New(db), cursors))
+	followGroup := routerGroup.Group("/user/:id", middleware.RejectAPIKeys())
+	{
+		followGroup.PUT("/follow", followCtrl.Follow)
+		followGroup.DELETE("/follow", followCtrl.Unfollow)
+		followGroup.GET("/followers", followCtrl.GetFollowers)
+		followGroup.GET("/following", followCtrl.GetFollowing)
+	}
+	routerGroup.GET("/feed", middleware.RequireScope(repoModels.ScopePostsRead), followCtrl.GetFeed)
+}
+
+func setupV1CategoryRoutes(db *mongo.Database, routerGroup *gin.RouterGroup) {
+	categoryCtrl := ctrlCategory.New(newCategoryService(db))
+	categoryGroup := routerGroup.Group("/categories", middleware.RequireScope(repoModels.ScopePostsRead))
+	{
+		categoryGroup.GET("", categoryCtrl.GetCategories)
+		categoryGroup.GET("/:ref", categoryCtrl.GetCategory)
+	}
+
+	adminGroup := routerGroup.Group("/admin/categories", adminOnly()...)
+	{
+		adminGroup.POST("", categoryCtrl.CreateCategory)
+		adminGroup.PUT("/:id", categoryCtrl.UpdateCategory)
+		adminGroup.DELETE("/:id", categoryCtrl.DeleteCategory)
+	}
+}
+
+func setupV1SearchRoutes(searchSrv *srvSearch.Service, routerGroup *gin.RouterGroup) {
+	searchCtrl := ctrlSearch.New(searchSrv)
+	routerGroup.GET("/search", middleware.RequireScope(repoModels.ScopePostsRead), searchCtrl.Search)
+}
+
+func setupV1TagRoutes(db *mongo.Database, searchSrv *srvSearch.Service, routerGroup *gin.RouterGroup) {
+	tagCtrl := ctrlTag.New(srvTag.New(post.New(db), searchSrv))
+	routerGroup.GET("/tags", middleware.RequireScope(repoModels.ScopePostsRead), tagCtrl.GetTags)
+
+	adminGroup := routerGroup.Group("/admin/tags", adminOnly()...)
+	{
+		adminGroup.POST("/merge", tagCtrl.MergeTags)
+		adminGroup.PUT("/:tag", tagCtrl.RenameTag)
+	}
+}
+
+func setupV1TwoFactorRoutes(db *mongo.Database, routerGroup *gin.RouterGroup) {
+	twoFactorCtrl := ctrlTwoFactor.New(srvTwoFactor.New(user.New(db), settings.New(db), session.New(db)))
+	twoFactorGroup := routerGroup.Group("/user/2fa", middleware.RejectAPIKeys())
+	{
+		twoFactorGroup.POST("/enroll", twoFactorCtrl.Enroll)
+		twoFactorGroup.GET("/qr.png", twoFactorCtrl.QRCode)
+		twoFactorGroup.POST("/confirm", twoFactorCtrl.Confirm)
+		twoFactorGroup.POST("/recovery-codes", twoFactorCtrl.RegenerateRecoveryCodes)
+		twoFactorGroup.DELETE("", twoFactorCtrl.Disable)
+	}
+}
+
+func setupV1APIKeyRoutes(db *mongo.Database, routerGroup *gin.RouterGroup) {
+	apiKeyCtrl := ctrlAPIKey.New(srvAPIKey.New(apikey.New(db), user.New(db)))
+	apiKeyGroup := routerGroup.Group("/user/api-keys", middleware.RejectAPIKeys())
+	{
+		apiKeyGroup.POST("", apiKeyCtrl.CreateAPIKey)
+		apiKeyGroup.GET("", apiKeyCtrl.GetAPIKeys)
+		apiKeyGroup.DELETE("/:id", apiKeyCtrl.RevokeAPIKey)
+	}
+}
+
+func setupV1AdminRoutes(db *mongo.Database, searchSrv *srvSearch.Service, cursors *pagination.Codec, routerGroup *gin.RouterGroup) {
+	adminCtrl := ctrlAdmin.New(newUserService(db, searchSrv, cursors), srvTwoFactor.New(user.New(db), settings.New(db), session.New(db)))
+	adminGroup := routerGroup.Group("/admin", adminOnly()...)
+	{
+		adminGroup.GET("/users", adminCtrl.ListUsers)
+		adminGroup.PUT("/users/:id/role", adminCtrl.SetRole)
+		adminGroup.POST("/users/:id/suspend", adminCtrl.Suspend)
+		adminGroup.POST("/users/:id/unsuspend", adminCtrl.Unsuspend)
+		adminGroup.POST("/users/:id/ban", adminCtrl.Ban)
+		adminGroup.POST("/users/:id/password-reset", adminCtrl.ForcePasswordReset)
+		adminGroup.DELETE("/users/:id/2fa", adminCtrl.ResetTwoFactor)
+		adminGroup.GET("/settings/2fa", adminCtrl.GetTwoFactorPolicy)
+		adminGroup.PUT("/settings/2fa", adminCtrl.SetTwoFactorPolicy)
+	}
+}
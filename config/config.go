@@ -1,11 +1,19 @@
 package config
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 const (
 	defaultMongoDBURI = "mongodb://localhost:27017"
+	defaultBaseURL    = "http://localhost:8080"
+	defaultMailDriver = "file"
+	defaultMailFrom   = "Blog Platform <no-reply@localhost>"
+	defaultOutboxDir  = "outbox"
 )
 
 type AppConfig struct {
@@ -17,6 +25,133 @@ type AppConfig struct {
 	DB struct {
 		URI string
 	}
+
+	// BaseURL is the public address of the server, used to build links sent to users.
+	BaseURL string
+	// PasswordResetURL is the page of the front end that lets users pick a new password, reset mails link to it
+	// with the token as the token query parameter. It defaults to /reset-password below BaseURL.
+	PasswordResetURL string
+
+	Mail MailConfig
+
+	OIDC OIDCConfig
+
+	Session SessionConfig
+
+	Search SearchConfig
+
+	// CursorSecret signs the cursors of paginated listings, a random one is used when it is empty.
+	CursorSecret string
+
+	Storage StorageConfig
+	Images  ImageConfig
+	Site    SiteConfig
+	SEO     SEOConfig
+	Web     WebConfig
+}
+
+// WebConfig controls the public reading site, the HTML pages served next to the API when Enabled. Templates
+// and assets in ThemeDir override the embedded default theme file by file. With ReloadTemplates the theme is
+// read again on every page, for working on it without restarts. PageSize is the number of posts per page.
+type WebConfig struct {
+	Enabled         bool
+	ThemeDir        string
+	ReloadTemplates bool
+	PageSize        int
+}
+
+// SiteConfig describes the blog to readers, in feeds and pages.
+type SiteConfig struct {
+	Title       string
+	Description string
+}
+
+// SEOConfig controls what search engines are told. Without AllowIndexing robots.txt disallows every page, for
+// staging servers, otherwise it disallows the RobotsDisallow paths and points at the sitemap. Sitemaps list
+// SitemapPageSize pages each.
+type SEOConfig struct {
+	AllowIndexing   bool
+	RobotsDisallow  []string
+	SitemapPageSize int
+}
+
+// StorageConfig selects where uploaded media is stored, Driver is local (files below LocalDir) or s3 (a bucket
+// of an S3 compatible service). MaxUploadSize is in bytes. URLSecret signs download links, which stay valid for
+// URLTTL, a random secret is used when it is empty. With S3.DevServer a server built with the dev tag mounts an
+// in-memory stand-in at /dev/s3 and uses it as the bucket, never enable it in production.
+type StorageConfig struct {
+	Driver        string
+	LocalDir      string
+	MaxUploadSize int64
+	URLSecret     string
+	URLTTL        time.Duration
+	S3            S3Config
+}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	DevServer bool
+}
+
+// ImageConfig controls the processing of uploaded images: every image is resized to each of Widths that is
+// smaller than the image itself and encoded in each of Formats, webp or jpeg. Workers images are processed at
+// a time, images with more than MaxPixels pixels are rejected.
+type ImageConfig struct {
+	Widths      []int
+	Formats     []string
+	JPEGQuality int
+	Workers     int
+	MaxPixels   int
+}
+
+// SearchConfig selects the full-text search backend, mongo (a text index on the posts) or bleve (an embedded
+// index stored at IndexPath). The boosts weigh title and tag matches against content matches.
+type SearchConfig struct {
+	Backend    string
+	IndexPath  string
+	TitleBoost float64
+	TagBoost   float64
+}
+
+// SessionConfig controls browser sessions, SameSite is one of strict, lax or none.
+type SessionConfig struct {
+	CookieName      string
+	IdleTimeout     time.Duration
+	AbsoluteTimeout time.Duration
+	Secure          bool
+	SameSite        string
+}
+
+// OIDCConfig enables single sign-on, RoleMapping maps identity provider groups to blog roles.
+// With DevProvider the server mounts an in-process provider at /dev/oidc, which is only part of builds with the
+// dev tag, never enable it in production.
+type OIDCConfig struct {
+	Enabled      bool
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	GroupsClaim  string
+	RoleMapping  map[string]string
+	DevProvider  bool
+}
+
+// MailConfig selects the mailer, Driver is one of smtp, file or memory.
+type MailConfig struct {
+	Driver    string
+	From      string
+	OutboxDir string
+	SMTP      struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+	}
 }
 
 var cfg *AppConfig
@@ -51,10 +186,166 @@ func loadConfig() {
 
 	//db
 	cfg.DB.URI = viper.GetString("DB_URI")
+
+	cfg.BaseURL = viper.GetString("BASE_URL")
+	cfg.PasswordResetURL = viper.GetString("PASSWORD_RESET_URL")
+	if cfg.PasswordResetURL == "" {
+		cfg.PasswordResetURL = strings.TrimRight(cfg.BaseURL, "/") + "/reset-password"
+	}
+
+	// Mail.
+	cfg.Mail.Driver = viper.GetString("MAIL_DRIVER")
+	cfg.Mail.From = viper.GetString("MAIL_FROM")
+	cfg.Mail.OutboxDir = viper.GetString("MAIL_OUTBOX_DIR")
+	cfg.Mail.SMTP.Host = viper.GetString("SMTP_HOST")
+	cfg.Mail.SMTP.Port = viper.GetInt("SMTP_PORT")
+	cfg.Mail.SMTP.Username = viper.GetString("SMTP_USERNAME")
+	cfg.Mail.SMTP.Password = viper.GetString("SMTP_PASSWORD")
+
+	// OIDC.
+	cfg.OIDC.Enabled = viper.GetBool("OIDC_ENABLED")
+	cfg.OIDC.IssuerURL = viper.GetString("OIDC_ISSUER_URL")
+	cfg.OIDC.ClientID = viper.GetString("OIDC_CLIENT_ID")
+	cfg.OIDC.ClientSecret = viper.GetString("OIDC_CLIENT_SECRET")
+	cfg.OIDC.RedirectURL = viper.GetString("OIDC_REDIRECT_URL")
+	if cfg.OIDC.RedirectURL == "" {
+		cfg.OIDC.RedirectURL = strings.TrimRight(cfg.BaseURL, "/") + "/api/v1/auth/oidc/callback"
+	}
+	cfg.OIDC.GroupsClaim = viper.GetString("OIDC_GROUPS_CLAIM")
+	cfg.OIDC.RoleMapping = parseMapping(viper.GetString("OIDC_ROLE_MAPPING"))
+	cfg.OIDC.DevProvider = viper.GetBool("OIDC_DEV_PROVIDER")
+
+	// Sessions.
+	cfg.Session.CookieName = viper.GetString("SESSION_COOKIE_NAME")
+	cfg.Session.IdleTimeout = viper.GetDuration("SESSION_IDLE_TIMEOUT")
+	cfg.Session.AbsoluteTimeout = viper.GetDuration("SESSION_ABSOLUTE_TIMEOUT")
+	cfg.Session.Secure = viper.GetBool("SESSION_COOKIE_SECURE")
+	cfg.Session.SameSite = viper.GetString("SESSION_COOKIE_SAMESITE")
+
+	// Search.
+	cfg.Search.Backend = viper.GetString("SEARCH_BACKEND")
+	cfg.Search.IndexPath = viper.GetString("SEARCH_INDEX_PATH")
+	cfg.Search.TitleBoost = viper.GetFloat64("SEARCH_TITLE_BOOST")
+	cfg.Search.TagBoost = viper.GetFloat64("SEARCH_TAG_BOOST")
+
+	cfg.CursorSecret = viper.GetString("CURSOR_SECRET")
+
+	// Media storage.
+	cfg.Storage.Driver = viper.GetString("STORAGE_DRIVER")
+	cfg.Storage.LocalDir = viper.GetString("STORAGE_LOCAL_DIR")
+	cfg.Storage.MaxUploadSize = viper.GetInt64("MEDIA_MAX_UPLOAD_SIZE")
+	cfg.Storage.URLSecret = viper.GetString("MEDIA_URL_SECRET")
+	cfg.Storage.URLTTL = viper.GetDuration("MEDIA_URL_TTL")
+	cfg.Storage.S3.Endpoint = viper.GetString("S3_ENDPOINT")
+	cfg.Storage.S3.Region = viper.GetString("S3_REGION")
+	cfg.Storage.S3.Bucket = viper.GetString("S3_BUCKET")
+	cfg.Storage.S3.AccessKey = viper.GetString("S3_ACCESS_KEY")
+	cfg.Storage.S3.SecretKey = viper.GetString("S3_SECRET_KEY")
+	cfg.Storage.S3.PathStyle = viper.GetBool("S3_PATH_STYLE")
+	cfg.Storage.S3.DevServer = viper.GetBool("S3_DEV_SERVER")
+
+	// Image processing.
+	cfg.Images.Widths = parseInts(viper.GetString("MEDIA_IMAGE_WIDTHS"))
+	cfg.Images.Formats = parseList(viper.GetString("MEDIA_IMAGE_FORMATS"))
+	cfg.Images.JPEGQuality = viper.GetInt("MEDIA_JPEG_QUALITY")
+	cfg.Images.Workers = viper.GetInt("MEDIA_WORKERS")
+	cfg.Images.MaxPixels = viper.GetInt("MEDIA_MAX_PIXELS")
+
+	cfg.Site.Title = viper.GetString("SITE_TITLE")
+	cfg.Site.Description = viper.GetString("SITE_DESCRIPTION")
+
+	cfg.SEO.AllowIndexing = viper.GetBool("SEO_ALLOW_INDEXING")
+	cfg.SEO.RobotsDisallow = parseList(viper.GetString("ROBOTS_DISALLOW"))
+	cfg.SEO.SitemapPageSize = viper.GetInt("SITEMAP_PAGE_SIZE")
+
+	cfg.Web.Enabled = viper.GetBool("WEB_ENABLED")
+	cfg.Web.ThemeDir = viper.GetString("WEB_THEME_DIR")
+	cfg.Web.ReloadTemplates = viper.GetBool("WEB_THEME_RELOAD")
+	cfg.Web.PageSize = viper.GetInt("WEB_PAGE_SIZE")
+}
+
+// parseMapping reads "key:value,key:value" pairs.
+func parseMapping(s string) map[string]string {
+	m := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && k != "" {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// parseList reads comma separated values, dropping empty ones.
+func parseList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
 }
 
+// parseInts reads comma separated numbers, values that are not positive numbers are dropped.
+func parseInts(s string) []int {
+	var values []int
+	for _, v := range parseList(s) {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			values = append(values, n)
+		}
+	}
+	return values
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// setDefaultValues falls back to Constcfg, so it stays the place to change defaults.
 func setDefaultValues() {
-	viper.SetDefault("DB_URI", defaultMongoDBURI)
+	viper.SetDefault("APP_ENV", Constcfg.App.Env)
+	viper.SetDefault("APP_PORT", Constcfg.App.Port)
+	viper.SetDefault("DB_URI", Constcfg.DB.URI)
+	viper.SetDefault("BASE_URL", Constcfg.BaseURL)
+	viper.SetDefault("MAIL_DRIVER", Constcfg.Mail.Driver)
+	viper.SetDefault("MAIL_FROM", Constcfg.Mail.From)
+	viper.SetDefault("MAIL_OUTBOX_DIR", Constcfg.Mail.OutboxDir)
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("OIDC_GROUPS_CLAIM", Constcfg.OIDC.GroupsClaim)
+	viper.SetDefault("SESSION_COOKIE_NAME", Constcfg.Session.CookieName)
+	viper.SetDefault("SESSION_IDLE_TIMEOUT", Constcfg.Session.IdleTimeout)
+	viper.SetDefault("SESSION_ABSOLUTE_TIMEOUT", Constcfg.Session.AbsoluteTimeout)
+	viper.SetDefault("SESSION_COOKIE_SECURE", Constcfg.Session.Secure)
+	viper.SetDefault("SESSION_COOKIE_SAMESITE", Constcfg.Session.SameSite)
+	viper.SetDefault("SEARCH_BACKEND", Constcfg.Search.Backend)
+	viper.SetDefault("SEARCH_INDEX_PATH", Constcfg.Search.IndexPath)
+	viper.SetDefault("SEARCH_TITLE_BOOST", Constcfg.Search.TitleBoost)
+	viper.SetDefault("SEARCH_TAG_BOOST", Constcfg.Search.TagBoost)
+	viper.SetDefault("STORAGE_DRIVER", Constcfg.Storage.Driver)
+	viper.SetDefault("STORAGE_LOCAL_DIR", Constcfg.Storage.LocalDir)
+	viper.SetDefault("MEDIA_MAX_UPLOAD_SIZE", Constcfg.Storage.MaxUploadSize)
+	viper.SetDefault("MEDIA_URL_TTL", Constcfg.Storage.URLTTL)
+	viper.SetDefault("S3_REGION", Constcfg.Storage.S3.Region)
+	viper.SetDefault("S3_PATH_STYLE", Constcfg.Storage.S3.PathStyle)
+	viper.SetDefault("MEDIA_IMAGE_WIDTHS", joinInts(Constcfg.Images.Widths))
+	viper.SetDefault("MEDIA_IMAGE_FORMATS", strings.Join(Constcfg.Images.Formats, ","))
+	viper.SetDefault("MEDIA_JPEG_QUALITY", Constcfg.Images.JPEGQuality)
+	viper.SetDefault("MEDIA_WORKERS", Constcfg.Images.Workers)
+	viper.SetDefault("MEDIA_MAX_PIXELS", Constcfg.Images.MaxPixels)
+	viper.SetDefault("SITE_TITLE", Constcfg.Site.Title)
+	viper.SetDefault("SITE_DESCRIPTION", Constcfg.Site.Description)
+	viper.SetDefault("SEO_ALLOW_INDEXING", Constcfg.SEO.AllowIndexing)
+	viper.SetDefault("ROBOTS_DISALLOW", strings.Join(Constcfg.SEO.RobotsDisallow, ","))
+	viper.SetDefault("SITEMAP_PAGE_SIZE", Constcfg.SEO.SitemapPageSize)
+	viper.SetDefault("WEB_ENABLED", Constcfg.Web.Enabled)
+	viper.SetDefault("WEB_THEME_DIR", Constcfg.Web.ThemeDir)
+	viper.SetDefault("WEB_THEME_RELOAD", Constcfg.Web.ReloadTemplates)
+	viper.SetDefault("WEB_PAGE_SIZE", Constcfg.Web.PageSize)
 }
 
 var Constcfg = AppConfig{
@@ -66,6 +357,58 @@ var Constcfg = AppConfig{
 		Port: 8080,
 	},
 	DB: struct{ URI string }{
-		URI: "mongodb://localhost:27017",
+		URI: defaultMongoDBURI,
+	},
+	BaseURL: defaultBaseURL,
+	Mail: MailConfig{
+		Driver:    defaultMailDriver,
+		From:      defaultMailFrom,
+		OutboxDir: defaultOutboxDir,
+	},
+	OIDC: OIDCConfig{
+		GroupsClaim: "groups",
+	},
+	Session: SessionConfig{
+		CookieName:      "blog_session",
+		IdleTimeout:     30 * time.Minute,
+		AbsoluteTimeout: 7 * 24 * time.Hour,
+		Secure:          true,
+		SameSite:        "lax",
+	},
+	Search: SearchConfig{
+		Backend:    "mongo",
+		IndexPath:  "data/search.bleve",
+		TitleBoost: 3,
+		TagBoost:   2,
+	},
+	Storage: StorageConfig{
+		Driver:        "local",
+		LocalDir:      "data/media",
+		MaxUploadSize: 10 << 20,
+		URLTTL:        15 * time.Minute,
+		S3: S3Config{
+			Region:    "us-east-1",
+			PathStyle: true,
+		},
+	},
+	Images: ImageConfig{
+		Widths:      []int{320, 640, 1280, 1920},
+		Formats:     []string{"webp", "jpeg"},
+		JPEGQuality: 82,
+		Workers:     2,
+		MaxPixels:   50_000_000,
+	},
+	Site: SiteConfig{
+		Title:       "Blog Platform",
+		Description: "Posts from the Blog Platform",
+	},
+	SEO: SEOConfig{
+		AllowIndexing:   true,
+		RobotsDisallow:  []string{"/api/", "/dev/"},
+		SitemapPageSize: 5000,
+	},
+	Web: WebConfig{
+		Enabled:  false,
+		PageSize: 10,
 	},
 }
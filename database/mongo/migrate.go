@@ -0,0 +1,218 @@
+package dbmongo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const migrationsCollection = "migrations"
+
+// Migration is a one-off data change, applied migrations are recorded by ID in the migrations collection.
+type Migration struct {
+	ID string
+	Up func(ctx context.Context, db *mongo.Database) error
+}
+
+// Migrations lists every migration in the order it has to be applied, append new ones at the end.
+var Migrations = []Migration{
+	{
+		ID: "0001_user_status",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("users").UpdateMany(ctx,
+				bson.M{"status": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"status": "active"}})
+			return err
+		},
+	},
+	{
+		ID: "0002_post_slugs",
+		Up: backfillPostSlugs,
+	},
+	{
+		ID: "0003_post_published_at",
+		// imported drafts have no publication date either, they stay drafts
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").UpdateMany(ctx,
+				bson.M{"published_at": bson.M{"$exists": false}, "import": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"published_at": "$created_at"}}}})
+			return err
+		},
+	},
+	{
+		ID: "0004_comment_moderation",
+		Up: backfillCommentModeration,
+	},
+	{
+		ID: "0005_post_popularity",
+		// posts had no reactions or bookmarks yet, their popularity only counts the comments
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").UpdateMany(ctx,
+				bson.M{"popularity": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{
+					"reaction_count": 0,
+					"bookmark_count": 0,
+					"popularity":     bson.M{"$multiply": bson.A{repoModels.PopularityCommentWeight, bson.M{"$ifNull": bson.A{"$comment_count", 0}}}},
+				}}}})
+			return err
+		},
+	},
+	{
+		ID: "0006_post_content_format",
+		// the reindex command renders the content of these posts
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("posts").UpdateMany(ctx,
+				bson.M{"content_format": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"content_format": "markdown"}})
+			return err
+		},
+	},
+	{
+		ID: "0007_media_processing",
+		// the image workers of the server pick these up
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("media").UpdateMany(ctx,
+				bson.M{"status": bson.M{"$exists": false}, "content_type": bson.M{"$regex": "^image/"}},
+				bson.M{"$set": bson.M{"status": repoModels.MediaStatusPending}})
+			return err
+		},
+	},
+}
+
+// backfillCommentModeration approves the comments written before moderation and copies the author of their
+// post, which the moderation queue filters on.
+func backfillCommentModeration(ctx context.Context, db *mongo.Database) error {
+	comments := db.Collection("comments")
+	_, err := comments.UpdateMany(ctx,
+		bson.M{"status": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"status": "approved"}})
+	if err != nil {
+		return err
+	}
+
+	postIDs, err := comments.Distinct(ctx, "post_id", bson.M{"post_author_id": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	for _, postID := range postIDs {
+		var post struct {
+			Author struct {
+				ID primitive.ObjectID `bson:"_id"`
+			} `bson:"author"`
+		}
+		err = db.Collection("posts").FindOne(ctx, bson.M{"_id": postID},
+			options.FindOne().SetProjection(bson.M{"author._id": 1})).Decode(&post)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = comments.UpdateMany(ctx,
+			bson.M{"post_id": postID, "post_author_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"post_author_id": post.Author.ID}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillPostSlugs generates the slugs of posts created before posts had one, unique per author.
+func backfillPostSlugs(ctx context.Context, db *mongo.Database) error {
+	coll := db.Collection("posts")
+	taken := map[primitive.ObjectID]map[string]bool{}
+
+	cursor, err := coll.Find(ctx, bson.M{"slug": bson.M{"$type": "string", "$ne": ""}},
+		options.Find().SetProjection(bson.M{"author._id": 1, "slug": 1, "slug_history": 1}))
+	if err != nil {
+		return err
+	}
+	var existing []slugDoc
+	if err = cursor.All(ctx, &existing); err != nil {
+		return err
+	}
+	for _, p := range existing {
+		for _, slug := range append(p.SlugHistory, p.Slug) {
+			markSlug(taken, p.Author.ID, slug)
+		}
+	}
+
+	cursor, err = coll.Find(ctx, bson.M{"$or": bson.A{bson.M{"slug": bson.M{"$exists": false}}, bson.M{"slug": ""}}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetProjection(bson.M{"author._id": 1, "title": 1}))
+	if err != nil {
+		return err
+	}
+	var missing []slugDoc
+	if err = cursor.All(ctx, &missing); err != nil {
+		return err
+	}
+	for _, p := range missing {
+		base := utils.Slugify(p.Title)
+		if base == "" {
+			base = "post"
+		}
+		slug := base
+		for i := 2; taken[p.Author.ID][slug]; i++ {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+		markSlug(taken, p.Author.ID, slug)
+
+		if _, err = coll.UpdateOne(ctx, bson.M{"_id": p.ID}, bson.M{"$set": bson.M{"slug": slug}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type slugDoc struct {
+	ID     primitive.ObjectID `bson:"_id"`
+	Title  string             `bson:"title"`
+	Author struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"author"`
+	Slug        string   `bson:"slug"`
+	SlugHistory []string `bson:"slug_history"`
+}
+
+func markSlug(taken map[primitive.ObjectID]map[string]bool, author primitive.ObjectID, slug string) {
+	if taken[author] == nil {
+		taken[author] = map[string]bool{}
+	}
+	taken[author][slug] = true
+}
+
+// Migrate applies the migrations that have not been recorded yet and returns the IDs it applied.
+func Migrate(ctx context.Context, db *mongo.Database, migrations []Migration) ([]string, error) {
+	coll := db.Collection(migrationsCollection)
+	var applied []string
+
+	for _, m := range migrations {
+		err := coll.FindOne(ctx, bson.M{"_id": m.ID}).Err()
+		if err == nil {
+			continue
+		}
+		if err != mongo.ErrNoDocuments {
+			return applied, err
+		}
+
+		log.Printf("applying migration %s", m.ID)
+		if err = m.Up(ctx, db); err != nil {
+			return applied, err
+		}
+		if _, err = coll.InsertOne(ctx, bson.M{"_id": m.ID, "applied_at": time.Now()}); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.ID)
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,317 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strconv"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=UserService --case underscore
+type UserService interface {
+	ListUsers(role, status string, includeDeleted bool, page models.PageReq) ([]repoModels.User, *repoModels.ListMetaData, error)
+	SetRole(id primitive.ObjectID, role string, access models.UserAccess) error
+	Suspend(id primitive.ObjectID, req models.UserStatusReq, access models.UserAccess) error
+	Unsuspend(id primitive.ObjectID, access models.UserAccess) error
+	Ban(id primitive.ObjectID, req models.UserStatusReq, access models.UserAccess) error
+	ForcePasswordReset(id primitive.ObjectID, password string) (string, error)
+}
+
+//go:generate mockery --name=TwoFactorService --case underscore
+type TwoFactorService interface {
+	Reset(id primitive.ObjectID) error
+	GetPolicy() (repoModels.SecuritySettings, error)
+	SetPolicy(roles []string) (repoModels.SecuritySettings, error)
+}
+
+type Controller struct {
+	users     UserService
+	twoFactor TwoFactorService
+}
+
+func New(users UserService, twoFactor TwoFactorService) *Controller {
+	return &Controller{users, twoFactor}
+}
+
+// ListUsers godoc
+// @Summary List users (admin)
+// @Description List users filtered by role and status
+// @Tags admin
+// @Produce json
+// @Param role query string false "Role"
+// @Param status query string false "Status (active, suspended, banned)"
+// @Param deleted query bool false "Include soft deleted users"
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the matching users"
+// @Success 200 {object} models.ListUserRes
+// @Header 200 {string} Link "Links to the next and previous page"
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users [get]
+func (c *Controller) ListUsers(ctx *gin.Context) {
+	page, err := models.ParsePage(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	deleted, _ := strconv.ParseBool(ctx.DefaultQuery("deleted", "false"))
+
+	users, pagi, err := c.users.ListUsers(ctx.Query("role"), ctx.Query("status"), deleted, page)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListUserRes{Data: users, Metadata: *pagi})
+}
+
+// SetRole godoc
+// @Summary Change a user's role (admin)
+// @Description Promote or demote a user
+// @Tags admin
+// @Accept json
+// @Param id path string true "User ID"
+// @Param role body models.RoleReq true "Role"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/role [put]
+func (c *Controller) SetRole(ctx *gin.Context) {
+	id, access, ok := c.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.RoleReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.users.SetRole(id, req.Role, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Suspend godoc
+// @Summary Suspend a user (admin)
+// @Description Suspend a user until the given time, or indefinitely when until is omitted
+// @Tags admin
+// @Accept json
+// @Param id path string true "User ID"
+// @Param status body models.UserStatusReq false "Reason and end of the suspension"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/suspend [post]
+func (c *Controller) Suspend(ctx *gin.Context) {
+	id, access, ok := c.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.UserStatusReq
+	if !bindOptionalJSON(ctx, &req) {
+		return
+	}
+
+	if err := c.users.Suspend(id, req, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Unsuspend godoc
+// @Summary Lift a suspension (admin)
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/unsuspend [post]
+func (c *Controller) Unsuspend(ctx *gin.Context) {
+	id, access, ok := c.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.users.Unsuspend(id, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Ban godoc
+// @Summary Ban a user (admin)
+// @Description Permanently block a user from authenticating
+// @Tags admin
+// @Accept json
+// @Param id path string true "User ID"
+// @Param status body models.UserStatusReq false "Reason"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/ban [post]
+func (c *Controller) Ban(ctx *gin.Context) {
+	id, access, ok := c.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.UserStatusReq
+	if !bindOptionalJSON(ctx, &req) {
+		return
+	}
+
+	if err := c.users.Ban(id, req, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ForcePasswordReset godoc
+// @Summary Force a password reset (admin)
+// @Description Set a temporary password, generated when not given, that the user must change on next use
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param password body models.PasswordResetReq false "Temporary password"
+// @Success 200 {object} models.PasswordResetRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/password-reset [post]
+func (c *Controller) ForcePasswordReset(ctx *gin.Context) {
+	id, _, ok := c.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.PasswordResetReq
+	if !bindOptionalJSON(ctx, &req) {
+		return
+	}
+
+	password, err := c.users.ForcePasswordReset(id, req.Password)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.PasswordResetRes{TemporaryPassword: password})
+}
+
+// ResetTwoFactor godoc
+// @Summary Remove a user's two-factor enrollment (admin)
+// @Description For users who lost both their authenticator and recovery codes
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/2fa [delete]
+func (c *Controller) ResetTwoFactor(ctx *gin.Context) {
+	id, _, ok := c.parseRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.twoFactor.Reset(id); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetTwoFactorPolicy godoc
+// @Summary Roles required to use two-factor authentication (admin)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} repoModels.SecuritySettings
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/settings/2fa [get]
+func (c *Controller) GetTwoFactorPolicy(ctx *gin.Context) {
+	settings, err := c.twoFactor.GetPolicy()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// SetTwoFactorPolicy godoc
+// @Summary Require two-factor authentication for roles (admin)
+// @Description Users of these roles without an enrollment can only reach the enrollment endpoints
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param policy body models.TwoFactorPolicyReq true "Roles"
+// @Success 200 {object} repoModels.SecuritySettings
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/settings/2fa [put]
+func (c *Controller) SetTwoFactorPolicy(ctx *gin.Context) {
+	var req models.TwoFactorPolicyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := c.twoFactor.SetPolicy(req.Roles)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// parseRequest reads the user id path parameter and the calling admin, writing the error response when either fails.
+func (c *Controller) parseRequest(ctx *gin.Context) (primitive.ObjectID, models.UserAccess, bool) {
+	access := models.UserAccess{}
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return id, access, false
+	}
+
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return id, access, false
+	}
+	return id, access, true
+}
+
+func bindOptionalJSON(ctx *gin.Context, obj any) bool {
+	if ctx.Request.ContentLength == 0 {
+		return true
+	}
+	if err := ctx.ShouldBindJSON(obj); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	CreateAPIKey(req models.APIKeyReq, access models.UserAccess) (repoModels.APIKey, string, error)
+	GetAPIKeys(access models.UserAccess) ([]repoModels.APIKey, error)
+	RevokeAPIKey(id primitive.ObjectID, access models.UserAccess) error
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a personal API key, the key is only part of this response. Scopes: posts:read, posts:write, users:admin (admins only)
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body models.APIKeyReq true "API key"
+// @Success 201 {object} models.APIKeyRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/api-keys [post]
+func (c *Controller) CreateAPIKey(ctx *gin.Context) {
+	var req models.APIKeyReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access := models.UserAccess{}
+	err := access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	key, raw, err := c.service.CreateAPIKey(req, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, models.APIKeyRes{APIKey: key, Key: raw})
+}
+
+// GetAPIKeys godoc
+// @Summary List own API keys
+// @Description List the API keys of the authenticated user, including revoked and expired ones
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} repoModels.APIKey
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/api-keys [get]
+func (c *Controller) GetAPIKeys(ctx *gin.Context) {
+	access := models.UserAccess{}
+	err := access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	keys, err := c.service.GetAPIKeys(access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Tags api-keys
+// @Param id path string true "API key ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/api-keys/{id} [delete]
+func (c *Controller) RevokeAPIKey(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	err = access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = c.service.RevokeAPIKey(id, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
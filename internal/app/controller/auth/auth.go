@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(req models.ResetPasswordReq) error
+	ResendEmailVerification(ctx context.Context, access models.UserAccess) error
+	VerifyEmail(token string) error
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Mail a single-use reset link, the response is the same whether or not the address is registered
+// @Tags auth
+// @Accept json
+// @Param body body models.ForgotPasswordReq true "Email"
+// @Success 202
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/password/forgot [post]
+func (c *Controller) ForgotPassword(ctx *gin.Context) {
+	var req models.ForgotPasswordReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ForgotPassword(ctx, req.Email); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Set a new password using the token from the reset mail
+// @Tags auth
+// @Accept json
+// @Param body body models.ResetPasswordReq true "Token and new password"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/password/reset [post]
+func (c *Controller) ResetPassword(ctx *gin.Context) {
+	var req models.ResetPasswordReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.service.ResetPassword(req); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Confirm the address using the token from the verification mail, the token can be sent as query parameter or JSON body
+// @Tags auth
+// @Accept json
+// @Param token query string false "Token"
+// @Param body body models.VerifyEmailReq false "Token"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/email/verify [post]
+func (c *Controller) VerifyEmail(ctx *gin.Context) {
+	req := models.VerifyEmailReq{Token: ctx.Query("token")}
+	if req.Token == "" {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := c.service.VerifyEmail(req.Token); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ResendEmailVerification godoc
+// @Summary Resend the verification mail
+// @Tags auth
+// @Success 202
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/email/resend [post]
+func (c *Controller) ResendEmailVerification(ctx *gin.Context) {
+	access := models.UserAccess{}
+	err := access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = c.service.ResendEmailVerification(ctx, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,107 @@
+package bookmark
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Bookmark(postID primitive.ObjectID, access models.UserAccess) error
+	Unbookmark(postID primitive.ObjectID, access models.UserAccess) error
+	GetBookmarks(page models.PageReq, access models.UserAccess) ([]models.BookmarkRes, *repoModels.ListMetaData, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Bookmark godoc
+// @Summary Bookmark a post
+// @Description Bookmarking a post twice changes nothing
+// @Tags bookmarks
+// @Param id path string true "Post ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/bookmark [put]
+func (c *Controller) Bookmark(ctx *gin.Context) {
+	c.toggle(ctx, c.service.Bookmark)
+}
+
+// Unbookmark godoc
+// @Summary Remove the bookmark of a post
+// @Description Removing a bookmark that does not exist changes nothing
+// @Tags bookmarks
+// @Param id path string true "Post ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/bookmark [delete]
+func (c *Controller) Unbookmark(ctx *gin.Context) {
+	c.toggle(ctx, c.service.Unbookmark)
+}
+
+func (c *Controller) toggle(ctx *gin.Context, fn func(primitive.ObjectID, models.UserAccess) error) {
+	postID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = fn(postID, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetBookmarks godoc
+// @Summary List my bookmarks
+// @Description The bookmarked posts, most recently bookmarked first
+// @Tags bookmarks
+// @Produce json
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the bookmarks"
+// @Success 200 {object} models.ListBookmarkRes
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/bookmarks [get]
+func (c *Controller) GetBookmarks(ctx *gin.Context) {
+	page, err := models.ParsePage(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	bookmarks, pagi, err := c.service.GetBookmarks(page, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListBookmarkRes{Data: bookmarks, Metadata: *pagi})
+}
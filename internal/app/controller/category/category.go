@@ -0,0 +1,149 @@
+package category
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	CreateCategory(req models.CategoryReq) (repoModels.Category, error)
+	GetCategories() ([]repoModels.Category, error)
+	GetCategory(ref string) (repoModels.Category, error)
+	UpdateCategory(id primitive.ObjectID, req models.CategoryReq) (repoModels.Category, error)
+	DeleteCategory(id primitive.ObjectID) error
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// GetCategories godoc
+// @Summary List categories
+// @Description All categories sorted by name, parent_id and ancestors describe the tree
+// @Tags categories
+// @Produce json
+// @Success 200 {array} repoModels.Category
+// @Failure 500 {object} gin.H
+// @Router /categories [get]
+func (c *Controller) GetCategories(ctx *gin.Context) {
+	categories, err := c.service.GetCategories()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, categories)
+}
+
+// GetCategory godoc
+// @Summary Get a category by ID or slug
+// @Tags categories
+// @Produce json
+// @Param ref path string true "Category ID or slug"
+// @Success 200 {object} repoModels.Category
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /categories/{ref} [get]
+func (c *Controller) GetCategory(ctx *gin.Context) {
+	category, err := c.service.GetCategory(ctx.Param("ref"))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, category)
+}
+
+// CreateCategory godoc
+// @Summary Create a category (admin)
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body models.CategoryReq true "Category"
+// @Success 201 {object} repoModels.Category
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/categories [post]
+func (c *Controller) CreateCategory(ctx *gin.Context) {
+	var req models.CategoryReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := c.service.CreateCategory(req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Rename or move a category (admin)
+// @Description Replace name, slug, description and parent, subcategories move along
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param category body models.CategoryReq true "Category"
+// @Success 200 {object} repoModels.Category
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/categories/{id} [put]
+func (c *Controller) UpdateCategory(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	var req models.CategoryReq
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := c.service.UpdateCategory(id, req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category (admin)
+// @Description Only categories without subcategories can be deleted, their posts move to the parent category
+// @Tags categories
+// @Param id path string true "Category ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/categories/{id} [delete]
+func (c *Controller) DeleteCategory(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err = c.service.DeleteCategory(id); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,212 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	GetThread(postID primitive.ObjectID, access models.UserAccess) (models.CommentThreadRes, error)
+	CreateComment(postID primitive.ObjectID, req models.CommentReq, access models.UserAccess) (repoModels.Comment, error)
+	UpdateComment(id primitive.ObjectID, req models.CommentUpdateReq, access models.UserAccess) (repoModels.Comment, error)
+	DeleteComment(id primitive.ObjectID, access models.UserAccess) error
+	SetCommentsClosed(postID primitive.ObjectID, closed bool, access models.UserAccess) error
+	ModerationService
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// GetComments godoc
+// @Summary Get the comments on a post
+// @Description The comments the user can see as a tree of replies, oldest first. Held comments are only shown
+// @Description to their author. Deleted and hidden comments are kept without content and author while they have replies.
+// @Tags comments
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.CommentThreadRes
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/comments [get]
+func (c *Controller) GetComments(ctx *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	thread, err := c.service.GetThread(postID, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, thread)
+}
+
+// CreateComment godoc
+// @Summary Comment on a post
+// @Description Add a comment, or a reply to the comment given as parent_id. Depending on the moderation settings
+// @Description the comment is held for a moderator, it is then returned with the status pending.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param comment body models.CommentReq true "Comment"
+// @Success 201 {object} repoModels.Comment
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/comments [post]
+func (c *Controller) CreateComment(ctx *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req models.CommentReq
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	comment, err := c.service.CreateComment(postID, req, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment godoc
+// @Summary Edit a comment
+// @Description Authors can edit their comment for 15 minutes after posting it
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Param comment body models.CommentUpdateReq true "Comment"
+// @Success 200 {object} repoModels.Comment
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /comments/{id} [put]
+func (c *Controller) UpdateComment(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req models.CommentUpdateReq
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	comment, err := c.service.UpdateComment(id, req, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment godoc
+// @Summary Delete a comment
+// @Description Soft delete a comment, allowed for its author, the author of the post and admins
+// @Tags comments
+// @Param id path string true "Comment ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /comments/{id} [delete]
+func (c *Controller) DeleteComment(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = c.service.DeleteComment(id, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// SetCommentsClosed godoc
+// @Summary Close or reopen the comments on a post
+// @Description Allowed for the author of the post and admins, existing comments stay visible
+// @Tags comments
+// @Accept json
+// @Param id path string true "Post ID"
+// @Param state body models.CommentsClosedReq true "Closed"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/comments/closed [put]
+func (c *Controller) SetCommentsClosed(ctx *gin.Context) {
+	postID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	var req models.CommentsClosedReq
+	if err = ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = c.service.SetCommentsClosed(postID, req.Closed, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
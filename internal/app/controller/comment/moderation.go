@@ -0,0 +1,178 @@
+package comment
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+// ModerationService is the part of Service the moderation queue and settings use.
+type ModerationService interface {
+	ModerationQueue(filter models.CommentQueueFilter, access models.UserAccess) ([]repoModels.Comment, *repoModels.ListMetaData, error)
+	Moderate(id primitive.ObjectID, decision string, access models.UserAccess) (repoModels.Comment, error)
+	GetModerationSettings() (repoModels.ModerationSettings, error)
+	SetModerationSettings(req models.ModerationSettingsReq) (repoModels.ModerationSettings, error)
+	SetShadowHidden(userID primitive.ObjectID, hidden bool) error
+}
+
+// ModerationQueue godoc
+// @Summary List comments to moderate
+// @Description Comments with the status, oldest first. Admins see every comment, other users the comments on their posts.
+// @Tags moderation
+// @Produce json
+// @Param status query string false "pending (default), spam, rejected or approved"
+// @Param post_id query string false "Only comments on this post"
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the matching comments"
+// @Success 200 {object} models.ListCommentRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /moderation/comments [get]
+func (c *Controller) ModerationQueue(ctx *gin.Context) {
+	filter, err := models.ParseCommentQueueFilter(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	comments, pagi, err := c.service.ModerationQueue(filter, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListCommentRes{Data: comments, Metadata: *pagi})
+}
+
+// ModerateComment godoc
+// @Summary Approve, reject or mark a comment as spam
+// @Description Allowed for the author of the post and admins. Approving and marking as spam train the spam filter.
+// @Tags moderation
+// @Produce json
+// @Param id path string true "Comment ID"
+// @Param decision path string true "approve, reject or spam"
+// @Success 200 {object} repoModels.Comment
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /moderation/comments/{id}/{decision} [post]
+func (c *Controller) ModerateComment(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	comment, err := c.service.Moderate(id, ctx.Param("decision"), access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, comment)
+}
+
+// GetModerationSettings godoc
+// @Summary Get the comment moderation settings (admin)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} repoModels.ModerationSettings
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/settings/comments [get]
+func (c *Controller) GetModerationSettings(ctx *gin.Context) {
+	settings, err := c.service.GetModerationSettings()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// SetModerationSettings godoc
+// @Summary Change the comment moderation settings (admin)
+// @Description mode is open, first (hold the first comment of every user) or all
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param settings body models.ModerationSettingsReq true "Settings"
+// @Success 200 {object} repoModels.ModerationSettings
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/settings/comments [put]
+func (c *Controller) SetModerationSettings(ctx *gin.Context) {
+	var req models.ModerationSettingsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := c.service.SetModerationSettings(req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// ShadowHide godoc
+// @Summary Shadow hide a user (admin)
+// @Description The user can keep commenting, but nobody else sees their comments
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/shadow-hide [post]
+func (c *Controller) ShadowHide(ctx *gin.Context) {
+	c.setShadowHidden(ctx, true)
+}
+
+// ShadowUnhide godoc
+// @Summary Lift a shadow hide (admin)
+// @Tags admin
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/users/{id}/shadow-unhide [post]
+func (c *Controller) ShadowUnhide(ctx *gin.Context) {
+	c.setShadowHidden(ctx, false)
+}
+
+func (c *Controller) setShadowHidden(ctx *gin.Context, hidden bool) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	if err = c.service.SetShadowHidden(id, hidden); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,131 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-platform/internal/app/controller/models"
+	"blog-platform/internal/feed"
+	"blog-platform/internal/utils"
+)
+
+// maxAge is how long readers and proxies may cache a feed without asking again.
+const maxAge = 5 * time.Minute
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Feed(ctx context.Context, req models.FeedReq) (feed.Feed, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Posts godoc
+// @Summary Feed of the newest posts
+// @Description RSS 2.0 (posts.rss), Atom 1.0 (posts.atom) or JSON Feed 1.1 (posts.json), no authentication needed. Supports conditional requests with If-None-Match and If-Modified-Since.
+// @Tags feeds
+// @Produce xml
+// @Produce json
+// @Param file path string true "posts.rss, posts.atom or posts.json"
+// @Param content query string false "full (default) for the rendered posts, excerpt for their excerpts only"
+// @Param limit query int false "Number of posts, at most 50"
+// @Success 200
+// @Success 304
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /feeds/{file} [get]
+func (c *Controller) Posts(ctx *gin.Context) {
+	c.serve(ctx, models.FeedReq{})
+}
+
+// AuthorPosts godoc
+// @Summary Feed of the newest posts of an author
+// @Description As the feed of all posts, limited to the author
+// @Tags feeds
+// @Produce xml
+// @Produce json
+// @Param username path string true "Username of the author"
+// @Param file path string true "posts.rss, posts.atom or posts.json"
+// @Param content query string false "full (default) for the rendered posts, excerpt for their excerpts only"
+// @Param limit query int false "Number of posts, at most 50"
+// @Success 200
+// @Success 304
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /feeds/authors/{username}/{file} [get]
+func (c *Controller) AuthorPosts(ctx *gin.Context) {
+	c.serve(ctx, models.FeedReq{Author: ctx.Param("username")})
+}
+
+// TagPosts godoc
+// @Summary Feed of the newest posts with a tag
+// @Description As the feed of all posts, limited to the tag
+// @Tags feeds
+// @Produce xml
+// @Produce json
+// @Param tag path string true "Tag"
+// @Param file path string true "posts.rss, posts.atom or posts.json"
+// @Param content query string false "full (default) for the rendered posts, excerpt for their excerpts only"
+// @Param limit query int false "Number of posts, at most 50"
+// @Success 200
+// @Success 304
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Router /feeds/tags/{tag}/{file} [get]
+func (c *Controller) TagPosts(ctx *gin.Context) {
+	c.serve(ctx, models.FeedReq{Tag: ctx.Param("tag")})
+}
+
+func (c *Controller) serve(ctx *gin.Context, req models.FeedReq) {
+	format, ok := strings.CutPrefix(ctx.Param("file"), "posts.")
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "feeds are posts.rss, posts.atom and posts.json"})
+		return
+	}
+	req.Format = format
+
+	content := ctx.DefaultQuery("content", "full")
+	switch content {
+	case "full":
+		req.Full = true
+	case "excerpt":
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "content must be full or excerpt"})
+		return
+	}
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return
+		}
+		req.Limit = n
+	}
+
+	f, err := c.service.Feed(ctx.Request.Context(), req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	if utils.CachePublic(ctx, f.ETag(format, content), f.Updated, maxAge) {
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = feed.Write(&buf, f, format); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Data(http.StatusOK, feed.ContentTypes[format], buf.Bytes())
+}
@@ -0,0 +1,165 @@
+package follow
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Follow(authorID primitive.ObjectID, access models.UserAccess) error
+	Unfollow(authorID primitive.ObjectID, access models.UserAccess) error
+	GetFollowers(userID primitive.ObjectID, page models.PageReq) ([]models.FollowRes, *repoModels.ListMetaData, error)
+	GetFollowing(userID primitive.ObjectID, page models.PageReq) ([]models.FollowRes, *repoModels.ListMetaData, error)
+	GetFeed(ctx context.Context, page models.PageReq, access models.UserAccess) ([]repoModels.Post, *repoModels.ListMetaData, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Follow godoc
+// @Summary Follow an author
+// @Description Following an author twice changes nothing
+// @Tags follows
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/{id}/follow [put]
+func (c *Controller) Follow(ctx *gin.Context) {
+	c.toggle(ctx, c.service.Follow)
+}
+
+// Unfollow godoc
+// @Summary Unfollow an author
+// @Description Unfollowing an author that is not followed changes nothing
+// @Tags follows
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/{id}/follow [delete]
+func (c *Controller) Unfollow(ctx *gin.Context) {
+	c.toggle(ctx, c.service.Unfollow)
+}
+
+func (c *Controller) toggle(ctx *gin.Context, fn func(primitive.ObjectID, models.UserAccess) error) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = fn(id, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetFollowers godoc
+// @Summary List the followers of a user
+// @Description The most recent follower first
+// @Tags follows
+// @Produce json
+// @Param id path string true "User ID"
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the followers"
+// @Success 200 {object} models.ListFollowRes
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/{id}/followers [get]
+func (c *Controller) GetFollowers(ctx *gin.Context) {
+	c.list(ctx, c.service.GetFollowers)
+}
+
+// GetFollowing godoc
+// @Summary List the users a user follows
+// @Description The most recently followed first
+// @Tags follows
+// @Produce json
+// @Param id path string true "User ID"
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the followed users"
+// @Success 200 {object} models.ListFollowRes
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/{id}/following [get]
+func (c *Controller) GetFollowing(ctx *gin.Context) {
+	c.list(ctx, c.service.GetFollowing)
+}
+
+func (c *Controller) list(ctx *gin.Context, fn func(primitive.ObjectID, models.PageReq) ([]models.FollowRes, *repoModels.ListMetaData, error)) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+	page, err := models.ParsePage(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	follows, pagi, err := fn(id, page)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListFollowRes{Data: follows, Metadata: *pagi})
+}
+
+// GetFeed godoc
+// @Summary Get my feed
+// @Description The posts of the authors you follow, newest first
+// @Tags follows
+// @Produce json
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the posts"
+// @Success 200 {object} models.ListPostReq
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /feed [get]
+func (c *Controller) GetFeed(ctx *gin.Context) {
+	page, err := models.ParsePage(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	posts, pagi, err := c.service.GetFeed(ctx, page, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListPostReq{Data: posts, Metadata: *pagi})
+}
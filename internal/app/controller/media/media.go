@@ -0,0 +1,250 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+// multipartOverhead is allowed on top of the upload size limit for the boundaries and headers of the form.
+const multipartOverhead = 64 << 10
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	MaxUploadSize() int64
+	Upload(ctx context.Context, r io.Reader, size int64, filename string, access models.UserAccess) (models.MediaRes, error)
+	GetMedia(id primitive.ObjectID, access models.UserAccess) (models.MediaRes, error)
+	ListMedia(page models.PageReq, access models.UserAccess) ([]models.MediaRes, *repoModels.ListMetaData, error)
+	DeleteMedia(id primitive.ObjectID, access models.UserAccess) error
+	Open(ctx context.Context, id primitive.ObjectID, variant, expires, signature string) (models.MediaFile, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Upload godoc
+// @Summary Upload a file
+// @Description Images (JPEG, PNG, GIF, WebP) and PDF documents, the type is detected from the content. Link to embed_url in a post to show the file there.
+// @Tags media
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "The file"
+// @Success 201 {object} models.MediaRes
+// @Failure 400 {object} gin.H
+// @Failure 413 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /media [post]
+func (c *Controller) Upload(ctx *gin.Context) {
+	access := models.UserAccess{}
+	if err := access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.service.MaxUploadSize()+multipartOverhead)
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "the file is too large"})
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "a multipart form with the file in the file field is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	defer file.Close()
+
+	media, err := c.service.Upload(ctx.Request.Context(), file, header.Size, header.Filename, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, media)
+}
+
+// ListMedia godoc
+// @Summary List my uploads
+// @Description The files uploaded by the user, newest first
+// @Tags media
+// @Produce json
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the files"
+// @Success 200 {object} models.ListMediaRes
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /media [get]
+func (c *Controller) ListMedia(ctx *gin.Context) {
+	page, err := models.ParsePage(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	media, pagi, err := c.service.ListMedia(page, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListMediaRes{Data: media, Metadata: *pagi})
+}
+
+// GetMedia godoc
+// @Summary Get an upload
+// @Description The file with a fresh signed download link, only its owner and admins can see it
+// @Tags media
+// @Produce json
+// @Param id path string true "Media ID"
+// @Success 200 {object} models.MediaRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /media/{id} [get]
+func (c *Controller) GetMedia(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	media, err := c.service.GetMedia(id, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, media)
+}
+
+// DeleteMedia godoc
+// @Summary Delete an upload
+// @Description Files that a post links to can not be deleted
+// @Tags media
+// @Param id path string true "Media ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /media/{id} [delete]
+func (c *Controller) DeleteMedia(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = c.service.DeleteMedia(id, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// Download godoc
+// @Summary Download a file
+// @Description Files that a post links to are public, the others need the expires and signature of a signed link. The response may redirect to the storage backend.
+// @Tags media
+// @Param id path string true "Media ID"
+// @Param expires query int false "Expiry of a signed link, in Unix seconds"
+// @Param signature query string false "Signature of a signed link"
+// @Success 200
+// @Success 302
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /media/{id}/file [get]
+func (c *Controller) Download(ctx *gin.Context) {
+	c.download(ctx, "")
+}
+
+// DownloadVariant godoc
+// @Summary Download a variant of an image
+// @Description A resized copy of a processed image, listed in its variants and srcset. Access works as for the image itself.
+// @Tags media
+// @Param id path string true "Media ID"
+// @Param variant path string true "Variant name, such as 640.webp"
+// @Param expires query int false "Expiry of a signed link, in Unix seconds"
+// @Param signature query string false "Signature of a signed link"
+// @Success 200
+// @Success 302
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /media/{id}/file/{variant} [get]
+func (c *Controller) DownloadVariant(ctx *gin.Context) {
+	c.download(ctx, ctx.Param("variant"))
+}
+
+func (c *Controller) download(ctx *gin.Context, variant string) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	signature := ctx.Query("signature")
+	file, err := c.service.Open(ctx.Request.Context(), id, variant, ctx.Query("expires"), signature)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	if file.Redirect != "" {
+		ctx.Redirect(http.StatusFound, file.Redirect)
+		return
+	}
+	defer file.Body.Close()
+
+	// Signed links are personal, public files may be cached by anyone.
+	cache := "public, max-age=3600"
+	if signature != "" {
+		cache = "private, no-store"
+	}
+	disposition := "inline"
+	if !strings.HasPrefix(file.ContentType, "image/") {
+		disposition = "attachment"
+	}
+	ctx.DataFromReader(http.StatusOK, file.Size, file.ContentType, file.Body, map[string]string{
+		"Cache-Control":          cache,
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": file.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
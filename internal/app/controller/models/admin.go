@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type RoleReq struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UserStatusReq is used to suspend or ban a user, Until is only used for suspensions and a nil value means indefinitely.
+type UserStatusReq struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+type PasswordResetReq struct {
+	Password string `json:"password"`
+}
+
+type PasswordResetRes struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+)
+
+type APIKeyReq struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyRes is only returned on creation, it is the one time the key is shown.
+type APIKeyRes struct {
+	repoModels.APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CategoryReq creates or replaces a category, the slug is derived from the name when empty and a
+// category without parent is a root category.
+type CategoryReq struct {
+	Name        string              `json:"name" binding:"required"`
+	Slug        string              `json:"slug"`
+	Description string              `json:"description"`
+	ParentID    *primitive.ObjectID `json:"parent_id,omitempty"`
+}
+
+type RenameTagReq struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MergeTagsReq replaces every tag of Tags with Into.
+type MergeTagsReq struct {
+	Tags []string `json:"tags" binding:"required"`
+	Into string   `json:"into" binding:"required"`
+}
+
+type TagChangeRes struct {
+	Tag   string `json:"tag"`
+	Posts int64  `json:"posts"`
+}
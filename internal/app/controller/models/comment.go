@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CommentReq creates a comment, with ParentID it is a reply to that comment.
+type CommentReq struct {
+	Content  string              `json:"content" binding:"required"`
+	ParentID *primitive.ObjectID `json:"parent_id,omitempty"`
+}
+
+type CommentUpdateReq struct {
+	Content string `json:"content" binding:"required"`
+}
+
+type CommentsClosedReq struct {
+	Closed bool `json:"closed"`
+}
+
+// CommentNode is a comment with its replies. Hidden placeholders stand for comments the user can not see
+// which have replies the user can see.
+type CommentNode struct {
+	repoModels.Comment
+	Hidden  bool          `json:"hidden,omitempty"`
+	Replies []CommentNode `json:"replies"`
+}
+
+type CommentThreadRes struct {
+	PostID   primitive.ObjectID `json:"post_id"`
+	Count    int64              `json:"count"`
+	Closed   bool               `json:"closed"`
+	Comments []CommentNode      `json:"comments"`
+}
+
+type ListCommentRes struct {
+	Data     []repoModels.Comment
+	Metadata repoModels.ListMetaData
+}
+
+// CommentQueueFilter selects the comments of the moderation queue, pending ones unless Status says otherwise.
+type CommentQueueFilter struct {
+	Status string
+	PostID *primitive.ObjectID
+	Page   PageReq
+}
+
+// ParseCommentQueueFilter reads status, post_id and the page parameters of the moderation queue.
+func ParseCommentQueueFilter(q url.Values) (CommentQueueFilter, error) {
+	filter := CommentQueueFilter{Status: q.Get("status")}
+	if v := q.Get("post_id"); v != "" {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return filter, fmt.Errorf("%w: invalid post_id", utils.ErrBadRequest)
+		}
+		filter.PostID = &id
+	}
+
+	var err error
+	filter.Page, err = ParsePage(q)
+	return filter, err
+}
+
+type ModerationSettingsReq struct {
+	Mode          string   `json:"mode" binding:"required"`
+	MaxLinks      int      `json:"max_links"`
+	Blocklist     []string `json:"blocklist"`
+	SpamThreshold float64  `json:"spam_threshold" binding:"required"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// FeedReq selects a syndication feed in Format (rss, atom or json), of the posts of Author or with Tag when
+// either is set. Full feeds carry the rendered content of the posts, the others only their excerpt.
+type FeedReq struct {
+	Format string
+	Author string
+	Tag    string
+	Full   bool
+	Limit  int
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+)
+
+// FollowRes is a follower or followed user with the time the follow started.
+type FollowRes struct {
+	User       repoModels.BasicUser `json:"user"`
+	FollowedAt time.Time            `json:"followed_at"`
+}
+
+type ListFollowRes struct {
+	Data     []FollowRes
+	Metadata repoModels.ListMetaData
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"io"
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+)
+
+// MediaRes is an uploaded file with a signed download link valid until URLExpiresAt. EmbedURL is the link to
+// put in the content of a post, it needs no signature once the post references the file. Srcset lists the
+// embed links of the variants of an image per content type, in the format of the srcset attribute.
+type MediaRes struct {
+	repoModels.Media
+	URL          string            `json:"url"`
+	URLExpiresAt time.Time         `json:"url_expires_at"`
+	EmbedURL     string            `json:"embed_url"`
+	Srcset       map[string]string `json:"srcset,omitempty"`
+}
+
+// MediaFile is a file opened for download, either Body, which the caller closes, or Redirect is set.
+type MediaFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	Body        io.ReadCloser
+	Redirect    string
+}
+
+type ListMediaRes struct {
+	Data     []MediaRes
+	Metadata repoModels.ListMetaData
+}
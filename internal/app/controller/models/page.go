@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// PageReq selects a page of a cursor paginated listing, the first one when Cursor is empty.
+type PageReq struct {
+	Cursor string
+	Limit  int
+	// Total asks for the number of matching items, which costs a count over the whole listing.
+	Total bool
+}
+
+// ParsePage reads cursor, limit and total, defaulting to the first page of DefaultPageSize items. The cursor
+// is the next_cursor or prev_cursor of a previous response, page numbers are not supported anymore.
+func ParsePage(q url.Values) (PageReq, error) {
+	page := PageReq{Cursor: q.Get("cursor"), Limit: DefaultPageSize}
+	if q.Has("page") {
+		return page, fmt.Errorf("%w: page is not supported, follow the next and prev links or pass their cursor", utils.ErrBadRequest)
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil || page.Limit < 1 || page.Limit > MaxPageSize {
+			return page, fmt.Errorf("%w: limit must be a number between 1 and %d", utils.ErrBadRequest, MaxPageSize)
+		}
+	}
+	if v := q.Get("total"); v != "" {
+		if page.Total, err = strconv.ParseBool(v); err != nil {
+			return page, fmt.Errorf("%w: total must be true or false", utils.ErrBadRequest)
+		}
+	}
+	return page, nil
+}
+
+// SetPageLinks fills in the links to the neighbouring pages of meta, the current request with its cursor
+// replaced, and announces them in a Link header as well.
+func SetPageLinks(ctx *gin.Context, meta *repoModels.ListMetaData) {
+	var links []string
+	if meta.NextCursor != "" {
+		meta.Next = pageLink(ctx.Request.URL, meta.NextCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, meta.Next))
+	}
+	if meta.PrevCursor != "" {
+		meta.Prev = pageLink(ctx.Request.URL, meta.PrevCursor)
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, meta.Prev))
+	}
+	if len(links) > 0 {
+		ctx.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageLink(current *url.URL, cursor string) string {
+	q := current.Query()
+	q.Set("cursor", cursor)
+	link := url.URL{Path: current.Path, RawQuery: q.Encode()}
+	return link.String()
+}
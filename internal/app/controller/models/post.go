@@ -0,0 +1,287 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+// PostSortFields maps the sortable fields of the post listing to their document paths, all of them are indexed.
+var PostSortFields = map[string]string{
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"published_at": "published_at",
+	"title":        "title",
+	"slug":         "slug",
+	"author":       "author.username",
+	"popularity":   "popularity",
+}
+
+// PostFields maps the fields that can be selected with fields= to their document paths.
+var PostFields = map[string]string{
+	"id":              "_id",
+	"title":           "title",
+	"slug":            "slug",
+	"content":         "content",
+	"content_format":  "content_format",
+	"content_html":    "content_html",
+	"excerpt":         "excerpt",
+	"word_count":      "word_count",
+	"reading_time":    "reading_time",
+	"toc":             "toc",
+	"tags":            "tags",
+	"category_id":     "category_id",
+	"seo":             "seo",
+	"author":          "author",
+	"comment_count":   "comment_count",
+	"comments_closed": "comments_closed",
+	"reactions":       "reactions",
+	"reaction_count":  "reaction_count",
+	"bookmark_count":  "bookmark_count",
+	"popularity":      "popularity",
+	"created_at":      "created_at",
+	"updated_at":      "updated_at",
+	"published_at":    "published_at",
+}
+
+// PostFilter narrows the post listing. Tag and AllTags must all be present on a post, at least one of AnyTags
+// must be, and Category, an id or slug, includes the posts of its subcategories.
+type PostFilter struct {
+	Authors   []string
+	Tag       string
+	AnyTags   []string
+	AllTags   []string
+	Category  string
+	Created   TimeRange
+	Updated   TimeRange
+	Published TimeRange
+	Sort      []SortField
+	// Fields selects the returned fields. Without them posts are summaries, leaving out the content, its HTML
+	// and the table of contents, unless Full asks for everything.
+	Fields []string
+	Full   bool
+	Page   PageReq
+}
+
+// TimeRange matches times from From, inclusive, up to To, exclusive. Either bound may be nil.
+type TimeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+func (r TimeRange) IsZero() bool {
+	return r.From == nil && r.To == nil
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ParsePostFilter reads the query parameters of the post listing:
+//
+//	author=alice,bob              posts of any of the authors, username is an alias
+//	tag=go                        posts with the tag
+//	tags_all=go,mongodb           posts with all of the tags
+//	tags_any=go,rust              posts with at least one of the tags
+//	category=backend              posts in the category, id or slug, or one of its subcategories
+//	created=2024-01-01..2024-03-31
+//	updated=2024-01-01T10:00:00Z..
+//	published=..2024-03-31        ranges of dates or RFC 3339 times, either end may be left open, a single
+//	                              date matches that day and a single time that second, date is an alias
+//	                              for created
+//	sort=-published_at,title      sort fields, - for descending, newest first by default
+//	fields=title,author           only return these fields, the id is always included
+//	full=true                     return the content with the other fields instead of a summary
+//	cursor=...&limit=20&total=1   see ParsePage
+//
+// Errors wrap utils.ErrBadRequest and explain which parameter is wrong.
+func ParsePostFilter(q url.Values) (PostFilter, error) {
+	filter := PostFilter{
+		Authors:  splitValues(q["author"], q["username"]),
+		Tag:      q.Get("tag"),
+		AllTags:  splitValues(q["tags_all"]),
+		AnyTags:  splitValues(q["tags_any"]),
+		Category: q.Get("category"),
+	}
+
+	var err error
+	created := q.Get("created")
+	if created == "" {
+		created = q.Get("date")
+	}
+	if filter.Created, err = ParseTimeRange("created", created); err != nil {
+		return filter, err
+	}
+	if filter.Updated, err = ParseTimeRange("updated", q.Get("updated")); err != nil {
+		return filter, err
+	}
+	if filter.Published, err = ParseTimeRange("published", q.Get("published")); err != nil {
+		return filter, err
+	}
+
+	if filter.Sort, err = ParseSort(q.Get("sort"), PostSortFields); err != nil {
+		return filter, err
+	}
+	if filter.Fields, err = ParseFields(q.Get("fields"), PostFields); err != nil {
+		return filter, err
+	}
+	if v := q.Get("full"); v != "" {
+		if filter.Full, err = strconv.ParseBool(v); err != nil {
+			return filter, fmt.Errorf("%w: full must be true or false", utils.ErrBadRequest)
+		}
+	}
+
+	filter.Page, err = ParsePage(q)
+	return filter, err
+}
+
+// ParseTimeRange reads from..to. A date as the upper bound includes that whole day. A single date matches
+// that day and a single time the second it names, or the millisecond, the precision of stored times, when it
+// has fractional seconds.
+func ParseTimeRange(name, value string) (TimeRange, error) {
+	var r TimeRange
+	if value == "" {
+		return r, nil
+	}
+	from, to, isRange := strings.Cut(value, "..")
+	if !isRange {
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return singleTime(name, value)
+		}
+		to = from
+	}
+
+	var err error
+	if from != "" {
+		if r.From, err = parseTime(from, false); err != nil {
+			return r, fmt.Errorf("%w: %s: %v", utils.ErrBadRequest, name, err)
+		}
+	}
+	if to != "" {
+		if r.To, err = parseTime(to, true); err != nil {
+			return r, fmt.Errorf("%w: %s: %v", utils.ErrBadRequest, name, err)
+		}
+	}
+	if r.From != nil && r.To != nil && !r.From.Before(*r.To) {
+		return r, fmt.Errorf("%w: %s: the range %q is empty", utils.ErrBadRequest, name, value)
+	}
+	return r, nil
+}
+
+// singleTime matches the second or the millisecond an RFC 3339 time names.
+func singleTime(name, value string) (TimeRange, error) {
+	t, err := parseTime(value, false)
+	if err != nil {
+		return TimeRange{}, fmt.Errorf("%w: %s: %v", utils.ErrBadRequest, name, err)
+	}
+	unit := time.Second
+	if strings.Contains(value, ".") {
+		unit = time.Millisecond
+	}
+	from := t.Truncate(unit)
+	to := from.Add(unit)
+	return TimeRange{From: &from, To: &to}, nil
+}
+
+func parseTime(value string, upper bool) (*time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a date (2006-01-02) nor an RFC 3339 time (2006-01-02T15:04:05Z)", value)
+	}
+	return &t, nil
+}
+
+// ParseSort reads comma separated field names, a leading - sorts descending.
+func ParseSort(value string, allowed map[string]string) ([]SortField, error) {
+	var fields []SortField
+	seen := map[string]bool{}
+	for _, name := range splitValues([]string{value}) {
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "-"), "+")
+		if _, ok := allowed[name]; !ok {
+			return nil, fmt.Errorf("%w: can not sort by %q, sortable fields are %s", utils.ErrBadRequest, name, fieldNames(allowed))
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: sort field %q is given twice", utils.ErrBadRequest, name)
+		}
+		seen[name] = true
+		fields = append(fields, SortField{Field: name, Desc: desc})
+	}
+	return fields, nil
+}
+
+// ParseFields reads a comma separated list of field names.
+func ParseFields(value string, allowed map[string]string) ([]string, error) {
+	fields := splitValues([]string{value})
+	for _, name := range fields {
+		if _, ok := allowed[name]; !ok {
+			return nil, fmt.Errorf("%w: unknown field %q, fields are %s", utils.ErrBadRequest, name, fieldNames(allowed))
+		}
+	}
+	return fields, nil
+}
+
+// SelectPostFields reduces the posts to the selected fields and their id, keyed by the JSON field names.
+func SelectPostFields(posts []repoModels.Post, fields []string) ([]map[string]interface{}, error) {
+	keep := map[string]bool{"id": true}
+	for _, f := range fields {
+		keep[f] = true
+	}
+
+	selected := make([]map[string]interface{}, 0, len(posts))
+	for _, post := range posts {
+		raw, err := json.Marshal(post)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]interface{}
+		if err = json.Unmarshal(raw, &all); err != nil {
+			return nil, err
+		}
+		for key := range all {
+			if !keep[key] {
+				delete(all, key)
+			}
+		}
+		selected = append(selected, all)
+	}
+	return selected, nil
+}
+
+// splitValues splits comma separated values of repeated parameters, dropping empty ones.
+func splitValues(params ...[]string) []string {
+	var values []string
+	for _, param := range params {
+		for _, p := range param {
+			for _, v := range strings.Split(p, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+	return values
+}
+
+func fieldNames(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
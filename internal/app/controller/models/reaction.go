@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReactionsRes counts the reactions to a post per emoji, Mine lists the emoji the user reacted with.
+type ReactionsRes struct {
+	PostID    primitive.ObjectID `json:"post_id"`
+	Reactions map[string]int64   `json:"reactions"`
+	Count     int64              `json:"count"`
+	Mine      []string           `json:"mine"`
+}
+
+type ReactionSettingsReq struct {
+	Emoji []string `json:"emoji" binding:"required"`
+}
+
+// BookmarkRes is a bookmarked post with the time it was bookmarked.
+type BookmarkRes struct {
+	BookmarkedAt time.Time       `json:"bookmarked_at"`
+	Post         repoModels.Post `json:"post"`
+}
+
+type ListBookmarkRes struct {
+	Data     []BookmarkRes
+	Metadata repoModels.ListMetaData
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/search"
+)
+
+// SearchReq is a full-text query, Q supports "quoted phrases" and prefix* words. From and To are
+// dates (2006-01-02), To is inclusive.
+type SearchReq struct {
+	Q      string   `form:"q"`
+	Tags   []string `form:"tag"`
+	Author string   `form:"author"`
+	From   string   `form:"from"`
+	To     string   `form:"to"`
+	Page   int      `form:"page"`
+	Limit  int      `form:"limit"`
+}
+
+type SearchRes struct {
+	Total  int64         `json:"total"`
+	Page   int           `json:"page"`
+	Limit  int           `json:"limit"`
+	Hits   []SearchHit   `json:"hits"`
+	Facets search.Facets `json:"facets"`
+}
+
+// SearchHit is a matching post, Highlights holds HTML fragments per field with the matches wrapped in <mark>.
+type SearchHit struct {
+	Post       repoModels.Post     `json:"post"`
+	Score      float64             `json:"score"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
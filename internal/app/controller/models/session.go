@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+	"github.com/gin-gonic/gin"
+)
+
+type LoginReq struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	OTP      string `json:"otp"`
+}
+
+// SessionRes is returned when a session starts, CSRFToken has to be sent in the X-CSRF-Token header
+// of every state changing request made with the session cookie.
+type SessionRes struct {
+	User      repoModels.User `json:"user"`
+	CSRFToken string          `json:"csrf_token"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// OIDCLoginStart is a started single sign-on login: the provider URL to send the browser to and the token that
+// ties the callback to that browser, it expires with the login.
+type OIDCLoginStart struct {
+	RedirectURL  string
+	BrowserToken string
+	ExpiresAt    time.Time
+}
+
+// ClientInfo describes where a session was started from, shown in the session listing.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+func NewClientInfo(ctx *gin.Context) ClientInfo {
+	return ClientInfo{UserAgent: ctx.Request.UserAgent(), IP: ctx.ClientIP()}
+}
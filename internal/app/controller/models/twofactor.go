@@ -0,0 +1,19 @@
+package models
+
+type TwoFactorEnrollmentRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCodeURL       string `json:"qr_code_url"`
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type RecoveryCodesRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorPolicyReq struct {
+	Roles []string `json:"roles"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	repoModels "blog-platform/internal/app/repositories/models"
@@ -15,10 +16,19 @@ type UserAccess struct {
 	Role *string            `json:"role"`
 }
 
+// PostReq is the body of post writes. Published false makes the post a draft and true publishes it, left out
+// new posts are published and existing ones keep their state.
 type PostReq struct {
-	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Title   string             `bson:"title" json:"title"`
-	Content string             `bson:"content" json:"content"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Title         string              `bson:"title" json:"title"`
+	Slug          string              `bson:"slug" json:"slug"`
+	Content       string              `bson:"content" json:"content"`
+	ContentFormat string              `bson:"content_format" json:"content_format"`
+	Excerpt       string              `bson:"excerpt" json:"excerpt"`
+	Tags          []string            `bson:"tags" json:"tags"`
+	CategoryID    *primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	SEO           repoModels.PostSEO  `bson:"seo" json:"seo"`
+	Published     *bool               `bson:"-" json:"published,omitempty"`
 }
 
 type ListPostReq struct {
@@ -26,10 +36,79 @@ type ListPostReq struct {
 	Metadata repoModels.ListMetaData
 }
 
+type ListUserRes struct {
+	Data     []repoModels.User
+	Metadata repoModels.ListMetaData
+}
+
+// UserRes is a user as the user API shows it. The fields after CreatedAt are private to the user and admins,
+// they are left out for everybody else.
+type UserRes struct {
+	ID                 primitive.ObjectID    `json:"id"`
+	Username           string                `json:"username"`
+	Role               string                `json:"role"`
+	FollowerCount      int64                 `json:"follower_count"`
+	FollowingCount     int64                 `json:"following_count"`
+	CreatedAt          time.Time             `json:"created_at"`
+	Email              string                `json:"email,omitempty"`
+	EmailVerifiedAt    *time.Time            `json:"email_verified_at,omitempty"`
+	Status             string                `json:"status,omitempty"`
+	StatusReason       string                `json:"status_reason,omitempty"`
+	SuspendedUntil     *time.Time            `json:"suspended_until,omitempty"`
+	MustChangePassword bool                  `json:"must_change_password,omitempty"`
+	TwoFactor          *repoModels.TwoFactor `json:"two_factor,omitempty"`
+	Identities         []repoModels.Identity `json:"identities,omitempty"`
+	DeletedAt          *time.Time            `json:"deleted_at,omitempty"`
+}
+
+// NewUserRes shows the user to access.
+func NewUserRes(user repoModels.User, access UserAccess) UserRes {
+	res := UserRes{
+		ID:             user.ID,
+		Username:       user.Username,
+		Role:           user.Role,
+		FollowerCount:  user.FollowerCount,
+		FollowingCount: user.FollowingCount,
+		CreatedAt:      user.CreatedAt,
+	}
+	if user.ID != access.ID && !access.IsAdmin() {
+		return res
+	}
+	res.Email, res.EmailVerifiedAt = user.Email, user.EmailVerifiedAt
+	res.Status, res.StatusReason, res.SuspendedUntil = user.Status, user.StatusReason, user.SuspendedUntil
+	res.MustChangePassword, res.TwoFactor, res.Identities = user.MustChangePassword, user.TwoFactor, user.Identities
+	res.DeletedAt = user.DeletedAt
+	return res
+}
+
+type ListPublicUserRes struct {
+	Data     []UserRes
+	Metadata repoModels.ListMetaData
+}
+
+// SparseListPostRes is returned instead of ListPostReq when fields are selected.
+type SparseListPostRes struct {
+	Data     []map[string]interface{}
+	Metadata repoModels.ListMetaData
+}
+
 type UserReq struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Username string             `bson:"username" json:"username"`
-	Password string             `bson:"password" json:"-"`
+	Password string             `bson:"password" json:"password"`
+	Email    string             `bson:"email" json:"email" binding:"omitempty,email"`
+}
+
+// UserUpdateReq holds the fields a user may change on their own account.
+// Changing the email clears its verification.
+type UserUpdateReq struct {
+	Username string `json:"username"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 func (userA *UserAccess) GetUserFromCtx(ctx *gin.Context) error {
@@ -65,23 +144,34 @@ func (userA *UserAccess) GetUserFromCtx(ctx *gin.Context) error {
 	if !ok {
 		return errors.New("role not found")
 	}
-	if nRole, err := role.(string); err {
-		userA.Role = nil
-	} else {
+	if nRole, ok := role.(string); ok {
 		userA.Role = &nRole
+	} else {
+		userA.Role = nil
 	}
 
 	return nil
 }
 
+func (userA *UserAccess) IsAdmin() bool {
+	return userA.Role != nil && *userA.Role == repoModels.RoleAdmin
+}
+
 func CreatePostFromReq(req PostReq, userAccess UserAccess) repoModels.Post {
 	now := time.Now()
 	return repoModels.Post{
-		ID:        primitive.NewObjectID(),
-		Title:     req.Title,
-		Content:   req.Content,
-		UpdatedAt: now,
-		CreatedAt: now,
+		ID:            primitive.NewObjectID(),
+		Title:         req.Title,
+		Slug:          req.Slug,
+		Content:       req.Content,
+		ContentFormat: req.ContentFormat,
+		Excerpt:       req.Excerpt,
+		Tags:          req.Tags,
+		CategoryID:    req.CategoryID,
+		SEO:           req.SEO,
+		UpdatedAt:     now,
+		CreatedAt:     now,
+		PublishedAt:   PublishedAt(req.Published, now, now),
 		Author: repoModels.BasicUser{
 			ID:       userAccess.ID,
 			Username: userAccess.Name,
@@ -89,13 +179,29 @@ func CreatePostFromReq(req PostReq, userAccess UserAccess) repoModels.Post {
 	}
 }
 
+// PublishedAt returns the publication time of a post published at current, zero for drafts, after a request
+// with published. Publishing a draft publishes it now, published posts keep their time.
+func PublishedAt(published *bool, current, now time.Time) time.Time {
+	switch {
+	case published == nil:
+		return current
+	case !*published:
+		return time.Time{}
+	case current.IsZero():
+		return now
+	}
+	return current
+}
+
 func CreateUserFromReq(req UserReq) repoModels.User {
 	now := time.Now()
 	return repoModels.User{
 		ID:        primitive.NewObjectID(),
 		Username:  req.Username,
 		Password:  req.Password,
-		Role:      "user", //admin user has to be created directly on DB or should have separate API non-public facing
+		Email:     strings.ToLower(strings.TrimSpace(req.Email)),
+		Role:      repoModels.RoleUser, // promotion goes through the admin API
+		Status:    repoModels.UserStatusActive,
 		CreatedAt: now,
 	}
 }
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	repoModels "blog-platform/internal/app/repositories/models"
+)
+
+// PageMeta describes a page of the reading site to browsers, search engines and link previews. The URLs are
+// absolute. Type is the OpenGraph type, website or article, and Published is only set for articles.
+type PageMeta struct {
+	Title        string
+	Description  string
+	CanonicalURL string
+	Image        string
+	Type         string
+	NoIndex      bool
+	FeedURL      string
+	Published    time.Time
+}
+
+// PostListPage is a page of posts, newest first: the home page or the page of an author or a tag. PrevURL and
+// NextURL link the neighbouring pages, Metadata holds their cursors when the list is paged by cursor.
+type PostListPage struct {
+	Meta     PageMeta
+	Heading  string
+	Author   string
+	Tag      string
+	Posts    []repoModels.Post
+	Metadata repoModels.ListMetaData
+	PrevURL  string
+	NextURL  string
+}
+
+// PostPage shows a post.
+type PostPage struct {
+	Meta PageMeta
+	Post repoModels.Post
+}
+
+// SearchPage shows the results of a search, Result is empty until a query is given.
+type SearchPage struct {
+	Meta   PageMeta
+	Query  SearchReq
+	Result SearchRes
+	// PrevPage and NextPage are the numbers of the neighbouring pages of results, 0 at either end.
+	PrevPage int
+	NextPage int
+}
+
+// ErrorPage tells a reader that a page could not be shown.
+type ErrorPage struct {
+	Meta    PageMeta
+	Status  int
+	Message string
+}
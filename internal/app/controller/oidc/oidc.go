@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	StartLogin(ctx context.Context, loginHint string) (models.OIDCLoginStart, error)
+	FinishLogin(ctx context.Context, code, state, browserToken string, info models.ClientInfo) (models.SessionRes, string, error)
+}
+
+type Controller struct {
+	service Service
+	cfg     config.SessionConfig
+}
+
+func New(service Service, cfg config.SessionConfig) *Controller {
+	return &Controller{service, cfg}
+}
+
+// Login godoc
+// @Summary Start single sign-on
+// @Description Redirect to the OpenID Connect provider using the authorization code flow with PKCE
+// @Description A short-lived oidc_login cookie ties the callback to the browser starting the login
+// @Tags auth
+// @Param login_hint query string false "Account hint passed on to the provider"
+// @Success 302
+// @Failure 500 {object} gin.H
+// @Router /auth/oidc/login [get]
+func (c *Controller) Login(ctx *gin.Context) {
+	login, err := c.service.StartLogin(ctx, ctx.Query("login_hint"))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	utils.SetOIDCLoginCookie(ctx, c.cfg, login.BrowserToken, login.ExpiresAt)
+	ctx.Redirect(http.StatusFound, login.RedirectURL)
+}
+
+// Callback godoc
+// @Summary Finish single sign-on
+// @Description Redirect target of the provider, links or provisions the user and starts a browser session
+// @Description Only the browser holding the oidc_login cookie of the login can finish it
+// @Tags auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} models.SessionRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/oidc/callback [get]
+func (c *Controller) Callback(ctx *gin.Context) {
+	browserToken, _ := ctx.Cookie(utils.OIDCLoginCookie)
+	utils.ClearOIDCLoginCookie(ctx, c.cfg)
+	if errCode := ctx.Query("error"); errCode != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "single sign-on failed: " + errCode})
+		return
+	}
+	code, state := ctx.Query("code"), ctx.Query("state")
+	if code == "" || state == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required"})
+		return
+	}
+	if browserToken == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "single sign-on failed: the login was not started in this browser"})
+		return
+	}
+
+	res, token, err := c.service.FinishLogin(ctx, code, state, browserToken, models.NewClientInfo(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	utils.SetSessionCookie(ctx, c.cfg, token)
+	ctx.JSON(http.StatusOK, res)
+}
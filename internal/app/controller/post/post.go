@@ -5,7 +5,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
-	"strconv"
+	"net/url"
+	"path"
 
 	"blog-platform/internal/app/controller/models"
 	repoModels "blog-platform/internal/app/repositories/models"
@@ -14,10 +15,11 @@ import (
 
 //go:generate mockery --name=Service --case underscore
 type Service interface {
-	CreatePost(post repoModels.Post) error
-	GetPosts(ctx context.Context, author, date string, page, limit int) ([]repoModels.Post, *repoModels.ListMetaData, error)
-	GetPostByID(id primitive.ObjectID) (repoModels.Post, error)
-	UpdatePost(post repoModels.Post, access models.UserAccess) error
+	CreatePost(post repoModels.Post) (repoModels.Post, error)
+	GetPosts(ctx context.Context, filter models.PostFilter) ([]repoModels.Post, *repoModels.ListMetaData, error)
+	GetPostByID(id primitive.ObjectID, access models.UserAccess) (repoModels.Post, error)
+	GetPostBySlug(username, slug string) (repoModels.Post, bool, error)
+	UpdatePost(id primitive.ObjectID, req models.PostReq, access models.UserAccess) (repoModels.Post, error)
 	DeletePost(id primitive.ObjectID, access models.UserAccess) error
 }
 
@@ -54,9 +56,9 @@ func (c *Controller) CreatePost(ctx *gin.Context) {
 		return
 	}
 
-	pModel := models.CreatePostFromReq(req, userAccess)
-	if err = c.service.CreatePost(pModel); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	pModel, err := c.service.CreatePost(models.CreatePostFromReq(req, userAccess))
+	if err != nil {
+		utils.HandleError(ctx, err)
 		return
 	}
 
@@ -65,25 +67,50 @@ func (c *Controller) CreatePost(ctx *gin.Context) {
 
 // GetPosts godoc
 // @Summary Get all posts
-// @Description Get a list of all posts with optional filters
+// @Description Get a list of post summaries, newest first, with optional filters, ordering and field selection.
+// @Description Summaries leave out content, content_html and toc, full=true returns them as well.
+// @Description Ranges are from..to with dates (2006-01-02) or RFC 3339 times, either end may be open, a single date matches that day and a single time that second.
 // @Tags posts
 // @Produce json
-// @Param username query string false "Author username"
-// @Param date query string false "Creation date"
-// @Param page query int false "Page number"
-// @Param limit query int false "Page size"
+// @Param author query string false "Comma separated author usernames (username is an alias)"
+// @Param created query string false "Creation range, e.g. 2024-01-01..2024-03-31 (date is an alias)"
+// @Param updated query string false "Last update range"
+// @Param published query string false "Publication range"
+// @Param tag query string false "Tag"
+// @Param tags_all query string false "Comma separated tags that must all be present"
+// @Param tags_any query string false "Comma separated tags of which one must be present"
+// @Param category query string false "Category id or slug, includes subcategories"
+// @Param sort query string false "Comma separated fields, - for descending: created_at, updated_at, published_at, title, slug, author, popularity"
+// @Param fields query string false "Comma separated fields to return, the id is always included"
+// @Param full query bool false "Return the full posts instead of summaries"
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the matching posts"
 // @Success 200 {object} models.ListPostReq
+// @Header 200 {string} Link "Links to the next and previous page"
+// @Failure 400 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /posts [get]
 func (c *Controller) GetPosts(ctx *gin.Context) {
-	username := ctx.Query("username")
-	date := ctx.Query("date")
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	filter, err := models.ParsePostFilter(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
 
-	posts, pagi, err := c.service.GetPosts(ctx, username, date, page, limit)
+	posts, pagi, err := c.service.GetPosts(ctx, filter)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.HandleError(ctx, err)
+		return
+	}
+	models.SetPageLinks(ctx, pagi)
+	if len(filter.Fields) > 0 {
+		sparse, err := models.SelectPostFields(posts, filter.Fields)
+		if err != nil {
+			utils.HandleError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, models.SparseListPostRes{Data: sparse, Metadata: *pagi})
 		return
 	}
 	ctx.JSON(http.StatusOK, models.ListPostReq{Data: posts, Metadata: *pagi})
@@ -91,12 +118,13 @@ func (c *Controller) GetPosts(ctx *gin.Context) {
 
 // GetPost godoc
 // @Summary Get a post by ID
-// @Description Get details of a post by ID
+// @Description Get details of a post by ID, drafts are only found by their author and admins
 // @Tags posts
 // @Produce json
 // @Param id path string true "Post ID"
 // @Success 200 {object} repoModels.Post
 // @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /posts/{id} [get]
 func (c *Controller) GetPost(ctx *gin.Context) {
@@ -107,9 +135,41 @@ func (c *Controller) GetPost(ctx *gin.Context) {
 		return
 	}
 
-	resPost, err := c.service.GetPostByID(id)
+	userAccess := models.UserAccess{}
+	if err = userAccess.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	resPost, err := c.service.GetPostByID(id, userAccess)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, resPost)
+}
+
+// GetPostBySlug godoc
+// @Summary Get a post by author and slug
+// @Description Old slugs of a post permanently redirect to its current slug
+// @Tags posts
+// @Produce json
+// @Param username path string true "Author username"
+// @Param slug path string true "Post slug"
+// @Success 200 {object} repoModels.Post
+// @Success 301
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /authors/{username}/posts/{slug} [get]
+func (c *Controller) GetPostBySlug(ctx *gin.Context) {
+	resPost, moved, err := c.service.GetPostBySlug(ctx.Param("username"), ctx.Param("slug"))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	if moved {
+		location := url.URL{Path: path.Join(path.Dir(ctx.Request.URL.Path), resPost.Slug), RawQuery: ctx.Request.URL.RawQuery}
+		ctx.Redirect(http.StatusMovedPermanently, location.String())
 		return
 	}
 	ctx.JSON(http.StatusOK, resPost)
@@ -149,9 +209,8 @@ func (c *Controller) UpdatePost(ctx *gin.Context) {
 		return
 	}
 
-	var updatePost repoModels.Post
-	updatePost.ID = id
-	if err = c.service.UpdatePost(updatePost, userAccess); err != nil {
+	updatePost, err := c.service.UpdatePost(id, req, userAccess)
+	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
@@ -0,0 +1,144 @@
+package reaction
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	GetReactions(postID primitive.ObjectID, access models.UserAccess) (models.ReactionsRes, error)
+	React(postID primitive.ObjectID, emoji string, access models.UserAccess) (models.ReactionsRes, error)
+	Unreact(postID primitive.ObjectID, emoji string, access models.UserAccess) (models.ReactionsRes, error)
+	GetReactionSettings() (repoModels.ReactionSettings, error)
+	SetReactionSettings(req models.ReactionSettingsReq) (repoModels.ReactionSettings, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// GetReactions godoc
+// @Summary Get the reactions to a post
+// @Description The number of reactions per emoji and the emoji the user reacted with
+// @Tags reactions
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.ReactionsRes
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/reactions [get]
+func (c *Controller) GetReactions(ctx *gin.Context) {
+	c.handle(ctx, c.service.GetReactions)
+}
+
+// React godoc
+// @Summary React to a post
+// @Description React with one of the configured emoji, reacting twice with the same emoji changes nothing
+// @Tags reactions
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param emoji path string true "Emoji, URL encoded"
+// @Success 200 {object} models.ReactionsRes
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/reactions/{emoji} [put]
+func (c *Controller) React(ctx *gin.Context) {
+	c.handle(ctx, func(postID primitive.ObjectID, access models.UserAccess) (models.ReactionsRes, error) {
+		return c.service.React(postID, ctx.Param("emoji"), access)
+	})
+}
+
+// Unreact godoc
+// @Summary Take back a reaction to a post
+// @Description Removing a reaction that does not exist changes nothing
+// @Tags reactions
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param emoji path string true "Emoji, URL encoded"
+// @Success 200 {object} models.ReactionsRes
+// @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /posts/{id}/reactions/{emoji} [delete]
+func (c *Controller) Unreact(ctx *gin.Context) {
+	c.handle(ctx, func(postID primitive.ObjectID, access models.UserAccess) (models.ReactionsRes, error) {
+		return c.service.Unreact(postID, ctx.Param("emoji"), access)
+	})
+}
+
+func (c *Controller) handle(ctx *gin.Context, fn func(primitive.ObjectID, models.UserAccess) (models.ReactionsRes, error)) {
+	postID, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	reactions, err := fn(postID, access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, reactions)
+}
+
+// GetReactionSettings godoc
+// @Summary Get the emoji readers can react with (admin)
+// @Tags admin
+// @Produce json
+// @Success 200 {object} repoModels.ReactionSettings
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/settings/reactions [get]
+func (c *Controller) GetReactionSettings(ctx *gin.Context) {
+	settings, err := c.service.GetReactionSettings()
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
+
+// SetReactionSettings godoc
+// @Summary Change the emoji readers can react with (admin)
+// @Description Reactions with emoji that are left out keep counting and can still be taken back
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param settings body models.ReactionSettingsReq true "Settings"
+// @Success 200 {object} repoModels.ReactionSettings
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/settings/reactions [put]
+func (c *Controller) SetReactionSettings(ctx *gin.Context) {
+	var req models.ReactionSettingsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := c.service.SetReactionSettings(req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, settings)
+}
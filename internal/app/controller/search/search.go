@@ -0,0 +1,55 @@
+package search
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Search(ctx context.Context, req models.SearchReq) (models.SearchRes, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Search godoc
+// @Summary Search posts
+// @Description Relevance ranked full-text search over title, content and tags with facets and highlighted snippets.
+// @Description Use "quoted phrases" for exact phrases and word* for prefixes, without q the newest posts come first.
+// @Tags posts
+// @Produce json
+// @Param q query string false "Query"
+// @Param tag query []string false "Tags that must all be present" collectionFormat(multi)
+// @Param author query string false "Author username"
+// @Param from query string false "First creation date (2006-01-02)"
+// @Param to query string false "Last creation date (2006-01-02)"
+// @Param page query int false "Page number"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} models.SearchRes
+// @Failure 400 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /search [get]
+func (c *Controller) Search(ctx *gin.Context) {
+	var req models.SearchReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := c.service.Search(ctx, req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,133 @@
+package seo
+
+import (
+	"bytes"
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-platform/internal/sitemap"
+	"blog-platform/internal/utils"
+)
+
+const (
+	// sitemapMaxAge is how long crawlers and proxies may cache a sitemap without asking again.
+	sitemapMaxAge = time.Hour
+	// robotsMaxAge is how long robots.txt may be cached, it only changes with the configuration.
+	robotsMaxAge = 24 * time.Hour
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Robots() string
+	SitemapIndex(ctx context.Context) ([]sitemap.URL, error)
+	Sitemap(ctx context.Context, kind string, page int) ([]sitemap.URL, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Robots godoc
+// @Summary robots.txt
+// @Description Tells crawlers which paths to leave out and where the sitemap is, or to leave out everything when indexing is not allowed
+// @Tags seo
+// @Produce plain
+// @Success 200 {string} string
+// @Router /robots.txt [get]
+func (c *Controller) Robots(ctx *gin.Context) {
+	body := []byte(c.service.Robots())
+	if utils.CachePublic(ctx, utils.ContentETag(body), time.Time{}, robotsMaxAge) {
+		return
+	}
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", body)
+}
+
+// SitemapIndex godoc
+// @Summary Sitemap index
+// @Description Lists the sitemaps of the published posts and the author pages
+// @Tags seo
+// @Produce xml
+// @Success 200
+// @Success 304
+// @Router /sitemap.xml [get]
+func (c *Controller) SitemapIndex(ctx *gin.Context) {
+	sitemaps, err := c.service.SitemapIndex(ctx.Request.Context())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err = sitemap.WriteIndex(&buf, sitemaps); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	writeXML(ctx, buf.Bytes(), sitemaps)
+}
+
+// Sitemap godoc
+// @Summary Sitemap
+// @Description A page of the sitemap of the published posts (posts-1.xml, posts-2.xml, ...) or the author pages (authors-1.xml, ...)
+// @Tags seo
+// @Produce xml
+// @Param file path string true "posts-N.xml or authors-N.xml"
+// @Success 200
+// @Success 304
+// @Failure 404 {object} gin.H
+// @Router /sitemaps/{file} [get]
+func (c *Controller) Sitemap(ctx *gin.Context) {
+	kind, page, ok := parseSitemapFile(ctx.Param("file"))
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "sitemaps are named like posts-1.xml and authors-1.xml"})
+		return
+	}
+	urls, err := c.service.Sitemap(ctx.Request.Context(), kind, page)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err = sitemap.WriteSitemap(&buf, urls); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	writeXML(ctx, buf.Bytes(), urls)
+}
+
+// parseSitemapFile reads the kind and the page number from a name like posts-2.xml.
+func parseSitemapFile(file string) (string, int, bool) {
+	name, ok := strings.CutSuffix(file, ".xml")
+	if !ok {
+		return "", 0, false
+	}
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return "", 0, false
+	}
+	page, err := strconv.Atoi(name[i+1:])
+	if err != nil || page < 1 || strconv.Itoa(page) != name[i+1:] {
+		return "", 0, false
+	}
+	return name[:i], page, true
+}
+
+// writeXML answers with the sitemap, or with 304 Not Modified when the crawler has this version.
+func writeXML(ctx *gin.Context, body []byte, urls []sitemap.URL) {
+	var lastModified time.Time
+	for _, u := range urls {
+		if u.LastModified.After(lastModified) {
+			lastModified = u.LastModified
+		}
+	}
+	if utils.CachePublic(ctx, utils.ContentETag(body), lastModified, sitemapMaxAge) {
+		return
+	}
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
@@ -0,0 +1,194 @@
+package session
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Login(req models.LoginReq, info models.ClientInfo) (models.SessionRes, string, error)
+	GetSession(current primitive.ObjectID) (models.SessionRes, error)
+	Logout(current primitive.ObjectID) error
+	GetSessions(access models.UserAccess, current primitive.ObjectID) ([]repoModels.Session, error)
+	RevokeSession(id primitive.ObjectID, access models.UserAccess) error
+	RevokeOtherSessions(access models.UserAccess, current primitive.ObjectID) (int64, error)
+}
+
+type Controller struct {
+	service Service
+	cfg     config.SessionConfig
+}
+
+func New(service Service, cfg config.SessionConfig) *Controller {
+	return &Controller{service, cfg}
+}
+
+// Login godoc
+// @Summary Sign in with a session cookie
+// @Description Start a browser session, otp is required for users with two-factor authentication
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param login body models.LoginReq true "Credentials"
+// @Success 200 {object} models.SessionRes
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/login [post]
+func (c *Controller) Login(ctx *gin.Context) {
+	var req models.LoginReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, token, err := c.service.Login(req, models.NewClientInfo(ctx))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	utils.SetSessionCookie(ctx, c.cfg, token)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetSession godoc
+// @Summary Current session
+// @Description Returns the signed in user and the CSRF token of the session cookie
+// @Tags sessions
+// @Produce json
+// @Success 200 {object} models.SessionRes
+// @Failure 400 {object} gin.H
+// @Failure 401 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/session [get]
+func (c *Controller) GetSession(ctx *gin.Context) {
+	current, ok := currentSession(ctx)
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "not signed in with a session"})
+		return
+	}
+
+	res, err := c.service.GetSession(current)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Logout godoc
+// @Summary Sign out
+// @Description End the current session and clear the cookie
+// @Tags sessions
+// @Param X-CSRF-Token header string true "CSRF token"
+// @Success 204
+// @Failure 401 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /auth/logout [post]
+func (c *Controller) Logout(ctx *gin.Context) {
+	if current, ok := currentSession(ctx); ok {
+		if err := c.service.Logout(current); err != nil {
+			utils.HandleError(ctx, err)
+			return
+		}
+	}
+	utils.ClearSessionCookie(ctx, c.cfg)
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetSessions godoc
+// @Summary List my active sessions
+// @Tags sessions
+// @Produce json
+// @Success 200 {array} repoModels.Session
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/sessions [get]
+func (c *Controller) GetSessions(ctx *gin.Context) {
+	access := models.UserAccess{}
+	err := access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	current, _ := currentSession(ctx)
+	sessions, err := c.service.GetSessions(access, current)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Tags sessions
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 404 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/sessions/{id} [delete]
+func (c *Controller) RevokeSession(ctx *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format"})
+		return
+	}
+
+	access := models.UserAccess{}
+	err = access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	if err = c.service.RevokeSession(id, access); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// RevokeOtherSessions godoc
+// @Summary Sign out everywhere else
+// @Description Revoke every session of the user except the current one
+// @Tags sessions
+// @Produce json
+// @Success 200 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/sessions [delete]
+func (c *Controller) RevokeOtherSessions(ctx *gin.Context) {
+	access := models.UserAccess{}
+	err := access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	current, _ := currentSession(ctx)
+	revoked, err := c.service.RevokeOtherSessions(access, current)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"revoked": revoked})
+}
+
+// currentSession returns the session the request was authenticated with, set by AuthMiddleware.
+func currentSession(ctx *gin.Context) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(ctx.GetString("SessionID"))
+	return id, err == nil
+}
@@ -0,0 +1,99 @@
+package tag
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	GetTags(ctx context.Context, prefix string) ([]repoModels.TagCount, error)
+	RenameTag(ctx context.Context, tag string, req models.RenameTagReq) (models.TagChangeRes, error)
+	MergeTags(ctx context.Context, req models.MergeTagsReq) (models.TagChangeRes, error)
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// GetTags godoc
+// @Summary List tags
+// @Description Tags in use with the number of posts, most used first
+// @Tags tags
+// @Produce json
+// @Param prefix query string false "Only tags starting with prefix"
+// @Success 200 {array} repoModels.TagCount
+// @Failure 500 {object} gin.H
+// @Router /tags [get]
+func (c *Controller) GetTags(ctx *gin.Context) {
+	tags, err := c.service.GetTags(ctx, ctx.Query("prefix"))
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tags)
+}
+
+// RenameTag godoc
+// @Summary Rename a tag (admin)
+// @Description Renaming to a tag that is already in use merges both
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag path string true "Tag"
+// @Param name body models.RenameTagReq true "New name"
+// @Success 200 {object} models.TagChangeRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/tags/{tag} [put]
+func (c *Controller) RenameTag(ctx *gin.Context) {
+	var req models.RenameTagReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := c.service.RenameTag(ctx, ctx.Param("tag"), req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// MergeTags godoc
+// @Summary Merge tags (admin)
+// @Description Replace each of the tags with the target tag on every post
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param merge body models.MergeTagsReq true "Tags to merge"
+// @Success 200 {object} models.TagChangeRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /admin/tags/merge [post]
+func (c *Controller) MergeTags(ctx *gin.Context) {
+	var req models.MergeTagsReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := c.service.MergeTags(ctx, req)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, res)
+}
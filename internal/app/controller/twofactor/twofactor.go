@@ -0,0 +1,179 @@
+package twofactor
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+
+	"blog-platform/internal/app/controller/models"
+	"blog-platform/internal/utils"
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Enroll(access models.UserAccess) (models.TwoFactorEnrollmentRes, error)
+	QRCode(access models.UserAccess) ([]byte, error)
+	Confirm(access models.UserAccess, code string) ([]string, error)
+	RegenerateRecoveryCodes(access models.UserAccess, code string) ([]string, error)
+	Disable(access models.UserAccess, code string) error
+}
+
+type Controller struct {
+	service Service
+}
+
+func New(service Service) *Controller {
+	return &Controller{service}
+}
+
+// Enroll godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret, it has to be confirmed with a code before it is enforced
+// @Tags two-factor
+// @Produce json
+// @Success 201 {object} models.TwoFactorEnrollmentRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/2fa/enroll [post]
+func (c *Controller) Enroll(ctx *gin.Context) {
+	access, ok := accessFromCtx(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.service.Enroll(access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	res.QRCodeURL = "/api/v1/user/2fa/qr.png"
+	ctx.JSON(http.StatusCreated, res)
+}
+
+// QRCode godoc
+// @Summary QR code of the pending enrollment
+// @Tags two-factor
+// @Produce png
+// @Success 200 {file} binary
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/2fa/qr.png [get]
+func (c *Controller) QRCode(ctx *gin.Context) {
+	access, ok := accessFromCtx(ctx)
+	if !ok {
+		return
+	}
+
+	png, err := c.service.QRCode(access)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "image/png", png)
+}
+
+// Confirm godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a code from the authenticator, returns the recovery codes once
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param code body models.TwoFactorCodeReq true "Code"
+// @Success 200 {object} models.RecoveryCodesRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/2fa/confirm [post]
+func (c *Controller) Confirm(ctx *gin.Context) {
+	access, req, ok := bindCode(ctx)
+	if !ok {
+		return
+	}
+
+	codes, err := c.service.Confirm(access, req.Code)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes, needs a current authenticator code
+// @Tags two-factor
+// @Accept json
+// @Produce json
+// @Param code body models.TwoFactorCodeReq true "Code"
+// @Success 200 {object} models.RecoveryCodesRes
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/2fa/recovery-codes [post]
+func (c *Controller) RegenerateRecoveryCodes(ctx *gin.Context) {
+	access, req, ok := bindCode(ctx)
+	if !ok {
+		return
+	}
+
+	codes, err := c.service.RegenerateRecoveryCodes(access, req.Code)
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, models.RecoveryCodesRes{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary Disable two-factor authentication
+// @Description Remove the enrollment, needs an authenticator or recovery code once enabled
+// @Tags two-factor
+// @Accept json
+// @Param code body models.TwoFactorCodeReq false "Code"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /user/2fa [delete]
+func (c *Controller) Disable(ctx *gin.Context) {
+	access, ok := accessFromCtx(ctx)
+	if !ok {
+		return
+	}
+
+	var req models.TwoFactorCodeReq
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := c.service.Disable(access, req.Code); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func accessFromCtx(ctx *gin.Context) (models.UserAccess, bool) {
+	access := models.UserAccess{}
+	if err := access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return access, false
+	}
+	return access, true
+}
+
+func bindCode(ctx *gin.Context) (models.UserAccess, models.TwoFactorCodeReq, bool) {
+	var req models.TwoFactorCodeReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.UserAccess{}, req, false
+	}
+
+	access, ok := accessFromCtx(ctx)
+	return access, req, ok
+}
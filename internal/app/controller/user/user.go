@@ -1,10 +1,11 @@
 package user
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
 	"net/http"
-	"strconv"
 
 	"blog-platform/internal/app/controller/models"
 	repoModels "blog-platform/internal/app/repositories/models"
@@ -14,23 +15,37 @@ import (
 //go:generate mockery --name=Service --case underscore
 type Service interface {
 	CreateUser(user repoModels.User) error
-	GetUsers(page, limit int) ([]repoModels.User, error)
+	GetUsers(page models.PageReq) ([]repoModels.User, *repoModels.ListMetaData, error)
 	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
-	UpdateUser(user repoModels.User, access models.UserAccess) error
+	UpdateUser(id primitive.ObjectID, req models.UserUpdateReq, access models.UserAccess) (repoModels.User, error)
+	ChangePassword(req models.ChangePasswordReq, access models.UserAccess, current primitive.ObjectID) error
 	DeleteUser(id primitive.ObjectID, access models.UserAccess) error
 }
 
+//go:generate mockery --name=EmailVerifier --case underscore
+type EmailVerifier interface {
+	SendEmailVerification(ctx context.Context, user repoModels.User) error
+}
+
 type Controller struct {
-	service Service
+	service  Service
+	verifier EmailVerifier
 }
 
-func New(service Service) *Controller {
-	return &Controller{service}
+func New(service Service, verifier EmailVerifier) *Controller {
+	return &Controller{service, verifier}
+}
+
+// sendVerification does not fail the request, the user can ask for a new mail through /auth/email/resend.
+func (c *Controller) sendVerification(ctx context.Context, user repoModels.User) {
+	if err := c.verifier.SendEmailVerification(ctx, user); err != nil {
+		log.Printf("sending verification mail to user %s: %v", user.ID.Hex(), err)
+	}
 }
 
 // CreateUser godoc
 // @Summary Create a new user
-// @Description Create a new user with the input payload
+// @Description Create a new user with the input payload, a verification mail is sent when an email is given
 // @Tags users
 // @Accept json
 // @Produce json
@@ -51,47 +66,60 @@ func (c *Controller) CreateUser(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.sendVerification(ctx, newUser)
 	ctx.JSON(http.StatusCreated, newUser)
 }
 
 // GetUsers godoc
 // @Summary Get all users
-// @Description Get a list of all users
+// @Description Get a list of all users, oldest first. Email, status and identities are only shown to the user and admins
 // @Tags users
 // @Produce json
-// @Param page query int false "Page number"
-// @Param limit query int false "Page size"
-// @Success 200 {array} repoModels.User
+// @Param cursor query string false "next_cursor or prev_cursor of the previous response"
+// @Param limit query int false "Page size, at most 100"
+// @Param total query bool false "Count the users"
+// @Success 200 {object} models.ListPublicUserRes
+// @Header 200 {string} Link "Links to the next and previous page"
+// @Failure 400 {object} gin.H
 // @Failure 403 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /users [get]
 func (c *Controller) GetUsers(ctx *gin.Context) {
-	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	page, err := models.ParsePage(ctx.Request.URL.Query())
+	if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
 
 	access := models.UserAccess{}
-	err := access.GetUserFromCtx(ctx)
+	err = access.GetUserFromCtx(ctx)
 	if err != nil {
 		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
 		return
 	}
 
-	users, err := c.service.GetUsers(page, limit)
+	users, pagi, err := c.service.GetUsers(page)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.HandleError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, users)
+	res := make([]models.UserRes, len(users))
+	for i, user := range users {
+		res[i] = models.NewUserRes(user, access)
+	}
+	models.SetPageLinks(ctx, pagi)
+	ctx.JSON(http.StatusOK, models.ListPublicUserRes{Data: res, Metadata: *pagi})
 }
 
 // GetUser godoc
 // @Summary Get a user by ID
-// @Description Get details of a user by ID
+// @Description Get details of a user by ID, email, status and identities are only shown to the user and admins
 // @Tags users
 // @Produce json
 // @Param id path string true "User ID"
-// @Success 200 {object} repoModels.User
+// @Success 200 {object} models.UserRes
 // @Failure 400 {object} gin.H
+// @Failure 404 {object} gin.H
 // @Failure 500 {object} gin.H
 // @Router /users/{id} [get]
 func (c *Controller) GetUser(ctx *gin.Context) {
@@ -102,22 +130,28 @@ func (c *Controller) GetUser(ctx *gin.Context) {
 		return
 	}
 
+	access := models.UserAccess{}
+	if err = access.GetUserFromCtx(ctx); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
 	resUser, err := c.service.GetUserByID(id)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		utils.HandleError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, resUser)
+	ctx.JSON(http.StatusOK, models.NewUserRes(resUser, access))
 }
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description Update user details by ID
+// @Description Update user details by ID, role and status can only be changed through the admin API
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
-// @Param user body repoModels.User true "User"
+// @Param user body models.UserUpdateReq true "User"
 // @Success 200 {object} repoModels.User
 // @Failure 400 {object} gin.H
 // @Failure 403 {object} gin.H
@@ -131,7 +165,7 @@ func (c *Controller) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
-	var userUpdate repoModels.User
+	var userUpdate models.UserUpdateReq
 	if err := ctx.ShouldBindJSON(&userUpdate); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -144,12 +178,51 @@ func (c *Controller) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
-	userUpdate.ID = id
-	if err = c.service.UpdateUser(userUpdate, access); err != nil {
+	updated, err := c.service.UpdateUser(id, userUpdate, access)
+	if err != nil {
 		utils.HandleError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, userUpdate)
+	if userUpdate.Email != "" {
+		c.sendVerification(ctx, updated)
+	}
+	ctx.JSON(http.StatusOK, updated)
+}
+
+// ChangePassword godoc
+// @Summary Change own password
+// @Description Change the password of the authenticated user, required after an admin forced a reset
+// @Description Every other browser session of the user is signed out
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param password body models.ChangePasswordReq true "Passwords"
+// @Success 204
+// @Failure 400 {object} gin.H
+// @Failure 403 {object} gin.H
+// @Failure 500 {object} gin.H
+// @Router /users/password [put]
+func (c *Controller) ChangePassword(ctx *gin.Context) {
+	var req models.ChangePasswordReq
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	access := models.UserAccess{}
+	err := access.GetUserFromCtx(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"err": "resource cannot be accessed reason:" + err.Error()})
+		return
+	}
+
+	// the session of the request, if any, stays signed in
+	current, _ := primitive.ObjectIDFromHex(ctx.GetString("SessionID"))
+	if err = c.service.ChangePassword(req, access, current); err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
 }
 
 // DeleteUser godoc
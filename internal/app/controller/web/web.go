@@ -0,0 +1,134 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"blog-platform/internal/app/controller/models"
+	"blog-platform/internal/site"
+	"blog-platform/internal/theme"
+	"blog-platform/internal/utils"
+)
+
+const (
+	// pageMaxAge is how long browsers and proxies may keep a page without asking again.
+	pageMaxAge = time.Minute
+	// assetMaxAge is how long the assets of the theme may be kept.
+	assetMaxAge = time.Hour
+)
+
+//go:generate mockery --name=Service --case underscore
+type Service interface {
+	Home(ctx context.Context, cursor string) (models.PostListPage, error)
+	Author(ctx context.Context, username, cursor string) (models.PostListPage, error)
+	Tag(ctx context.Context, tag, cursor string) (models.PostListPage, error)
+	Post(ctx context.Context, username, slug string) (models.PostPage, bool, error)
+	Search(ctx context.Context, req models.SearchReq) (models.SearchPage, error)
+	Error(status int) models.ErrorPage
+}
+
+// Controller serves the HTML pages of the reading site.
+type Controller struct {
+	service Service
+	theme   *theme.Theme
+}
+
+func New(service Service, theme *theme.Theme) *Controller {
+	return &Controller{service: service, theme: theme}
+}
+
+// Home serves the front page with the newest posts.
+func (c *Controller) Home(ctx *gin.Context) {
+	page, err := c.service.Home(ctx.Request.Context(), ctx.Query("cursor"))
+	c.render(ctx, theme.PageHome, page, err)
+}
+
+// Author serves the page of an author with their newest posts.
+func (c *Controller) Author(ctx *gin.Context) {
+	page, err := c.service.Author(ctx.Request.Context(), ctx.Param("username"), ctx.Query("cursor"))
+	c.render(ctx, theme.PageAuthor, page, err)
+}
+
+// Tag serves the page of a tag with the newest posts carrying it.
+func (c *Controller) Tag(ctx *gin.Context) {
+	page, err := c.service.Tag(ctx.Request.Context(), ctx.Param("tag"), ctx.Query("cursor"))
+	c.render(ctx, theme.PageTag, page, err)
+}
+
+// Post serves the page of a post, old slugs permanently redirect to the current one.
+func (c *Controller) Post(ctx *gin.Context) {
+	page, moved, err := c.service.Post(ctx.Request.Context(), ctx.Param("username"), ctx.Param("slug"))
+	if err == nil && moved {
+		ctx.Redirect(http.StatusMovedPermanently, site.PostPath(page.Post.Author.Username, page.Post.Slug))
+		return
+	}
+	c.render(ctx, theme.PagePost, page, err)
+}
+
+// Search serves the search page and its results.
+func (c *Controller) Search(ctx *gin.Context) {
+	var req models.SearchReq
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.render(ctx, "", nil, utils.ErrBadRequest)
+		return
+	}
+	page, err := c.service.Search(ctx.Request.Context(), req)
+	c.render(ctx, theme.PageSearch, page, err)
+}
+
+// render answers with the page, or with the error page when err is set or the page does not render.
+func (c *Controller) render(ctx *gin.Context, name string, data interface{}, err error) {
+	status := http.StatusOK
+	if err != nil {
+		status = utils.ErrorStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("rendering %s: %v", ctx.Request.URL.Path, err)
+		}
+		name, data = theme.PageError, c.service.Error(status)
+	}
+
+	var buf bytes.Buffer
+	if err = c.theme.Render(&buf, name, data); err != nil {
+		log.Printf("rendering %s: %v", ctx.Request.URL.Path, err)
+		ctx.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	if status == http.StatusOK {
+		if utils.CachePublic(ctx, utils.ContentETag(buf.Bytes()), time.Time{}, pageMaxAge) {
+			return
+		}
+	}
+	ctx.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// Asset serves a static file of the theme.
+func (c *Controller) Asset(ctx *gin.Context) {
+	name := strings.TrimPrefix(path.Clean(ctx.Param("filepath")), "/")
+	data, err := c.theme.Asset(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		ctx.Status(http.StatusNotFound)
+		return
+	} else if err != nil {
+		utils.HandleError(ctx, err)
+		return
+	}
+
+	if utils.CachePublic(ctx, utils.ContentETag(data), time.Time{}, assetMaxAge) {
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,77 @@
+package apikey
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "api_keys"
+
+// lastUsedResolution limits how often last_used_at is written for a key that is used in bursts.
+const lastUsedResolution = time.Minute
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateAPIKey(key repoModels.APIKey) error {
+	_, err := r.db.InsertOne(context.Background(), key)
+	return err
+}
+
+func (r *Repository) GetAPIKeysByUser(userID primitive.ObjectID) ([]repoModels.APIKey, error) {
+	keys := []repoModels.APIKey{}
+	ctx := context.Background()
+
+	cursor, err := r.db.Find(ctx, bson.M{"user_id": userID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	return keys, cursor.All(ctx, &keys)
+}
+
+func (r *Repository) GetAPIKeyByID(id primitive.ObjectID) (repoModels.APIKey, error) {
+	var key repoModels.APIKey
+	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&key)
+	return key, err
+}
+
+func (r *Repository) GetAPIKeyByHash(hash string) (repoModels.APIKey, error) {
+	var key repoModels.APIKey
+	err := r.db.FindOne(context.Background(), bson.M{"hash": hash}).Decode(&key)
+	return key, err
+}
+
+func (r *Repository) RevokeAPIKey(id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	_, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+	return err
+}
+
+// TouchAPIKey records the use of a key, at most once per lastUsedResolution.
+func (r *Repository) TouchAPIKey(id primitive.ObjectID, now time.Time) error {
+	filter := bson.M{"_id": id, "$or": bson.A{
+		bson.M{"last_used_at": bson.M{"$exists": false}},
+		bson.M{"last_used_at": bson.M{"$lt": now.Add(-lastUsedResolution)}},
+	}}
+	_, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"last_used_at": now}})
+	return err
+}
+
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
@@ -0,0 +1,74 @@
+package bookmark
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "bookmarks"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+// AddBookmark stores the bookmark, it returns false when the user already bookmarked the post.
+// The upsert finds an existing bookmark even before the unique index exists, the index settles concurrent adds.
+func (r *Repository) AddBookmark(bookmark repoModels.Bookmark) (bool, error) {
+	filter := bson.M{"user_id": bookmark.UserID, "post_id": bookmark.PostID}
+	res, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$setOnInsert": bookmark}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// RemoveBookmark deletes the bookmark, it returns false when there was none.
+func (r *Repository) RemoveBookmark(userID, postID primitive.ObjectID) (bool, error) {
+	res, err := r.db.DeleteOne(context.Background(), bson.M{"user_id": userID, "post_id": postID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *Repository) GetBookmarks(query repoModels.ListQuery) ([]repoModels.Bookmark, error) {
+	bookmarks := []repoModels.Bookmark{}
+	ctx := context.Background()
+
+	opts := options.Find().SetLimit(int64(query.Limit))
+	if query.Sort != nil {
+		opts.SetSort(query.Sort)
+	}
+	cursor, err := r.db.Find(ctx, query.Filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, cursor.All(ctx, &bookmarks)
+}
+
+func (r *Repository) CountBookmarks(filter interface{}) (int64, error) {
+	return r.db.CountDocuments(context.Background(), filter)
+}
+
+// EnsureIndexes creates the indexes the bookmarks collection relies on, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
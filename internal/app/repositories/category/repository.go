@@ -0,0 +1,85 @@
+package category
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "categories"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateCategory(category repoModels.Category) error {
+	_, err := r.db.InsertOne(context.Background(), category)
+	return err
+}
+
+func (r *Repository) GetCategories() ([]repoModels.Category, error) {
+	categories := []repoModels.Category{}
+	ctx := context.Background()
+
+	cursor, err := r.db.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	return categories, cursor.All(ctx, &categories)
+}
+
+func (r *Repository) GetCategoryByID(id primitive.ObjectID) (repoModels.Category, error) {
+	var category repoModels.Category
+	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&category)
+	return category, err
+}
+
+func (r *Repository) GetCategoryBySlug(slug string) (repoModels.Category, error) {
+	var category repoModels.Category
+	err := r.db.FindOne(context.Background(), bson.M{"slug": slug}).Decode(&category)
+	return category, err
+}
+
+// GetSubtree returns the category and all of its descendants.
+func (r *Repository) GetSubtree(id primitive.ObjectID) ([]repoModels.Category, error) {
+	categories := []repoModels.Category{}
+	ctx := context.Background()
+
+	cursor, err := r.db.Find(ctx, bson.M{"$or": bson.A{bson.M{"_id": id}, bson.M{"ancestors": id}}})
+	if err != nil {
+		return nil, err
+	}
+	return categories, cursor.All(ctx, &categories)
+}
+
+// CountChildren counts the direct children of the category.
+func (r *Repository) CountChildren(id primitive.ObjectID) (int64, error) {
+	return r.db.CountDocuments(context.Background(), bson.M{"parent_id": id})
+}
+
+func (r *Repository) UpdateCategory(category repoModels.Category) error {
+	_, err := r.db.ReplaceOne(context.Background(), bson.M{"_id": category.ID}, category)
+	return err
+}
+
+func (r *Repository) DeleteCategory(id primitive.ObjectID) error {
+	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+		{Keys: bson.D{{Key: "parent_id", Value: 1}}},
+	})
+	return err
+}
@@ -0,0 +1,158 @@
+package comment
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "comments"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateComment(comment repoModels.Comment) error {
+	_, err := r.db.InsertOne(context.Background(), comment)
+	return err
+}
+
+func (r *Repository) GetCommentByID(id primitive.ObjectID) (repoModels.Comment, error) {
+	var comment repoModels.Comment
+	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&comment)
+	return comment, err
+}
+
+// GetCommentsByPost returns every comment on the post, deleted ones included, oldest first.
+func (r *Repository) GetCommentsByPost(postID primitive.ObjectID) ([]repoModels.Comment, error) {
+	comments := []repoModels.Comment{}
+	ctx := context.Background()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.db.Find(ctx, bson.M{"post_id": postID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return comments, cursor.All(ctx, &comments)
+}
+
+// GetComments returns a page of the comments matching the query, for the moderation queue.
+func (r *Repository) GetComments(query repoModels.ListQuery) ([]repoModels.Comment, error) {
+	comments := []repoModels.Comment{}
+	ctx := context.Background()
+
+	opts := options.Find().SetLimit(int64(query.Limit))
+	if query.Sort != nil {
+		opts.SetSort(query.Sort)
+	}
+	cursor, err := r.db.Find(ctx, query.Filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return comments, cursor.All(ctx, &comments)
+}
+
+func (r *Repository) CountComments(filter interface{}) (int64, error) {
+	return r.db.CountDocuments(context.Background(), filter)
+}
+
+// CountVisible counts the comments on the post everybody can see.
+func (r *Repository) CountVisible(postID primitive.ObjectID) (int64, error) {
+	return r.db.CountDocuments(context.Background(), bson.M{
+		"post_id":    postID,
+		"status":     repoModels.CommentStatusApproved,
+		"shadow":     bson.M{"$ne": true},
+		"deleted_at": bson.M{"$exists": false},
+	})
+}
+
+// HasApproved reports whether a comment of the user was ever approved.
+func (r *Repository) HasApproved(authorID primitive.ObjectID) (bool, error) {
+	filter := bson.M{"author._id": authorID, "status": repoModels.CommentStatusApproved}
+	n, err := r.db.CountDocuments(context.Background(), filter, options.Count().SetLimit(1))
+	return n > 0, err
+}
+
+// UpdateCommentFields sets fields of a comment that is not deleted.
+func (r *Repository) UpdateCommentFields(id primitive.ObjectID, set bson.M) error {
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}}
+	res, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// RenameAuthor updates the username stored with the comments of the author, it returns the number of changed
+// comments.
+func (r *Repository) RenameAuthor(ctx context.Context, authorID primitive.ObjectID, username string) (int64, error) {
+	res, err := r.db.UpdateMany(ctx, bson.M{"author._id": authorID}, bson.M{"$set": bson.M{"author.username": username}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// SetShadow marks or unmarks every comment of the author as shadowed, it returns the ids of the posts the
+// author commented on.
+func (r *Repository) SetShadow(authorID primitive.ObjectID, shadow bool) ([]primitive.ObjectID, error) {
+	ctx := context.Background()
+	filter := bson.M{"author._id": authorID}
+	postIDs, err := r.db.Distinct(ctx, "post_id", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$unset": bson.M{"shadow": ""}}
+	if shadow {
+		update = bson.M{"$set": bson.M{"shadow": true}}
+	}
+	if _, err = r.db.UpdateMany(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(postIDs))
+	for _, id := range postIDs {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			ids = append(ids, oid)
+		}
+	}
+	return ids, nil
+}
+
+// DeleteComment clears the content of the comment and marks it deleted, it returns false when the comment
+// was already deleted.
+func (r *Repository) DeleteComment(id primitive.ObjectID, deletedBy string) (bool, error) {
+	now := time.Now()
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"content": "", "deleted_at": &now, "deleted_by": deletedBy}}
+	res, err := r.db.UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// EnsureIndexes creates the indexes the comments collection relies on, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "ancestors", Value: 1}}},
+		{Keys: bson.D{{Key: "author._id", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "post_author_id", Value: 1}, {Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	return err
+}
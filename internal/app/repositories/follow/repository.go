@@ -0,0 +1,90 @@
+package follow
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "follows"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+// AddFollow stores the follow, it returns false when the follower already follows the followee.
+// The upsert finds an existing follow even before the unique index exists, the index settles concurrent adds.
+func (r *Repository) AddFollow(follow repoModels.Follow) (bool, error) {
+	filter := bson.M{"follower_id": follow.FollowerID, "followee_id": follow.FolloweeID}
+	res, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$setOnInsert": follow}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// RemoveFollow deletes the follow, it returns false when there was none.
+func (r *Repository) RemoveFollow(followerID, followeeID primitive.ObjectID) (bool, error) {
+	res, err := r.db.DeleteOne(context.Background(), bson.M{"follower_id": followerID, "followee_id": followeeID})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *Repository) GetFollows(query repoModels.ListQuery) ([]repoModels.Follow, error) {
+	follows := []repoModels.Follow{}
+	ctx := context.Background()
+
+	opts := options.Find().SetLimit(int64(query.Limit))
+	if query.Sort != nil {
+		opts.SetSort(query.Sort)
+	}
+	cursor, err := r.db.Find(ctx, query.Filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return follows, cursor.All(ctx, &follows)
+}
+
+func (r *Repository) CountFollows(filter interface{}) (int64, error) {
+	return r.db.CountDocuments(context.Background(), filter)
+}
+
+// GetFolloweeIDs returns the ids of every user the follower follows.
+func (r *Repository) GetFolloweeIDs(followerID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	values, err := r.db.Distinct(context.Background(), "followee_id", bson.M{"follower_id": followerID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// EnsureIndexes creates the indexes the follows collection relies on, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "follower_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "followee_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
@@ -0,0 +1,125 @@
+package media
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "media"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateMedia(media repoModels.Media) error {
+	_, err := r.db.InsertOne(context.Background(), media)
+	return err
+}
+
+func (r *Repository) GetMediaByID(id primitive.ObjectID) (repoModels.Media, error) {
+	var media repoModels.Media
+	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&media)
+	return media, err
+}
+
+func (r *Repository) GetMedia(query repoModels.ListQuery) ([]repoModels.Media, error) {
+	media := []repoModels.Media{}
+	ctx := context.Background()
+
+	opts := options.Find().SetLimit(int64(query.Limit))
+	if query.Sort != nil {
+		opts.SetSort(query.Sort)
+	}
+	cursor, err := r.db.Find(ctx, query.Filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	return media, cursor.All(ctx, &media)
+}
+
+func (r *Repository) CountMedia(filter interface{}) (int64, error) {
+	return r.db.CountDocuments(context.Background(), filter)
+}
+
+// DeleteMedia removes the media unless a post references it, it returns false when it is referenced.
+func (r *Repository) DeleteMedia(id primitive.ObjectID) (bool, error) {
+	res, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id, "references.0": bson.M{"$exists": false}})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *Repository) UpdateMediaFields(id primitive.ObjectID, set bson.M) error {
+	res, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ClaimMedia moves a pending image to processing, it returns false when it is not pending, because another
+// worker claimed it or it was deleted.
+func (r *Repository) ClaimMedia(id primitive.ObjectID) (bool, error) {
+	res, err := r.db.UpdateOne(context.Background(),
+		bson.M{"_id": id, "status": repoModels.MediaStatusPending},
+		bson.M{"$set": bson.M{"status": repoModels.MediaStatusProcessing, "processing_at": time.Now()}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RequeueStale moves images that started processing before the time back to pending, their worker is gone.
+func (r *Repository) RequeueStale(before time.Time) (int64, error) {
+	res, err := r.db.UpdateMany(context.Background(),
+		bson.M{"status": repoModels.MediaStatusProcessing, "processing_at": bson.M{"$lt": before}},
+		bson.M{"$set": bson.M{"status": repoModels.MediaStatusPending}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// SetReferences makes the post reference exactly the media with the given ids that belong to the owner,
+// dropping the references it had to other media.
+func (r *Repository) SetReferences(postID, ownerID primitive.ObjectID, ids []primitive.ObjectID) error {
+	ctx := context.Background()
+	if ids == nil {
+		ids = []primitive.ObjectID{}
+	}
+	_, err := r.db.UpdateMany(ctx,
+		bson.M{"references": postID, "_id": bson.M{"$nin": ids}},
+		bson.M{"$pull": bson.M{"references": postID}})
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	_, err = r.db.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "owner_id": ownerID},
+		bson.M{"$addToSet": bson.M{"references": postID}})
+	return err
+}
+
+// EnsureIndexes creates the indexes the media collection relies on, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "references", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	return err
+}
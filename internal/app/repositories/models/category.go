@@ -0,0 +1,25 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Category is a node of the admin-managed category tree. Ancestors lists the ids from the root down to the
+// parent so a whole subtree can be found with a single query.
+type Category struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string               `bson:"name" json:"name"`
+	Slug        string               `bson:"slug" json:"slug"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	ParentID    *primitive.ObjectID  `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Ancestors   []primitive.ObjectID `bson:"ancestors" json:"ancestors"`
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// TagCount is a tag with the number of posts using it.
+type TagCount struct {
+	Name  string `bson:"_id" json:"name"`
+	Count int64  `bson:"count" json:"count"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const (
+	CommentDeletedByAuthor    = "author"
+	CommentDeletedByModerator = "moderator"
+)
+
+// CommentStatus values, only approved comments are shown to everyone but their author.
+const (
+	CommentStatusApproved = "approved"
+	CommentStatusPending  = "pending"
+	CommentStatusSpam     = "spam"
+	CommentStatusRejected = "rejected"
+)
+
+// Comment is a comment on a post or, with ParentID set, a reply to another comment. Ancestors lists the ids
+// from the top level comment down to the parent, like the categories do. Deleted comments keep their place in
+// the thread but lose their content.
+//
+// Status is the moderation state. Shadow marks comments of shadow hidden users, which are only shown to their
+// author, and TrainedAs remembers how the spam classifier learned from the comment so a changed decision can
+// be unlearned.
+type Comment struct {
+	ID               primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	PostID           primitive.ObjectID   `bson:"post_id" json:"post_id"`
+	PostAuthorID     primitive.ObjectID   `bson:"post_author_id" json:"-"`
+	ParentID         *primitive.ObjectID  `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Ancestors        []primitive.ObjectID `bson:"ancestors" json:"ancestors"`
+	Author           BasicUser            `bson:"author" json:"author"`
+	Content          string               `bson:"content" json:"content"`
+	Status           string               `bson:"status" json:"status"`
+	SpamScore        float64              `bson:"spam_score" json:"spam_score,omitempty"`
+	ModerationReason string               `bson:"moderation_reason,omitempty" json:"moderation_reason,omitempty"`
+	ModeratedAt      *time.Time           `bson:"moderated_at,omitempty" json:"moderated_at,omitempty"`
+	TrainedAs        string               `bson:"trained_as,omitempty" json:"-"`
+	Shadow           bool                 `bson:"shadow,omitempty" json:"-"`
+	CreatedAt        time.Time            `bson:"created_at" json:"created_at"`
+	EditedAt         *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+	DeletedAt        *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy        string               `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// VisibleTo reports whether the comment is shown to the user, everybody but the author only sees approved
+// comments of users who are not shadow hidden.
+func (c Comment) VisibleTo(userID primitive.ObjectID) bool {
+	if c.Author.ID == userID {
+		return true
+	}
+	return c.Status == CommentStatusApproved && !c.Shadow
+}
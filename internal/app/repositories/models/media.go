@@ -0,0 +1,50 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Processing states of uploaded images, other files are not processed and have no status.
+const (
+	MediaStatusPending    = "pending"
+	MediaStatusProcessing = "processing"
+	MediaStatusReady      = "ready"
+	MediaStatusFailed     = "failed"
+)
+
+// Media is an uploaded file, its bytes are kept by the blob storage under Key. ContentType is sniffed from
+// the content on upload and Filename is the name it was uploaded with. References lists the posts whose content
+// links to the file, referenced files can be downloaded by anyone, the others only with a signed link.
+//
+// Images are processed in the background, Status tells how far: once ready their metadata is stripped, they
+// are upright, Width and Height are known, Variants holds the resized copies and Blurhash a placeholder.
+type Media struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	OwnerID      primitive.ObjectID   `bson:"owner_id" json:"owner_id"`
+	Key          string               `bson:"key" json:"-"`
+	Filename     string               `bson:"filename" json:"filename"`
+	ContentType  string               `bson:"content_type" json:"content_type"`
+	Size         int64                `bson:"size" json:"size"`
+	References   []primitive.ObjectID `bson:"references" json:"references"`
+	Status       string               `bson:"status,omitempty" json:"status,omitempty"`
+	StatusError  string               `bson:"status_error,omitempty" json:"status_error,omitempty"`
+	Width        int                  `bson:"width,omitempty" json:"width,omitempty"`
+	Height       int                  `bson:"height,omitempty" json:"height,omitempty"`
+	Blurhash     string               `bson:"blurhash,omitempty" json:"blurhash,omitempty"`
+	Variants     []MediaVariant       `bson:"variants,omitempty" json:"variants,omitempty"`
+	ProcessingAt *time.Time           `bson:"processing_at,omitempty" json:"-"`
+	ProcessedAt  *time.Time           `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+}
+
+// MediaVariant is a resized copy of an image, Name (width and extension, 640.webp) addresses it below the
+// download link of the image.
+type MediaVariant struct {
+	Name        string `bson:"name" json:"name"`
+	Key         string `bson:"key" json:"-"`
+	ContentType string `bson:"content_type" json:"content_type"`
+	Width       int    `bson:"width" json:"width"`
+	Height      int    `bson:"height" json:"height"`
+	Size        int64  `bson:"size" json:"size"`
+}
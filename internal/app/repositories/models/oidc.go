@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// OIDCLogin is the state of a started single sign-on login, keyed by the hash of the state parameter.
+// BrowserHash is the hash of the token kept in a cookie of the browser that started the login.
+type OIDCLogin struct {
+	ID           string    `bson:"_id"`
+	Nonce        string    `bson:"nonce"`
+	CodeVerifier string    `bson:"code_verifier"`
+	BrowserHash  string    `bson:"browser_hash"`
+	CreatedAt    time.Time `bson:"created_at"`
+	ExpiresAt    time.Time `bson:"expires_at"`
+}
@@ -5,21 +5,117 @@ import (
 	"time"
 )
 
+// Post is addressed by ID or by its slug, which is unique per author. Its content is written by the author and
+// rendered by the post service, the counters are maintained by the comment, reaction and bookmark services.
 type Post struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Title     string             `bson:"title" json:"title"`
-	Content   string             `bson:"content" json:"content"`
-	Author    BasicUser          `bson:"author" json:"author"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
-	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	ID    primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Title string             `bson:"title" json:"title"`
+	Slug  string             `bson:"slug" json:"slug"`
+	// SlugHistory holds the previous slugs of the post, they redirect to the current one.
+	SlugHistory   []string `bson:"slug_history,omitempty" json:"slug_history,omitempty"`
+	Content       string   `bson:"content" json:"content,omitempty"`
+	ContentFormat string   `bson:"content_format" json:"content_format"`
+	// ContentHTML caches the sanitized HTML of Content, RenderVersion is the render.Version it was rendered with.
+	ContentHTML   string `bson:"content_html" json:"content_html,omitempty"`
+	RenderVersion int    `bson:"render_version,omitempty" json:"-"`
+	// ContentText, the text searched, Excerpt, WordCount, ReadingTime (in minutes) and TOC are derived from
+	// ContentHTML, the Excerpt only unless ExcerptManual says the author wrote it.
+	ContentText   string              `bson:"content_text,omitempty" json:"-"`
+	Excerpt       string              `bson:"excerpt" json:"excerpt"`
+	ExcerptManual bool                `bson:"excerpt_manual,omitempty" json:"-"`
+	WordCount     int                 `bson:"word_count" json:"word_count"`
+	ReadingTime   int                 `bson:"reading_time" json:"reading_time"`
+	TOC           []TOCEntry          `bson:"toc,omitempty" json:"toc,omitempty"`
+	Tags          []string            `bson:"tags,omitempty" json:"tags,omitempty"`
+	CategoryID    *primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	// SEO holds what the author wants search engines and link previews to show.
+	SEO    PostSEO   `bson:"seo" json:"seo"`
+	Author BasicUser `bson:"author" json:"author"`
+	// CommentCount counts the comments that are not deleted.
+	CommentCount   int64 `bson:"comment_count" json:"comment_count"`
+	CommentsClosed bool  `bson:"comments_closed,omitempty" json:"comments_closed,omitempty"`
+	// Reactions counts the reactions per emoji.
+	Reactions     map[string]int64 `bson:"reactions,omitempty" json:"reactions,omitempty"`
+	ReactionCount int64            `bson:"reaction_count" json:"reaction_count"`
+	BookmarkCount int64            `bson:"bookmark_count" json:"bookmark_count"`
+	// Popularity adds up the reactions, comments and bookmarks, each weighted by its Popularity weight.
+	Popularity int64     `bson:"popularity" json:"popularity"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+	// PublishedAt is left out of the document of drafts, so no filter on the publication date matches them.
+	PublishedAt time.Time  `bson:"published_at,omitempty" json:"published_at"`
+	DeletedAt   *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	// Import is only set on posts imported from another blog.
+	Import *PostImport `bson:"import,omitempty" json:"import,omitempty"`
 }
 
+// IsDraft reports whether the post was never published.
+func (p Post) IsDraft() bool {
+	return p.PublishedAt.IsZero()
+}
+
+// PostImport identifies the original of an imported post: its ID on the Source blog, when it was last updated
+// there and the Hash of what was imported, so importing the same export again finds the post and only updates it
+// when the original changed.
+type PostImport struct {
+	Source    string    `bson:"source" json:"source"`
+	ID        string    `bson:"id" json:"id"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	Hash      string    `bson:"hash,omitempty" json:"-"`
+}
+
+// TOCEntry is a heading of a post, ID is its anchor in the rendered HTML.
+type TOCEntry struct {
+	Level int    `bson:"level" json:"level"`
+	ID    string `bson:"id" json:"id"`
+	Text  string `bson:"text" json:"text"`
+}
+
+// PostSEO overrides what search engines and link previews show of a post. Description replaces the excerpt,
+// CanonicalURL points at the original of a post that was published elsewhere first, NoIndex keeps the post out
+// of search engines and the sitemap, and Image is shown in link previews (OpenGraph and Twitter cards).
+type PostSEO struct {
+	Description  string `bson:"description,omitempty" json:"description,omitempty"`
+	CanonicalURL string `bson:"canonical_url,omitempty" json:"canonical_url,omitempty"`
+	NoIndex      bool   `bson:"noindex,omitempty" json:"noindex,omitempty"`
+	Image        string `bson:"image,omitempty" json:"image,omitempty"`
+}
+
+// AuthorActivity sums up the posts of an author, LastUpdated is the latest update or publication of one of them.
+type AuthorActivity struct {
+	Username    string    `bson:"_id" json:"username"`
+	PostCount   int64     `bson:"post_count" json:"post_count"`
+	LastUpdated time.Time `bson:"last_updated" json:"last_updated"`
+}
+
+// Weights of the counters in the popularity of a post.
+const (
+	PopularityReactionWeight = 1
+	PopularityCommentWeight  = 2
+	PopularityBookmarkWeight = 3
+)
+
+// ListQuery describes a page of a listing, Sort and Projection are passed to the driver as they are. Skip is
+// only meant for listings numbered by page, such as sitemaps, the others seek with cursors.
+type ListQuery struct {
+	Filter     interface{}
+	Sort       interface{}
+	Projection interface{}
+	Skip       int
+	Limit      int
+}
+
+// ListMetaData describes a page of a listing. NextCursor and PrevCursor are the cursor parameters of the
+// neighbouring pages, Next and Prev the links to them, and Total is only counted when asked for.
 type ListMetaData struct {
-	Total  int64 `bson:"total" json:"total"`
-	Offset int   `bson:"offset" json:"offset"`
-	Limit  int   `bson:"limit" json:"limit"`
+	Limit      int    `bson:"limit" json:"limit"`
+	Total      *int64 `bson:"total,omitempty" json:"total,omitempty"`
+	NextCursor string `bson:"next_cursor,omitempty" json:"next_cursor,omitempty"`
+	PrevCursor string `bson:"prev_cursor,omitempty" json:"prev_cursor,omitempty"`
+	Next       string `bson:"next,omitempty" json:"next,omitempty"`
+	Prev       string `bson:"prev,omitempty" json:"prev,omitempty"`
 }
+
 type BasicUser struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Username string             `bson:"username" json:"username"`
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Reaction is the reaction of a user to a post with one emoji, a user can react with several emoji.
+type Reaction struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"post_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Emoji     string             `bson:"emoji" json:"emoji"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Bookmark marks a post a user wants to read later.
+type Bookmark struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	PostID    primitive.ObjectID `bson:"post_id" json:"post_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Session is a browser login, the cookie holds a token of which only the hash is stored.
+// ExpiresAt is the absolute timeout and drives the TTL index, IdleExpiresAt moves forward on use.
+type Session struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"-"`
+	TokenHash     string             `bson:"token_hash" json:"-"`
+	CSRFToken     string             `bson:"csrf_token" json:"-"`
+	Method        string             `bson:"method" json:"method"`
+	UserAgent     string             `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	IP            string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	LastSeenAt    time.Time          `bson:"last_seen_at" json:"last_seen_at"`
+	IdleExpiresAt time.Time          `bson:"idle_expires_at" json:"idle_expires_at"`
+	ExpiresAt     time.Time          `bson:"expires_at" json:"expires_at"`
+	Current       bool               `bson:"-" json:"current"`
+}
+
+const (
+	SessionMethodPassword = "password"
+	SessionMethodOIDC     = "oidc"
+)
+
+func (s Session) IsActive(now time.Time) bool {
+	return now.Before(s.ExpiresAt) && now.Before(s.IdleExpiresAt)
+}
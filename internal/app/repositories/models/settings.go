@@ -0,0 +1,78 @@
+package models
+
+const (
+	SecuritySettingsID   = "security"
+	ModerationSettingsID = "moderation"
+	ReactionSettingsID   = "reactions"
+)
+
+// ModerationMode values: open publishes comments right away, first holds the first comment of every user
+// until a moderator approved one and all holds every comment.
+const (
+	ModerationOpen  = "open"
+	ModerationFirst = "first"
+	ModerationAll   = "all"
+)
+
+// SecuritySettings is the single document holding the security policy admins can change at runtime.
+type SecuritySettings struct {
+	ID                   string   `bson:"_id" json:"-"`
+	TwoFactorRequiredFor []string `bson:"two_factor_required_for" json:"two_factor_required_for"`
+}
+
+// RequiresTwoFactor reports whether users with the role must enroll in two-factor authentication.
+func (s SecuritySettings) RequiresTwoFactor(role string) bool {
+	for _, r := range s.TwoFactorRequiredFor {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ModerationSettings decide which comments wait in the moderation queue. Comments with more than MaxLinks
+// links are held, comments containing a Blocklist term or scoring at least SpamThreshold with the spam
+// classifier are filed as spam.
+type ModerationSettings struct {
+	ID            string   `bson:"_id" json:"-"`
+	Mode          string   `bson:"mode" json:"mode"`
+	MaxLinks      int      `bson:"max_links" json:"max_links"`
+	Blocklist     []string `bson:"blocklist" json:"blocklist"`
+	SpamThreshold float64  `bson:"spam_threshold" json:"spam_threshold"`
+}
+
+// DefaultModerationSettings apply until an admin saved other settings.
+var DefaultModerationSettings = ModerationSettings{
+	ID:            ModerationSettingsID,
+	Mode:          ModerationFirst,
+	MaxLinks:      2,
+	Blocklist:     []string{},
+	SpamThreshold: 0.9,
+}
+
+// IsValidModerationMode reports whether mode is one of the moderation modes.
+func IsValidModerationMode(mode string) bool {
+	return mode == ModerationOpen || mode == ModerationFirst || mode == ModerationAll
+}
+
+// ReactionSettings holds the emoji readers can react to posts with.
+type ReactionSettings struct {
+	ID    string   `bson:"_id" json:"-"`
+	Emoji []string `bson:"emoji" json:"emoji"`
+}
+
+// Allows reports whether readers can react with the emoji.
+func (s ReactionSettings) Allows(emoji string) bool {
+	for _, e := range s.Emoji {
+		if e == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultReactionSettings apply until an admin saved other settings, the thumbs up is the like.
+var DefaultReactionSettings = ReactionSettings{
+	ID:    ReactionSettingsID,
+	Emoji: []string{"👍", "❤️", "🎉", "😂", "😮", "😢"},
+}
@@ -5,11 +5,77 @@ import (
 	"time"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// UserStatus values, an empty status is treated as active for documents created before statuses existed.
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
+
+// User is an account. ShadowHidden users can keep commenting but nobody else sees their comments, the flag is
+// never part of a response so they can not tell. FollowerCount and FollowingCount are maintained by the follow
+// service.
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Username  string             `bson:"username" json:"username"`
-	Password  string             `bson:"password" json:"-"`
-	Role      string             `bson:"role" json:"role"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Username           string             `bson:"username" json:"username"`
+	Password           string             `bson:"password" json:"-"`
+	Email              string             `bson:"email,omitempty" json:"email,omitempty"`
+	EmailVerifiedAt    *time.Time         `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	Role               string             `bson:"role" json:"role"`
+	Status             string             `bson:"status,omitempty" json:"status,omitempty"`
+	StatusReason       string             `bson:"status_reason,omitempty" json:"status_reason,omitempty"`
+	SuspendedUntil     *time.Time         `bson:"suspended_until,omitempty" json:"suspended_until,omitempty"`
+	MustChangePassword bool               `bson:"must_change_password,omitempty" json:"must_change_password,omitempty"`
+	TwoFactor          *TwoFactor         `bson:"two_factor,omitempty" json:"two_factor,omitempty"`
+	Identities         []Identity         `bson:"identities,omitempty" json:"identities,omitempty"`
+	ShadowHidden       bool               `bson:"shadow_hidden,omitempty" json:"-"`
+	FollowerCount      int64              `bson:"follower_count" json:"follower_count"`
+	FollowingCount     int64              `bson:"following_count" json:"following_count"`
+	CreatedAt          time.Time          `bson:"created_at" json:"created_at"`
+	DeletedAt          *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+}
+
+// TwoFactor holds the TOTP enrollment of a user, Enabled is only set once a code has been confirmed.
+type TwoFactor struct {
+	Secret        string     `bson:"secret" json:"-"`
+	Enabled       bool       `bson:"enabled" json:"enabled"`
+	ConfirmedAt   *time.Time `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+	LastStep      int64      `bson:"last_step" json:"-"`
+	RecoveryCodes []string   `bson:"recovery_codes,omitempty" json:"-"` // hashed, see utils.HashToken
+}
+
+// Identity links the user to an account at an external OpenID Connect provider.
+type Identity struct {
+	Issuer   string    `bson:"issuer" json:"issuer"`
+	Subject  string    `bson:"subject" json:"subject"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
+}
+
+// HasTwoFactor reports whether the user completed a TOTP enrollment.
+func (u User) HasTwoFactor() bool {
+	return u.TwoFactor != nil && u.TwoFactor.Enabled
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+// CanAuthenticate reports whether the account is allowed to log in at the given time.
+func (u User) CanAuthenticate(now time.Time) bool {
+	if u.DeletedAt != nil {
+		return false
+	}
+	switch u.Status {
+	case UserStatusBanned:
+		return false
+	case UserStatusSuspended:
+		return u.SuspendedUntil != nil && !now.Before(*u.SuspendedUntil)
+	}
+	return true
 }
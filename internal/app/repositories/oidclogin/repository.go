@@ -0,0 +1,41 @@
+package oidclogin
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "oidc_logins"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateLogin(login repoModels.OIDCLogin) error {
+	_, err := r.db.InsertOne(context.Background(), login)
+	return err
+}
+
+// ConsumeLogin deletes and returns the unexpired login, so every state can only be used once.
+func (r *Repository) ConsumeLogin(id string) (repoModels.OIDCLogin, error) {
+	var login repoModels.OIDCLogin
+	filter := bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now()}}
+	err := r.db.FindOneAndDelete(context.Background(), filter).Decode(&login)
+	return login, err
+}
+
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
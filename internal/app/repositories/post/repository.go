@@ -3,6 +3,7 @@ package post
 import (
 	repoModels "blog-platform/internal/app/repositories/models"
 	"context"
+	"errors"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 
@@ -26,55 +27,321 @@ func (r *Repository) CreatePost(post repoModels.Post) error {
 	return err
 }
 
-func (r *Repository) GetPosts(ctx context.Context, filter interface{}, offset, limit int) ([]repoModels.Post, *repoModels.ListMetaData, error) {
+func (r *Repository) GetPosts(ctx context.Context, query repoModels.ListQuery) ([]repoModels.Post, error) {
 	var posts []repoModels.Post
 
-	cursor, err := r.db.Find(ctx, filter, options.Find().SetSkip(int64(offset)).SetLimit(int64(limit)))
+	opts := options.Find().SetLimit(int64(query.Limit))
+	if query.Skip > 0 {
+		opts.SetSkip(int64(query.Skip))
+	}
+	if query.Sort != nil {
+		opts.SetSort(query.Sort)
+	}
+	if query.Projection != nil {
+		opts.SetProjection(query.Projection)
+	}
+	cursor, err := r.db.Find(ctx, query.Filter, opts)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	for cursor.Next(ctx) {
 		var post repoModels.Post
 		err := cursor.Decode(&post)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		posts = append(posts, post)
 	}
 
-	if err := cursor.Err(); err != nil {
-		return nil, nil, err
-	}
+	return posts, cursor.Err()
+}
 
-	total, err := r.db.CountDocuments(ctx, filter)
-	if err != nil {
-		return nil, nil, err
-	}
+func (r *Repository) CountPosts(ctx context.Context, filter interface{}) (int64, error) {
+	return r.db.CountDocuments(ctx, filter)
+}
 
-	pagination := repoModels.ListMetaData{
-		Total:  total,
-		Offset: offset,
-		Limit:  limit,
-	}
+func (r *Repository) GetPostByID(id primitive.ObjectID) (repoModels.Post, error) {
+	var post repoModels.Post
+	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&post)
+	return post, err
+}
 
-	return posts, &pagination, nil
+// GetPostBySlug finds the post of the author with the slug, deleted posts and drafts are not found.
+func (r *Repository) GetPostBySlug(username, slug string) (repoModels.Post, error) {
+	var post repoModels.Post
+	filter := bson.M{"author.username": username, "slug": slug, "deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$exists": true}}
+	err := r.db.FindOne(context.Background(), filter).Decode(&post)
+	return post, err
+}
 
+// GetPostByOldSlug finds the post that used to have the slug, the most recently renamed one first. Deleted
+// posts and drafts are not found.
+func (r *Repository) GetPostByOldSlug(username, slug string) (repoModels.Post, error) {
+	var post repoModels.Post
+	opts := options.FindOne().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	filter := bson.M{"author.username": username, "slug_history": slug, "deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$exists": true}}
+	err := r.db.FindOne(context.Background(), filter, opts).Decode(&post)
+	return post, err
 }
 
-func (r *Repository) GetPostByID(id primitive.ObjectID) (repoModels.Post, error) {
+// GetPostByImport finds the post imported from the post with the ID on the source blog.
+func (r *Repository) GetPostByImport(source, id string) (repoModels.Post, error) {
 	var post repoModels.Post
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&post)
+	err := r.db.FindOne(context.Background(), bson.M{"import.source": source, "import.id": id}).Decode(&post)
 	return post, err
 }
 
+// SlugTaken reports whether another post of the author uses the slug now or used it before.
+func (r *Repository) SlugTaken(authorID primitive.ObjectID, slug string, exclude primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"author._id": authorID,
+		"_id":        bson.M{"$ne": exclude},
+		"$or":        bson.A{bson.M{"slug": slug}, bson.M{"slug_history": slug}},
+	}
+	err := r.db.FindOne(context.Background(), filter).Err()
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (r *Repository) UpdatePost(post repoModels.Post) error {
 	_, err := r.db.ReplaceOne(context.Background(), bson.M{"_id": post.ID}, post)
 	return err
 }
 
+// RenameAuthor updates the username stored with the posts of the author, it returns the number of changed posts.
+func (r *Repository) RenameAuthor(ctx context.Context, authorID primitive.ObjectID, username string) (int64, error) {
+	res, err := r.db.UpdateMany(ctx, bson.M{"author._id": authorID}, bson.M{"$set": bson.M{"author.username": username}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
 func (r *Repository) DeletePost(id primitive.ObjectID) error {
 	now := time.Now()
 	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"deleted_at": &now}})
 	return err
 }
+
+// GetPostsByIDs returns the posts with the given ids, in no particular order.
+func (r *Repository) GetPostsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]repoModels.Post, error) {
+	posts := []repoModels.Post{}
+	cursor, err := r.db.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	return posts, cursor.All(ctx, &posts)
+}
+
+// EachPost calls fn for every post matching filter without loading them all at once.
+func (r *Repository) EachPost(ctx context.Context, filter interface{}, fn func(repoModels.Post) error) error {
+	cursor, err := r.db.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var post repoModels.Post
+		if err = cursor.Decode(&post); err != nil {
+			return err
+		}
+		if err = fn(post); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// GetTagCounts counts the posts matching filter per tag, most used tags first.
+func (r *Repository) GetTagCounts(ctx context.Context, filter interface{}) ([]repoModels.TagCount, error) {
+	tags := []repoModels.TagCount{}
+	cursor, err := r.db.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, cursor.All(ctx, &tags)
+}
+
+// GetAuthorActivity sums up the posts matching filter per author, ordered by username.
+func (r *Repository) GetAuthorActivity(ctx context.Context, filter interface{}) ([]repoModels.AuthorActivity, error) {
+	authors := []repoModels.AuthorActivity{}
+	cursor, err := r.db.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$author.username",
+			"post_count":   bson.M{"$sum": 1},
+			"last_updated": bson.M{"$max": bson.M{"$max": bson.A{"$updated_at", "$published_at"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return authors, cursor.All(ctx, &authors)
+}
+
+// GetLastUpdated returns the latest update or publication of the posts the query selects, in the order and the
+// window of its Sort, Skip and Limit, or the zero time when it selects none.
+func (r *Repository) GetLastUpdated(ctx context.Context, query repoModels.ListQuery) (time.Time, error) {
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: query.Filter}}}
+	if query.Sort != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: query.Sort}})
+	}
+	if query.Skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: query.Skip}})
+	}
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: query.Limit}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$group", Value: bson.M{
+		"_id":          nil,
+		"last_updated": bson.M{"$max": bson.M{"$max": bson.A{"$updated_at", "$published_at"}}},
+	}}})
+	cursor, err := r.db.Aggregate(ctx, pipeline)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var groups []struct {
+		LastUpdated time.Time `bson:"last_updated"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil || len(groups) == 0 {
+		return time.Time{}, err
+	}
+	return groups[0].LastUpdated, nil
+}
+
+// ReplaceTags replaces the tags from with the tag to on every post, posts that already carry to keep a single copy.
+func (r *Repository) ReplaceTags(ctx context.Context, from []string, to string) (int64, error) {
+	filter := bson.M{"tags": bson.M{"$in": from}}
+	// $addToSet and $pull can not touch the same field in one update, hence the pipeline
+	res, err := r.db.UpdateMany(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tags": bson.M{"$setUnion": bson.A{
+			bson.M{"$filter": bson.M{"input": "$tags", "cond": bson.M{"$not": bson.A{bson.M{"$in": bson.A{"$$this", from}}}}}},
+			bson.A{to},
+		}}}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// MoveCategory moves every post of the category from to the category to, or leaves them uncategorised when to is nil.
+func (r *Repository) MoveCategory(from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	update := bson.M{"$unset": bson.M{"category_id": ""}}
+	if to != nil {
+		update = bson.M{"$set": bson.M{"category_id": to}}
+	}
+	res, err := r.db.UpdateMany(context.Background(), bson.M{"category_id": from}, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// popularityStage recomputes the popularity of a post from its counters after they changed.
+var popularityStage = bson.D{{Key: "$set", Value: bson.M{"popularity": bson.M{"$add": bson.A{
+	bson.M{"$multiply": bson.A{repoModels.PopularityReactionWeight, bson.M{"$ifNull": bson.A{"$reaction_count", 0}}}},
+	bson.M{"$multiply": bson.A{repoModels.PopularityCommentWeight, bson.M{"$ifNull": bson.A{"$comment_count", 0}}}},
+	bson.M{"$multiply": bson.A{repoModels.PopularityBookmarkWeight, bson.M{"$ifNull": bson.A{"$bookmark_count", 0}}}},
+}}}}}
+
+// increment adds delta to a counter, never going below zero.
+func increment(field string, delta int) bson.M {
+	return bson.M{"$max": bson.A{0, bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + field, 0}}, delta}}}}
+}
+
+func (r *Repository) SetCommentCount(id primitive.ObjectID, count int64) error {
+	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"comment_count": count}}},
+		popularityStage,
+	})
+	return err
+}
+
+// IncrementReaction adds delta to the count of the emoji and to the reaction count of the post, an emoji
+// nobody reacts with any more is removed. The emoji must not contain dots or dollar signs.
+func (r *Repository) IncrementReaction(id primitive.ObjectID, emoji string, delta int) error {
+	field := "reactions." + emoji
+	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{field: increment(field, delta), "reaction_count": increment("reaction_count", delta)}}},
+		{{Key: "$set", Value: bson.M{field: bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$" + field, 0}}, "$" + field, "$$REMOVE"}}}}},
+		popularityStage,
+	})
+	return err
+}
+
+func (r *Repository) IncrementBookmarkCount(id primitive.ObjectID, delta int) error {
+	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"bookmark_count": increment("bookmark_count", delta)}}},
+		popularityStage,
+	})
+	return err
+}
+
+// SetRendered stores the rendered content of the post and what is derived from it.
+func (r *Repository) SetRendered(post repoModels.Post) error {
+	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": post.ID}, bson.M{"$set": bson.M{
+		"content_html":   post.ContentHTML,
+		"render_version": post.RenderVersion,
+		"content_text":   post.ContentText,
+		"excerpt":        post.Excerpt,
+		"word_count":     post.WordCount,
+		"reading_time":   post.ReadingTime,
+		"toc":            post.TOC,
+	}})
+	return err
+}
+
+func (r *Repository) SetCommentsClosed(id primitive.ObjectID, closed bool) error {
+	update := bson.M{"$unset": bson.M{"comments_closed": ""}}
+	if closed {
+		update = bson.M{"$set": bson.M{"comments_closed": true}}
+	}
+	res, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// EnsureIndexes creates the indexes used by the post listing filters, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "author.username", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "author._id", Value: 1}, {Key: "created_at", Value: -1}}},
+		// the feed merges the posts of the followed authors in publication order
+		{Keys: bson.D{{Key: "author._id", Value: 1}, {Key: "published_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "updated_at", Value: -1}}},
+		{Keys: bson.D{{Key: "published_at", Value: -1}}},
+		{Keys: bson.D{{Key: "popularity", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "title", Value: 1}}},
+		{Keys: bson.D{{Key: "slug", Value: 1}}},
+		{Keys: bson.D{{Key: "tags", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "category_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "author.username", Value: 1}, {Key: "slug", Value: 1}}},
+		{Keys: bson.D{{Key: "author.username", Value: 1}, {Key: "slug_history", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "author._id", Value: 1}, {Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"slug": bson.M{"$gt": ""}}),
+		},
+		{
+			Keys:    bson.D{{Key: "import.source", Value: 1}, {Key: "import.id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"import": bson.M{"$exists": true}}),
+		},
+	})
+	return err
+}
@@ -0,0 +1,75 @@
+package reaction
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "reactions"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+// AddReaction stores the reaction, it returns false when the user already reacted to the post with the emoji.
+// The upsert finds an existing reaction even before the unique index exists, the index settles concurrent adds.
+func (r *Repository) AddReaction(reaction repoModels.Reaction) (bool, error) {
+	filter := bson.M{"post_id": reaction.PostID, "user_id": reaction.UserID, "emoji": reaction.Emoji}
+	res, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$setOnInsert": reaction}, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// RemoveReaction deletes the reaction, it returns false when there was none.
+func (r *Repository) RemoveReaction(postID, userID primitive.ObjectID, emoji string) (bool, error) {
+	res, err := r.db.DeleteOne(context.Background(), bson.M{"post_id": postID, "user_id": userID, "emoji": emoji})
+	if err != nil {
+		return false, err
+	}
+	return res.DeletedCount > 0, nil
+}
+
+// GetUserEmoji returns the emoji the user reacted to the post with, in the order they were added.
+func (r *Repository) GetUserEmoji(postID, userID primitive.ObjectID) ([]string, error) {
+	ctx := context.Background()
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetProjection(bson.M{"emoji": 1})
+	cursor, err := r.db.Find(ctx, bson.M{"post_id": postID, "user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var reactions []repoModels.Reaction
+	if err = cursor.All(ctx, &reactions); err != nil {
+		return nil, err
+	}
+
+	emoji := make([]string, 0, len(reactions))
+	for _, reaction := range reactions {
+		emoji = append(emoji, reaction.Emoji)
+	}
+	return emoji, nil
+}
+
+// EnsureIndexes creates the indexes the reactions collection relies on, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "post_id", Value: 1}, {Key: "user_id", Value: 1}, {Key: "emoji", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	return err
+}
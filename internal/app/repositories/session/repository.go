@@ -0,0 +1,91 @@
+package session
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "sessions"
+
+// touchResolution limits how often a busy session is written to extend its idle timeout.
+const touchResolution = time.Minute
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateSession(session repoModels.Session) error {
+	_, err := r.db.InsertOne(context.Background(), session)
+	return err
+}
+
+func (r *Repository) GetSessionByTokenHash(hash string) (repoModels.Session, error) {
+	var session repoModels.Session
+	err := r.db.FindOne(context.Background(), bson.M{"token_hash": hash}).Decode(&session)
+	return session, err
+}
+
+func (r *Repository) GetSessionByID(id primitive.ObjectID) (repoModels.Session, error) {
+	var session repoModels.Session
+	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&session)
+	return session, err
+}
+
+// GetActiveSessions returns the sessions of the user that have not timed out, most recently used first.
+func (r *Repository) GetActiveSessions(userID primitive.ObjectID, now time.Time) ([]repoModels.Session, error) {
+	sessions := []repoModels.Session{}
+	ctx := context.Background()
+	filter := bson.M{"user_id": userID, "expires_at": bson.M{"$gt": now}, "idle_expires_at": bson.M{"$gt": now}}
+
+	cursor, err := r.db.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	return sessions, cursor.All(ctx, &sessions)
+}
+
+// TouchSession extends the idle timeout, at most once per touchResolution.
+func (r *Repository) TouchSession(id primitive.ObjectID, now, idleExpiresAt time.Time) error {
+	filter := bson.M{"_id": id, "last_seen_at": bson.M{"$lt": now.Add(-touchResolution)}}
+	update := bson.M{"$set": bson.M{"last_seen_at": now, "idle_expires_at": idleExpiresAt}}
+	_, err := r.db.UpdateOne(context.Background(), filter, update)
+	return err
+}
+
+func (r *Repository) DeleteSession(id primitive.ObjectID) error {
+	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
+	return err
+}
+
+// DeleteUserSessions removes every session of the user except keep, pass primitive.NilObjectID to remove all.
+func (r *Repository) DeleteUserSessions(userID, keep primitive.ObjectID) (int64, error) {
+	filter := bson.M{"user_id": userID}
+	if !keep.IsZero() {
+		filter["_id"] = bson.M{"$ne": keep}
+	}
+	res, err := r.db.DeleteMany(context.Background(), filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// EnsureIndexes creates the lookup indexes and a TTL index removing sessions past their absolute timeout.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "last_seen_at", Value: -1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
@@ -0,0 +1,69 @@
+package settings
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "settings"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+// GetSecuritySettings returns the stored security settings, or the zero settings when none were saved yet.
+func (r *Repository) GetSecuritySettings() (repoModels.SecuritySettings, error) {
+	settings := repoModels.SecuritySettings{ID: repoModels.SecuritySettingsID}
+	err := r.db.FindOne(context.Background(), bson.M{"_id": repoModels.SecuritySettingsID}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return settings, nil
+	}
+	return settings, err
+}
+
+func (r *Repository) SaveSecuritySettings(settings repoModels.SecuritySettings) error {
+	settings.ID = repoModels.SecuritySettingsID
+	_, err := r.db.ReplaceOne(context.Background(), bson.M{"_id": settings.ID}, settings, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetModerationSettings returns the stored comment moderation settings, or the defaults when none were saved yet.
+func (r *Repository) GetModerationSettings() (repoModels.ModerationSettings, error) {
+	settings := repoModels.DefaultModerationSettings
+	err := r.db.FindOne(context.Background(), bson.M{"_id": repoModels.ModerationSettingsID}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return repoModels.DefaultModerationSettings, nil
+	}
+	return settings, err
+}
+
+func (r *Repository) SaveModerationSettings(settings repoModels.ModerationSettings) error {
+	settings.ID = repoModels.ModerationSettingsID
+	_, err := r.db.ReplaceOne(context.Background(), bson.M{"_id": settings.ID}, settings, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetReactionSettings returns the stored reaction settings, or the defaults when none were saved yet.
+func (r *Repository) GetReactionSettings() (repoModels.ReactionSettings, error) {
+	settings := repoModels.DefaultReactionSettings
+	err := r.db.FindOne(context.Background(), bson.M{"_id": repoModels.ReactionSettingsID}).Decode(&settings)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return repoModels.DefaultReactionSettings, nil
+	}
+	return settings, err
+}
+
+func (r *Repository) SaveReactionSettings(settings repoModels.ReactionSettings) error {
+	settings.ID = repoModels.ReactionSettingsID
+	_, err := r.db.ReplaceOne(context.Background(), bson.M{"_id": settings.ID}, settings, options.Replace().SetUpsert(true))
+	return err
+}
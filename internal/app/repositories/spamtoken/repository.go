@@ -0,0 +1,69 @@
+package spamtoken
+
+import (
+	"blog-platform/internal/spam"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "spam_tokens"
+
+// docsID is the document holding the number of learned texts per class, tokens never contain a #.
+const docsID = "#docs"
+
+type tokenDoc struct {
+	Token string `bson:"_id"`
+	Spam  int64  `bson:"spam"`
+	Ham   int64  `bson:"ham"`
+}
+
+// Repository stores the training state of the spam classifier, one document per token.
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) LoadCounts(ctx context.Context) (spam.Counts, error) {
+	counts := spam.Counts{Tokens: map[string]spam.TokenCount{}}
+	cursor, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		return counts, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc tokenDoc
+		if err = cursor.Decode(&doc); err != nil {
+			return counts, err
+		}
+		if doc.Token == docsID {
+			counts.SpamDocs, counts.HamDocs = doc.Spam, doc.Ham
+			continue
+		}
+		counts.Tokens[doc.Token] = spam.TokenCount{Spam: doc.Spam, Ham: doc.Ham}
+	}
+	return counts, cursor.Err()
+}
+
+func (r *Repository) AddCounts(ctx context.Context, tokens []string, isSpam bool, delta int) error {
+	field := "ham"
+	if isSpam {
+		field = "spam"
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(tokens)+1)
+	for _, token := range append([]string{docsID}, tokens...) {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": token}).
+			SetUpdate(bson.M{"$inc": bson.M{field: delta}}).
+			SetUpsert(true))
+	}
+	_, err := r.db.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
@@ -0,0 +1,60 @@
+package token
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"context"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const collectionName = "user_tokens"
+
+type Repository struct {
+	db *mongo.Collection
+}
+
+func New(db *mongo.Database) *Repository {
+	return &Repository{db: db.Collection(collectionName)}
+}
+
+func (r *Repository) CreateToken(token repoModels.UserToken) error {
+	_, err := r.db.InsertOne(context.Background(), token)
+	return err
+}
+
+// ConsumeToken atomically marks an unused, unexpired token as used and returns it,
+// mongo.ErrNoDocuments is returned when no such token exists.
+func (r *Repository) ConsumeToken(hash, purpose string) (repoModels.UserToken, error) {
+	var token repoModels.UserToken
+	now := time.Now()
+	filter := bson.M{
+		"hash":       hash,
+		"purpose":    purpose,
+		"used_at":    bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": now},
+	}
+	err := r.db.FindOneAndUpdate(context.Background(), filter, bson.M{"$set": bson.M{"used_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&token)
+	return token, err
+}
+
+// InvalidateTokens marks every outstanding token of the user for the purpose as used.
+func (r *Repository) InvalidateTokens(userID primitive.ObjectID, purpose string) error {
+	filter := bson.M{"user_id": userID, "purpose": purpose, "used_at": bson.M{"$exists": false}}
+	_, err := r.db.UpdateMany(context.Background(), filter, bson.M{"$set": bson.M{"used_at": time.Now()}})
+	return err
+}
+
+// EnsureIndexes creates the lookup index and a TTL index so expired tokens are purged by MongoDB.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
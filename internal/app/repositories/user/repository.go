@@ -26,11 +26,15 @@ func (r *Repository) CreateUser(user repoModels.User) error {
 	return err
 }
 
-func (r *Repository) GetUsers(filter interface{}, offset, limit int) ([]repoModels.User, error) {
+func (r *Repository) GetUsers(query repoModels.ListQuery) ([]repoModels.User, error) {
 	var users []repoModels.User
 	ctx := context.Background()
 
-	cursor, err := r.db.Find(ctx, filter, options.Find().SetSkip(int64(offset)).SetLimit(int64(limit)))
+	opts := options.Find().SetLimit(int64(query.Limit))
+	if query.Sort != nil {
+		opts.SetSort(query.Sort)
+	}
+	cursor, err := r.db.Find(ctx, query.Filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -47,19 +51,114 @@ func (r *Repository) GetUsers(filter interface{}, offset, limit int) ([]repoMode
 	return users, cursor.Err()
 }
 
+func (r *Repository) CountUsers(filter interface{}) (int64, error) {
+	return r.db.CountDocuments(context.Background(), filter)
+}
+
 func (r *Repository) GetUserByID(id primitive.ObjectID) (repoModels.User, error) {
 	var user repoModels.User
 	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&user)
 	return user, err
 }
 
+func (r *Repository) GetUserByUsername(username string) (repoModels.User, error) {
+	var user repoModels.User
+	err := r.db.FindOne(context.Background(), bson.M{"username": username}).Decode(&user)
+	return user, err
+}
+
+func (r *Repository) GetUserByEmail(email string) (repoModels.User, error) {
+	var user repoModels.User
+	filter := bson.M{"email": email, "deleted_at": bson.M{"$exists": false}}
+	err := r.db.FindOne(context.Background(), filter).Decode(&user)
+	return user, err
+}
+
+func (r *Repository) GetUserByIdentity(issuer, subject string) (repoModels.User, error) {
+	var user repoModels.User
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"issuer": issuer, "subject": subject}}}
+	err := r.db.FindOne(context.Background(), filter).Decode(&user)
+	return user, err
+}
+
+func (r *Repository) AddIdentity(id primitive.ObjectID, identity repoModels.Identity) error {
+	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$push": bson.M{"identities": identity}})
+	return err
+}
+
 func (r *Repository) UpdateUser(user repoModels.User) error {
 	_, err := r.db.ReplaceOne(context.Background(), bson.M{"_id": user.ID}, user)
 	return err
 }
 
+func (r *Repository) UpdateUserFields(id primitive.ObjectID, set, unset bson.M) error {
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	res, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// IncrementFollowCounts adds delta to the following count of the follower and to the follower count of
+// the followee.
+func (r *Repository) IncrementFollowCounts(followerID, followeeID primitive.ObjectID, delta int) error {
+	_, err := r.db.BulkWrite(context.Background(), []mongo.WriteModel{
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": followerID}).SetUpdate(bson.M{"$inc": bson.M{"following_count": delta}}),
+		mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": followeeID}).SetUpdate(bson.M{"$inc": bson.M{"follower_count": delta}}),
+	})
+	return err
+}
+
+// AdvanceTwoFactorStep records step as the last accepted TOTP step, it returns false when a newer step
+// was already accepted so a code can not be replayed once a later one has been used.
+func (r *Repository) AdvanceTwoFactorStep(id primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{"_id": id, "two_factor.enabled": true, "two_factor.last_step": bson.M{"$lte": step}}
+	res, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$set": bson.M{"two_factor.last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// UseRecoveryCode removes the hashed recovery code from the user, it returns false when the code is unknown.
+func (r *Repository) UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error) {
+	filter := bson.M{"_id": id, "two_factor.recovery_codes": hash}
+	res, err := r.db.UpdateOne(context.Background(), filter, bson.M{"$pull": bson.M{"two_factor.recovery_codes": hash}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
 func (r *Repository) DeleteUser(id primitive.ObjectID) error {
 	now := time.Now()
 	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": bson.M{"deleted_at": &now}})
 	return err
 }
+
+// EnsureIndexes creates the indexes the users collection relies on, it is safe to call repeatedly.
+func (r *Repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.db.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "role", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"email": bson.M{"$type": "string"}})},
+		{Keys: bson.D{{Key: "identities.issuer", Value: 1}, {Key: "identities.subject", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true)},
+	})
+	return err
+}
@@ -0,0 +1,136 @@
+package apikey
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+// KeyPrefix starts every key so they are easy to recognise, for example by secret scanners.
+const KeyPrefix = "bp_"
+
+var ErrInvalidKey = errors.New("invalid API key")
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	CreateAPIKey(key repoModels.APIKey) error
+	GetAPIKeysByUser(userID primitive.ObjectID) ([]repoModels.APIKey, error)
+	GetAPIKeyByID(id primitive.ObjectID) (repoModels.APIKey, error)
+	GetAPIKeyByHash(hash string) (repoModels.APIKey, error)
+	RevokeAPIKey(id primitive.ObjectID) error
+	TouchAPIKey(id primitive.ObjectID, now time.Time) error
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+}
+
+type Service struct {
+	repo  Repository
+	users UserRepository
+}
+
+func New(repo Repository, users UserRepository) *Service {
+	return &Service{repo: repo, users: users}
+}
+
+// CreateAPIKey returns the stored key and the key itself, which is not retrievable afterwards.
+func (s *Service) CreateAPIKey(req models.APIKeyReq, access models.UserAccess) (repoModels.APIKey, string, error) {
+	if len(req.Scopes) == 0 {
+		return repoModels.APIKey{}, "", fmt.Errorf("%w: at least one scope is required", utils.ErrBadRequest)
+	}
+	for _, scope := range req.Scopes {
+		if !repoModels.IsValidScope(scope) {
+			return repoModels.APIKey{}, "", fmt.Errorf("%w: unknown scope %q", utils.ErrBadRequest, scope)
+		}
+		if scope == repoModels.ScopeUsersAdmin && !access.IsAdmin() {
+			return repoModels.APIKey{}, "", fmt.Errorf("%w: scope %s needs the admin role", utils.ErrNotAllowed, scope)
+		}
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return repoModels.APIKey{}, "", fmt.Errorf("%w: expires_at must be in the future", utils.ErrBadRequest)
+	}
+
+	prefix, err := utils.NewToken(6)
+	if err != nil {
+		return repoModels.APIKey{}, "", err
+	}
+	secret, err := utils.NewToken(32)
+	if err != nil {
+		return repoModels.APIKey{}, "", err
+	}
+	// the visible part must not contain the separator
+	prefix = KeyPrefix + strings.ReplaceAll(prefix, "_", "-")
+	raw := prefix + "_" + secret
+
+	key := repoModels.APIKey{
+		ID:        primitive.NewObjectID(),
+		UserID:    access.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		Hash:      utils.HashToken(raw),
+		Scopes:    req.Scopes,
+		CreatedAt: now,
+		ExpiresAt: req.ExpiresAt,
+	}
+	return key, raw, s.repo.CreateAPIKey(key)
+}
+
+func (s *Service) GetAPIKeys(access models.UserAccess) ([]repoModels.APIKey, error) {
+	return s.repo.GetAPIKeysByUser(access.ID)
+}
+
+// RevokeAPIKey revokes a key of the calling user, admins can revoke any key.
+func (s *Service) RevokeAPIKey(id primitive.ObjectID, access models.UserAccess) error {
+	key, err := s.repo.GetAPIKeyByID(id)
+	if err != nil {
+		return err
+	}
+	if key.UserID != access.ID && !access.IsAdmin() {
+		return utils.ErrNotAllowed
+	}
+
+	return s.repo.RevokeAPIKey(id)
+}
+
+// Authenticate resolves a raw key to its owner, ErrInvalidKey is returned for unknown, revoked or expired keys.
+func (s *Service) Authenticate(raw string) (repoModels.User, repoModels.APIKey, error) {
+	if !IsAPIKey(raw) {
+		return repoModels.User{}, repoModels.APIKey{}, ErrInvalidKey
+	}
+
+	now := time.Now()
+	key, err := s.repo.GetAPIKeyByHash(utils.HashToken(raw))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return repoModels.User{}, key, ErrInvalidKey
+	}
+	if err != nil {
+		return repoModels.User{}, key, err
+	}
+	if !key.IsUsable(now) {
+		return repoModels.User{}, key, ErrInvalidKey
+	}
+
+	user, err := s.users.GetUserByID(key.UserID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return user, key, ErrInvalidKey
+	}
+	if err != nil {
+		return user, key, err
+	}
+
+	return user, key, s.repo.TouchAPIKey(key.ID, now)
+}
+
+// IsAPIKey reports whether the credential has the shape of an API key.
+func IsAPIKey(raw string) bool {
+	return strings.HasPrefix(raw, KeyPrefix) && strings.Count(raw, "_") >= 2
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/mailer"
+	"blog-platform/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 48 * time.Hour
+)
+
+var ErrInvalidToken = fmt.Errorf("%w: invalid or expired token", utils.ErrBadRequest)
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	GetUserByEmail(email string) (repoModels.User, error)
+	UpdateUserFields(id primitive.ObjectID, set, unset bson.M) error
+}
+
+//go:generate mockery --name=TokenRepository --case underscore
+type TokenRepository interface {
+	CreateToken(token repoModels.UserToken) error
+	ConsumeToken(hash, purpose string) (repoModels.UserToken, error)
+	InvalidateTokens(userID primitive.ObjectID, purpose string) error
+}
+
+//go:generate mockery --name=SessionRepository --case underscore
+type SessionRepository interface {
+	DeleteUserSessions(userID, keep primitive.ObjectID) (int64, error)
+}
+
+type Service struct {
+	users    UserRepository
+	tokens   TokenRepository
+	sessions SessionRepository
+	mailer   mailer.Mailer
+	baseURL  string
+	resetURL string
+}
+
+// New creates the service, verification mails link to the API below baseURL and reset mails to resetURL, the
+// page of the front end where users pick their new password.
+func New(users UserRepository, tokens TokenRepository, sessions SessionRepository, mailer mailer.Mailer, baseURL, resetURL string) *Service {
+	return &Service{users: users, tokens: tokens, sessions: sessions, mailer: mailer, baseURL: strings.TrimRight(baseURL, "/"), resetURL: resetURL}
+}
+
+// ForgotPassword mails a reset link when the address belongs to a user. Unknown addresses are not
+// reported so the endpoint can not be used to find out which addresses are registered.
+func (s *Service) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.users.GetUserByEmail(strings.ToLower(strings.TrimSpace(email)))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.CanAuthenticate(time.Now()) {
+		return nil
+	}
+
+	// only the latest link is valid
+	if err = s.tokens.InvalidateTokens(user.ID, repoModels.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	token, err := s.issueToken(user.ID, repoModels.TokenPurposePasswordReset, PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+			"If that was you, use the link below within the next hour:\n\n%s\n\n"+
+			"Otherwise you can ignore this message.\n", user.Username, withToken(s.resetURL, token)),
+	})
+}
+
+// ResetPassword sets the new password and signs the user out of every browser session.
+func (s *Service) ResetPassword(req models.ResetPasswordReq) error {
+	token, err := s.tokens.ConsumeToken(utils.HashToken(req.Token), repoModels.TokenPurposePasswordReset)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.users.UpdateUserFields(token.UserID, bson.M{"password": req.Password}, bson.M{"must_change_password": ""})
+	if err != nil {
+		return err
+	}
+	_, err = s.sessions.DeleteUserSessions(token.UserID, primitive.NilObjectID)
+	return err
+}
+
+// SendEmailVerification mails a verification link to the user's address, users without an address are skipped.
+func (s *Service) SendEmailVerification(ctx context.Context, user repoModels.User) error {
+	if user.Email == "" || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err := s.tokens.InvalidateTokens(user.ID, repoModels.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+	token, err := s.issueToken(user.ID, repoModels.TokenPurposeEmailVerification, EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n",
+			user.Username, s.link("/api/v1/auth/email/verify", token)),
+	})
+}
+
+func (s *Service) ResendEmailVerification(ctx context.Context, access models.UserAccess) error {
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return fmt.Errorf("%w: no email address set", utils.ErrBadRequest)
+	}
+	if user.EmailVerifiedAt != nil {
+		return fmt.Errorf("%w: email address already verified", utils.ErrBadRequest)
+	}
+
+	return s.SendEmailVerification(ctx, user)
+}
+
+func (s *Service) VerifyEmail(rawToken string) error {
+	token, err := s.tokens.ConsumeToken(utils.HashToken(rawToken), repoModels.TokenPurposeEmailVerification)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.users.UpdateUserFields(token.UserID, bson.M{"email_verified_at": time.Now()}, nil)
+}
+
+func (s *Service) issueToken(userID primitive.ObjectID, purpose string, ttl time.Duration) (string, error) {
+	raw, err := utils.NewToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	return raw, s.tokens.CreateToken(repoModels.UserToken{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Purpose:   purpose,
+		Hash:      utils.HashToken(raw),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+}
+
+func (s *Service) link(path, token string) string {
+	return withToken(s.baseURL+path, token)
+}
+
+// withToken adds the token to the query of the link, which may have a query already.
+func withToken(link, token string) string {
+	sep := "?"
+	if strings.Contains(link, "?") {
+		sep = "&"
+	}
+	return link + sep + "token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,133 @@
+package bookmark
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/pagination"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	AddBookmark(bookmark repoModels.Bookmark) (bool, error)
+	RemoveBookmark(userID, postID primitive.ObjectID) (bool, error)
+	GetBookmarks(query repoModels.ListQuery) ([]repoModels.Bookmark, error)
+	CountBookmarks(filter interface{}) (int64, error)
+}
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetPostByID(id primitive.ObjectID) (repoModels.Post, error)
+	GetPostsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]repoModels.Post, error)
+	IncrementBookmarkCount(id primitive.ObjectID, delta int) error
+}
+
+// bookmarkKeys orders the bookmarks of a user, most recently bookmarked first.
+var bookmarkKeys = []pagination.Key{{Field: "_id", Desc: true}}
+
+type Service struct {
+	repo    Repository
+	posts   PostRepository
+	cursors *pagination.Codec
+}
+
+func New(repo Repository, posts PostRepository, cursors *pagination.Codec) *Service {
+	return &Service{repo: repo, posts: posts, cursors: cursors}
+}
+
+// Bookmark bookmarks the post for the user, bookmarking it again changes nothing.
+func (s *Service) Bookmark(postID primitive.ObjectID, access models.UserAccess) error {
+	post, err := s.posts.GetPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if post.DeletedAt != nil || post.IsDraft() && post.Author.ID != access.ID {
+		return mongo.ErrNoDocuments
+	}
+
+	added, err := s.repo.AddBookmark(repoModels.Bookmark{
+		ID:        primitive.NewObjectID(),
+		UserID:    access.ID,
+		PostID:    postID,
+		CreatedAt: time.Now(),
+	})
+	if err != nil || !added {
+		return err
+	}
+	return s.posts.IncrementBookmarkCount(postID, 1)
+}
+
+// Unbookmark removes the bookmark of the user, removing a bookmark that does not exist changes nothing.
+func (s *Service) Unbookmark(postID primitive.ObjectID, access models.UserAccess) error {
+	removed, err := s.repo.RemoveBookmark(access.ID, postID)
+	if err != nil || !removed {
+		return err
+	}
+	return s.posts.IncrementBookmarkCount(postID, -1)
+}
+
+// GetBookmarks lists a page of the posts the user bookmarked, most recently bookmarked first. Bookmarks of
+// posts that were deleted since are left out.
+func (s *Service) GetBookmarks(page models.PageReq, access models.UserAccess) ([]models.BookmarkRes, *repoModels.ListMetaData, error) {
+	filter := bson.M{"user_id": access.ID}
+	scope := pagination.Scope("bookmarks", bookmarkKeys)
+	cursor, err := s.cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	seek, sort, err := pagination.Seek(bookmarkKeys, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := repoModels.ListQuery{Filter: filter, Sort: sort, Limit: page.Limit + 1}
+	if seek != nil {
+		query.Filter = bson.M{"$and": bson.A{filter, seek}}
+	}
+
+	bookmarks, err := s.repo.GetBookmarks(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	bookmarks, next, prev := pagination.Page(bookmarks, page.Limit, cursor, func(b repoModels.Bookmark) []interface{} {
+		return []interface{}{b.ID}
+	})
+
+	ids := make([]primitive.ObjectID, len(bookmarks))
+	for i, b := range bookmarks {
+		ids[i] = b.PostID
+	}
+	posts, err := s.posts.GetPostsByIDs(context.Background(), ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[primitive.ObjectID]repoModels.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	res := make([]models.BookmarkRes, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		if post, ok := byID[b.PostID]; ok && post.DeletedAt == nil {
+			res = append(res, models.BookmarkRes{BookmarkedAt: b.CreatedAt, Post: post})
+		}
+	}
+
+	meta := repoModels.ListMetaData{Limit: page.Limit}
+	if meta.NextCursor, err = s.cursors.Encode(scope, next); err != nil {
+		return nil, nil, err
+	}
+	if meta.PrevCursor, err = s.cursors.Encode(scope, prev); err != nil {
+		return nil, nil, err
+	}
+	if page.Total {
+		total, err := s.repo.CountBookmarks(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.Total = &total
+	}
+	return res, &meta, nil
+}
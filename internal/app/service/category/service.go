@@ -0,0 +1,185 @@
+package category
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"slices"
+	"time"
+)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	CreateCategory(category repoModels.Category) error
+	GetCategories() ([]repoModels.Category, error)
+	GetCategoryByID(id primitive.ObjectID) (repoModels.Category, error)
+	GetCategoryBySlug(slug string) (repoModels.Category, error)
+	GetSubtree(id primitive.ObjectID) ([]repoModels.Category, error)
+	CountChildren(id primitive.ObjectID) (int64, error)
+	UpdateCategory(category repoModels.Category) error
+	DeleteCategory(id primitive.ObjectID) error
+}
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	MoveCategory(from primitive.ObjectID, to *primitive.ObjectID) (int64, error)
+}
+
+type Service struct {
+	repo  Repository
+	posts PostRepository
+}
+
+func New(repo Repository, posts PostRepository) *Service {
+	return &Service{repo: repo, posts: posts}
+}
+
+func (s *Service) CreateCategory(req models.CategoryReq) (repoModels.Category, error) {
+	now := time.Now()
+	category := repoModels.Category{ID: primitive.NewObjectID(), CreatedAt: now}
+	if err := s.apply(&category, req); err != nil {
+		return repoModels.Category{}, err
+	}
+	category.UpdatedAt = now
+
+	return category, s.repo.CreateCategory(category)
+}
+
+func (s *Service) GetCategories() ([]repoModels.Category, error) {
+	return s.repo.GetCategories()
+}
+
+// GetCategory looks a category up by id or by slug.
+func (s *Service) GetCategory(ref string) (repoModels.Category, error) {
+	if id, err := primitive.ObjectIDFromHex(ref); err == nil {
+		return s.repo.GetCategoryByID(id)
+	}
+	return s.repo.GetCategoryBySlug(ref)
+}
+
+// Subtree returns the ids of the category referenced by id or slug and of all its descendants.
+func (s *Service) Subtree(ref string) ([]primitive.ObjectID, error) {
+	category, err := s.GetCategory(ref)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: unknown category %q", utils.ErrBadRequest, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	subtree, err := s.repo.GetSubtree(category.ID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(subtree))
+	for _, c := range subtree {
+		ids = append(ids, c.ID)
+	}
+	return ids, nil
+}
+
+// UpdateCategory renames or moves a category, the ancestors of its descendants follow a move.
+func (s *Service) UpdateCategory(id primitive.ObjectID, req models.CategoryReq) (repoModels.Category, error) {
+	category, err := s.repo.GetCategoryByID(id)
+	if err != nil {
+		return repoModels.Category{}, err
+	}
+	oldAncestors := category.Ancestors
+	if err = s.apply(&category, req); err != nil {
+		return repoModels.Category{}, err
+	}
+	category.UpdatedAt = time.Now()
+	if err = s.repo.UpdateCategory(category); err != nil {
+		return repoModels.Category{}, err
+	}
+
+	if slices.Equal(oldAncestors, category.Ancestors) {
+		return category, nil
+	}
+	subtree, err := s.repo.GetSubtree(id)
+	if err != nil {
+		return category, err
+	}
+	for _, descendant := range subtree {
+		if descendant.ID == id {
+			continue
+		}
+		// the ancestors below the moved category stay the same
+		below := descendant.Ancestors[len(oldAncestors)+1:]
+		descendant.Ancestors = append(append(append([]primitive.ObjectID{}, category.Ancestors...), id), below...)
+		if err = s.repo.UpdateCategory(descendant); err != nil {
+			return category, err
+		}
+	}
+	return category, nil
+}
+
+// DeleteCategory removes a category without children, its posts move to the parent category.
+func (s *Service) DeleteCategory(id primitive.ObjectID) error {
+	category, err := s.repo.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+	children, err := s.repo.CountChildren(id)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return fmt.Errorf("%w: category has %d subcategories, move or delete them first", utils.ErrBadRequest, children)
+	}
+
+	if _, err = s.posts.MoveCategory(id, category.ParentID); err != nil {
+		return err
+	}
+	return s.repo.DeleteCategory(id)
+}
+
+// apply copies the request onto the category, checking the slug is free and the parent exists and is not
+// the category itself or one of its descendants.
+func (s *Service) apply(category *repoModels.Category, req models.CategoryReq) error {
+	slug := utils.Slugify(req.Slug)
+	if slug == "" {
+		slug = utils.Slugify(req.Name)
+	}
+	if slug == "" {
+		return fmt.Errorf("%w: name must contain letters or digits", utils.ErrBadRequest)
+	}
+	if _, err := primitive.ObjectIDFromHex(slug); err == nil {
+		return fmt.Errorf("%w: slug can not look like an id", utils.ErrBadRequest)
+	}
+	if slug != category.Slug {
+		existing, err := s.repo.GetCategoryBySlug(slug)
+		if err == nil && existing.ID != category.ID {
+			return fmt.Errorf("%w: slug %q is taken", utils.ErrBadRequest, slug)
+		}
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+	}
+
+	ancestors := []primitive.ObjectID{}
+	if req.ParentID != nil {
+		parent, err := s.repo.GetCategoryByID(*req.ParentID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: unknown parent category", utils.ErrBadRequest)
+		}
+		if err != nil {
+			return err
+		}
+		if parent.ID == category.ID || slices.Contains(parent.Ancestors, category.ID) {
+			return fmt.Errorf("%w: a category can not be moved below itself", utils.ErrBadRequest)
+		}
+		ancestors = append(append(ancestors, parent.Ancestors...), parent.ID)
+	}
+
+	category.Name = req.Name
+	category.Slug = slug
+	category.Description = req.Description
+	category.ParentID = req.ParentID
+	category.Ancestors = ancestors
+	return nil
+}
@@ -0,0 +1,264 @@
+package comment
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/spam"
+	"blog-platform/internal/utils"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"strings"
+	"time"
+)
+
+// Moderation decisions.
+const (
+	DecisionApprove = "approve"
+	DecisionReject  = "reject"
+	DecisionSpam    = "spam"
+)
+
+const (
+	trainedSpam = "spam"
+	trainedHam  = "ham"
+)
+
+// queueKeys orders the moderation queue oldest first.
+var queueKeys = []pagination.Key{{Field: "_id"}}
+
+// moderate decides the status of a new or edited comment. Blocklisted terms and a high spam score file it as
+// spam, too many links hold it, and new comments are held as the moderation mode asks. Comments of admins and
+// of the post author are never held.
+func (s *Service) moderate(comment *repoModels.Comment, post repoModels.Post, access models.UserAccess, isNew bool) error {
+	settings, err := s.settings.GetModerationSettings()
+	if err != nil {
+		return err
+	}
+
+	comment.Status, comment.ModerationReason = repoModels.CommentStatusApproved, ""
+	comment.SpamScore, err = s.classifier.Score(context.Background(), comment.Content)
+	if err != nil {
+		log.Printf("scoring comment %s for spam: %v", comment.ID.Hex(), err)
+		comment.SpamScore = 0
+	}
+	if access.IsAdmin() || post.Author.ID == access.ID {
+		return nil
+	}
+
+	if term, ok := spam.MatchBlocklist(comment.Content, settings.Blocklist); ok {
+		comment.Status, comment.ModerationReason = repoModels.CommentStatusSpam, fmt.Sprintf("contains the blocked term %q", term)
+		return nil
+	}
+	if comment.SpamScore >= settings.SpamThreshold {
+		comment.Status, comment.ModerationReason = repoModels.CommentStatusSpam, fmt.Sprintf("spam score %.2f", comment.SpamScore)
+		return nil
+	}
+	if links := spam.CountLinks(comment.Content); links > settings.MaxLinks {
+		comment.Status, comment.ModerationReason = repoModels.CommentStatusPending, fmt.Sprintf("%d links", links)
+		return nil
+	}
+	if !isNew {
+		return nil
+	}
+
+	switch settings.Mode {
+	case repoModels.ModerationAll:
+		comment.Status, comment.ModerationReason = repoModels.CommentStatusPending, "every comment is moderated"
+	case repoModels.ModerationFirst:
+		approved, err := s.repo.HasApproved(access.ID)
+		if err != nil {
+			return err
+		}
+		if !approved {
+			comment.Status, comment.ModerationReason = repoModels.CommentStatusPending, "first comment"
+		}
+	}
+	return nil
+}
+
+// ModerationQueue lists the comments with the requested status, pending by default, oldest first. Admins see
+// every comment, other users the comments on their own posts.
+func (s *Service) ModerationQueue(filter models.CommentQueueFilter, access models.UserAccess) ([]repoModels.Comment, *repoModels.ListMetaData, error) {
+	status := filter.Status
+	if status == "" {
+		status = repoModels.CommentStatusPending
+	}
+	switch status {
+	case repoModels.CommentStatusPending, repoModels.CommentStatusSpam, repoModels.CommentStatusRejected, repoModels.CommentStatusApproved:
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown status %q", utils.ErrBadRequest, status)
+	}
+
+	query := bson.M{"status": status, "deleted_at": bson.M{"$exists": false}}
+	if !access.IsAdmin() {
+		query["post_author_id"] = access.ID
+	}
+	if filter.PostID != nil {
+		query["post_id"] = *filter.PostID
+	}
+
+	scope := pagination.Scope("comment-queue", queueKeys)
+	cursor, err := s.cursors.Decode(scope, filter.Page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	seek, sort, err := pagination.Seek(queueKeys, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	list := repoModels.ListQuery{Filter: query, Sort: sort, Limit: filter.Page.Limit + 1}
+	if seek != nil {
+		list.Filter = bson.M{"$and": bson.A{query, seek}}
+	}
+
+	comments, err := s.repo.GetComments(list)
+	if err != nil {
+		return nil, nil, err
+	}
+	comments, next, prev := pagination.Page(comments, filter.Page.Limit, cursor, func(c repoModels.Comment) []interface{} {
+		return []interface{}{c.ID}
+	})
+
+	meta := repoModels.ListMetaData{Limit: filter.Page.Limit}
+	if meta.NextCursor, err = s.cursors.Encode(scope, next); err != nil {
+		return nil, nil, err
+	}
+	if meta.PrevCursor, err = s.cursors.Encode(scope, prev); err != nil {
+		return nil, nil, err
+	}
+	if filter.Page.Total {
+		total, err := s.repo.CountComments(query)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.Total = &total
+	}
+	return comments, &meta, nil
+}
+
+// Moderate applies a moderator decision to a comment. Approving and marking as spam train the spam classifier,
+// a changed decision first takes back what it learned before. The classifier is only trained once the decision is
+// stored, so a failed update can not leave it trained on a decision the comment does not record.
+func (s *Service) Moderate(id primitive.ObjectID, decision string, access models.UserAccess) (repoModels.Comment, error) {
+	comment, err := s.repo.GetCommentByID(id)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	if comment.DeletedAt != nil {
+		return repoModels.Comment{}, fmt.Errorf("%w: the comment is deleted", utils.ErrBadRequest)
+	}
+	if comment.PostAuthorID != access.ID && !access.IsAdmin() {
+		return repoModels.Comment{}, utils.ErrNotAllowed
+	}
+
+	var status, reason, trainAs string
+	switch decision {
+	case DecisionApprove:
+		status, trainAs = repoModels.CommentStatusApproved, trainedHam
+	case DecisionSpam:
+		status, reason, trainAs = repoModels.CommentStatusSpam, "marked as spam by a moderator", trainedSpam
+	case DecisionReject:
+		status, reason = repoModels.CommentStatusRejected, "rejected by a moderator"
+	default:
+		return repoModels.Comment{}, fmt.Errorf("%w: unknown decision %q", utils.ErrBadRequest, decision)
+	}
+
+	now := time.Now()
+	err = s.repo.UpdateCommentFields(id, bson.M{
+		"status":            status,
+		"moderation_reason": reason,
+		"moderated_at":      now,
+		"trained_as":        trainAs,
+	})
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	s.train(comment, trainAs)
+	comment.Status, comment.ModerationReason, comment.ModeratedAt, comment.TrainedAs = status, reason, &now, trainAs
+
+	s.recount(comment.PostID)
+	return comment, nil
+}
+
+// train brings the classifier in line with the decision, failures are only logged as the decision counts more.
+func (s *Service) train(comment repoModels.Comment, trainAs string) {
+	if comment.TrainedAs == trainAs {
+		return
+	}
+	ctx := context.Background()
+	if comment.TrainedAs != "" {
+		if err := s.classifier.Untrain(ctx, comment.Content, comment.TrainedAs == trainedSpam); err != nil {
+			log.Printf("untraining the spam classifier with comment %s: %v", comment.ID.Hex(), err)
+		}
+	}
+	if trainAs != "" {
+		if err := s.classifier.Train(ctx, comment.Content, trainAs == trainedSpam); err != nil {
+			log.Printf("training the spam classifier with comment %s: %v", comment.ID.Hex(), err)
+		}
+	}
+}
+
+func (s *Service) GetModerationSettings() (repoModels.ModerationSettings, error) {
+	return s.settings.GetModerationSettings()
+}
+
+// SetModerationSettings replaces the moderation settings, blocklist terms are trimmed and lower cased.
+func (s *Service) SetModerationSettings(req models.ModerationSettingsReq) (repoModels.ModerationSettings, error) {
+	if !repoModels.IsValidModerationMode(req.Mode) {
+		return repoModels.ModerationSettings{}, fmt.Errorf("%w: mode must be open, first or all", utils.ErrBadRequest)
+	}
+	if req.MaxLinks < 0 {
+		return repoModels.ModerationSettings{}, fmt.Errorf("%w: max_links can not be negative", utils.ErrBadRequest)
+	}
+	if req.SpamThreshold <= 0 || req.SpamThreshold > 1 {
+		return repoModels.ModerationSettings{}, fmt.Errorf("%w: spam_threshold must be above 0 and at most 1", utils.ErrBadRequest)
+	}
+
+	settings := repoModels.ModerationSettings{
+		ID:            repoModels.ModerationSettingsID,
+		Mode:          req.Mode,
+		MaxLinks:      req.MaxLinks,
+		Blocklist:     []string{},
+		SpamThreshold: req.SpamThreshold,
+	}
+	seen := map[string]bool{}
+	for _, term := range req.Blocklist {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term != "" && !seen[term] {
+			seen[term] = true
+			settings.Blocklist = append(settings.Blocklist, term)
+		}
+	}
+	return settings, s.settings.SaveModerationSettings(settings)
+}
+
+// SetShadowHidden hides or shows every comment of the user to everybody else, including the ones they write
+// from now on.
+func (s *Service) SetShadowHidden(userID primitive.ObjectID, hidden bool) error {
+	if _, err := s.users.GetUserByID(userID); err != nil {
+		return err
+	}
+
+	var set, unset bson.M
+	if hidden {
+		set = bson.M{"shadow_hidden": true}
+	} else {
+		unset = bson.M{"shadow_hidden": ""}
+	}
+	if err := s.users.UpdateUserFields(userID, set, unset); err != nil {
+		return err
+	}
+
+	postIDs, err := s.repo.SetShadow(userID, hidden)
+	if err != nil {
+		return err
+	}
+	for _, postID := range postIDs {
+		s.recount(postID)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package comment
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/spam"
+	"blog-platform/internal/utils"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// EditWindow is how long after posting authors can still edit a comment.
+	EditWindow = 15 * time.Minute
+	// MaxDepth limits the nesting of replies, a top level comment has depth 1.
+	MaxDepth = 8
+	// MaxLength limits the length of a comment in characters.
+	MaxLength = 5000
+)
+
+var ErrCommentsClosed = fmt.Errorf("%w: comments on this post are closed", utils.ErrNotAllowed)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	CreateComment(comment repoModels.Comment) error
+	GetCommentByID(id primitive.ObjectID) (repoModels.Comment, error)
+	GetCommentsByPost(postID primitive.ObjectID) ([]repoModels.Comment, error)
+	GetComments(query repoModels.ListQuery) ([]repoModels.Comment, error)
+	CountComments(filter interface{}) (int64, error)
+	CountVisible(postID primitive.ObjectID) (int64, error)
+	HasApproved(authorID primitive.ObjectID) (bool, error)
+	UpdateCommentFields(id primitive.ObjectID, set bson.M) error
+	SetShadow(authorID primitive.ObjectID, shadow bool) ([]primitive.ObjectID, error)
+	DeleteComment(id primitive.ObjectID, deletedBy string) (bool, error)
+}
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetPostByID(id primitive.ObjectID) (repoModels.Post, error)
+	SetCommentCount(id primitive.ObjectID, count int64) error
+	SetCommentsClosed(id primitive.ObjectID, closed bool) error
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	UpdateUserFields(id primitive.ObjectID, set, unset bson.M) error
+}
+
+//go:generate mockery --name=SettingsRepository --case underscore
+type SettingsRepository interface {
+	GetModerationSettings() (repoModels.ModerationSettings, error)
+	SaveModerationSettings(settings repoModels.ModerationSettings) error
+}
+
+type Service struct {
+	repo       Repository
+	posts      PostRepository
+	users      UserRepository
+	settings   SettingsRepository
+	classifier spam.Classifier
+	cursors    *pagination.Codec
+}
+
+func New(repo Repository, posts PostRepository, users UserRepository, settings SettingsRepository, classifier spam.Classifier, cursors *pagination.Codec) *Service {
+	return &Service{repo: repo, posts: posts, users: users, settings: settings, classifier: classifier, cursors: cursors}
+}
+
+// GetThread returns the comments on the post the user can see as a tree, oldest first on every level. Deleted
+// and hidden comments are kept as placeholders while they have replies that are shown.
+func (s *Service) GetThread(postID primitive.ObjectID, access models.UserAccess) (models.CommentThreadRes, error) {
+	post, err := s.getPost(postID, access)
+	if err != nil {
+		return models.CommentThreadRes{}, err
+	}
+	comments, err := s.repo.GetCommentsByPost(postID)
+	if err != nil {
+		return models.CommentThreadRes{}, err
+	}
+
+	children := map[primitive.ObjectID][]repoModels.Comment{}
+	var roots []repoModels.Comment
+	for _, c := range comments {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	return models.CommentThreadRes{
+		PostID:   post.ID,
+		Count:    post.CommentCount,
+		Closed:   post.CommentsClosed,
+		Comments: buildTree(roots, children, access.ID),
+	}, nil
+}
+
+func buildTree(comments []repoModels.Comment, children map[primitive.ObjectID][]repoModels.Comment, viewer primitive.ObjectID) []models.CommentNode {
+	nodes := []models.CommentNode{}
+	for _, c := range comments {
+		replies := buildTree(children[c.ID], children, viewer)
+		node := models.CommentNode{Comment: c, Replies: replies}
+		switch {
+		case c.DeletedAt != nil || !c.VisibleTo(viewer):
+			if len(replies) == 0 {
+				continue
+			}
+			node.Comment = repoModels.Comment{
+				ID: c.ID, PostID: c.PostID, ParentID: c.ParentID, Ancestors: c.Ancestors,
+				CreatedAt: c.CreatedAt, DeletedAt: c.DeletedAt, DeletedBy: c.DeletedBy,
+			}
+			node.Hidden = c.DeletedAt == nil
+		default:
+			node.Comment = publicView(c)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// CreateComment adds a comment to the post, or a reply when the request names a parent comment.
+func (s *Service) CreateComment(postID primitive.ObjectID, req models.CommentReq, access models.UserAccess) (repoModels.Comment, error) {
+	post, err := s.getPost(postID, access)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	if post.CommentsClosed {
+		return repoModels.Comment{}, ErrCommentsClosed
+	}
+	content, err := checkContent(req.Content)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	comment := repoModels.Comment{
+		ID:           primitive.NewObjectID(),
+		PostID:       post.ID,
+		PostAuthorID: post.Author.ID,
+		Ancestors:    []primitive.ObjectID{},
+		Author:       repoModels.BasicUser{ID: access.ID, Username: access.Name},
+		Content:      content,
+		Shadow:       user.ShadowHidden,
+		CreatedAt:    time.Now(),
+	}
+	if req.ParentID != nil {
+		parent, err := s.repo.GetCommentByID(*req.ParentID)
+		if errors.Is(err, mongo.ErrNoDocuments) || err == nil && parent.PostID != post.ID {
+			return repoModels.Comment{}, fmt.Errorf("%w: the parent comment is not a comment on this post", utils.ErrBadRequest)
+		}
+		if err != nil {
+			return repoModels.Comment{}, err
+		}
+		if parent.DeletedAt != nil || !parent.VisibleTo(access.ID) {
+			return repoModels.Comment{}, fmt.Errorf("%w: can not reply to a deleted or hidden comment", utils.ErrBadRequest)
+		}
+		if len(parent.Ancestors)+2 > MaxDepth {
+			return repoModels.Comment{}, fmt.Errorf("%w: replies can be nested at most %d levels deep", utils.ErrBadRequest, MaxDepth)
+		}
+		comment.ParentID = &parent.ID
+		comment.Ancestors = append(append(comment.Ancestors, parent.Ancestors...), parent.ID)
+	}
+	if err = s.moderate(&comment, post, access, true); err != nil {
+		return repoModels.Comment{}, err
+	}
+
+	if err = s.repo.CreateComment(comment); err != nil {
+		return repoModels.Comment{}, err
+	}
+	s.recount(post.ID)
+	return publicView(comment), nil
+}
+
+// UpdateComment lets the author change a comment during the EditWindow after posting it. The new content
+// goes through the spam checks again, an edit never releases a held comment.
+func (s *Service) UpdateComment(id primitive.ObjectID, req models.CommentUpdateReq, access models.UserAccess) (repoModels.Comment, error) {
+	comment, err := s.repo.GetCommentByID(id)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	if comment.DeletedAt != nil {
+		return repoModels.Comment{}, mongo.ErrNoDocuments
+	}
+	if comment.Author.ID != access.ID {
+		return repoModels.Comment{}, utils.ErrNotAllowed
+	}
+	now := time.Now()
+	if now.Sub(comment.CreatedAt) > EditWindow {
+		return repoModels.Comment{}, fmt.Errorf("%w: comments can only be edited for %s after posting", utils.ErrNotAllowed, EditWindow)
+	}
+	content, err := checkContent(req.Content)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+
+	post, err := s.posts.GetPostByID(comment.PostID)
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	held := comment
+	comment.Content = content
+	comment.EditedAt = &now
+	if err = s.moderate(&comment, post, access, false); err != nil {
+		return repoModels.Comment{}, err
+	}
+	if comment.Status == repoModels.CommentStatusApproved && held.Status != repoModels.CommentStatusApproved {
+		comment.Status, comment.ModerationReason = held.Status, held.ModerationReason
+	}
+
+	err = s.repo.UpdateCommentFields(id, bson.M{
+		"content":           comment.Content,
+		"edited_at":         now,
+		"status":            comment.Status,
+		"spam_score":        comment.SpamScore,
+		"moderation_reason": comment.ModerationReason,
+	})
+	if err != nil {
+		return repoModels.Comment{}, err
+	}
+	if comment.Status != held.Status {
+		s.recount(comment.PostID)
+	}
+	return publicView(comment), nil
+}
+
+// DeleteComment soft deletes a comment, its replies stay in the thread. Besides the comment author the
+// author of the post and admins can delete it as moderators.
+func (s *Service) DeleteComment(id primitive.ObjectID, access models.UserAccess) error {
+	comment, err := s.repo.GetCommentByID(id)
+	if err != nil {
+		return err
+	}
+	if comment.DeletedAt != nil {
+		return mongo.ErrNoDocuments
+	}
+
+	deletedBy := repoModels.CommentDeletedByAuthor
+	if comment.Author.ID != access.ID {
+		if err = s.authoriseModerator(comment.PostID, access); err != nil {
+			return err
+		}
+		deletedBy = repoModels.CommentDeletedByModerator
+	}
+
+	deleted, err := s.repo.DeleteComment(id, deletedBy)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		s.recount(comment.PostID)
+	}
+	return nil
+}
+
+// SetCommentsClosed opens or closes the post for new comments, existing ones stay visible.
+func (s *Service) SetCommentsClosed(postID primitive.ObjectID, closed bool, access models.UserAccess) error {
+	if err := s.authoriseModerator(postID, access); err != nil {
+		return err
+	}
+	return s.posts.SetCommentsClosed(postID, closed)
+}
+
+// authoriseModerator allows the author of the post and admins.
+func (s *Service) authoriseModerator(postID primitive.ObjectID, access models.UserAccess) error {
+	post, err := s.posts.GetPostByID(postID)
+	if err != nil {
+		return err
+	}
+	if post.Author.ID == access.ID || access.IsAdmin() {
+		return nil
+	}
+	return utils.ErrNotAllowed
+}
+
+// getPost returns the post unless it is deleted or a draft of another user.
+func (s *Service) getPost(id primitive.ObjectID, access models.UserAccess) (repoModels.Post, error) {
+	post, err := s.posts.GetPostByID(id)
+	if err == nil && (post.DeletedAt != nil || post.IsDraft() && post.Author.ID != access.ID) {
+		return repoModels.Post{}, mongo.ErrNoDocuments
+	}
+	return post, err
+}
+
+// recount updates the comment count of the post to the comments everybody can see, failures are only logged
+// because the comment itself was written.
+func (s *Service) recount(postID primitive.ObjectID) {
+	count, err := s.repo.CountVisible(postID)
+	if err == nil {
+		err = s.posts.SetCommentCount(postID, count)
+	}
+	if err != nil {
+		log.Printf("updating the comment count of post %s: %v", postID.Hex(), err)
+	}
+}
+
+// publicView leaves out the moderation details, authors see their held comments as pending whatever the reason.
+func publicView(c repoModels.Comment) repoModels.Comment {
+	if c.Status != repoModels.CommentStatusApproved {
+		c.Status = repoModels.CommentStatusPending
+	}
+	c.SpamScore, c.ModerationReason, c.ModeratedAt = 0, "", nil
+	return c
+}
+
+func checkContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", fmt.Errorf("%w: a comment can not be empty", utils.ErrBadRequest)
+	}
+	if len([]rune(content)) > MaxLength {
+		return "", fmt.Errorf("%w: a comment can have at most %d characters", utils.ErrBadRequest, MaxLength)
+	}
+	return content, nil
+}
@@ -0,0 +1,115 @@
+package feed
+
+import (
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/feed"
+	"blog-platform/internal/site"
+	"blog-platform/internal/utils"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultLimit and MaxLimit bound the number of posts in a feed.
+	DefaultLimit = 20
+	MaxLimit     = 50
+)
+
+//go:generate mockery --name=PostService --case underscore
+type PostService interface {
+	GetPosts(ctx context.Context, postFilter models.PostFilter) ([]repoModels.Post, *repoModels.ListMetaData, error)
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByUsername(username string) (repoModels.User, error)
+}
+
+type Service struct {
+	posts   PostService
+	users   UserRepository
+	site    config.SiteConfig
+	baseURL string
+}
+
+func New(posts PostService, users UserRepository, site config.SiteConfig, baseURL string) *Service {
+	return &Service{posts: posts, users: users, site: site, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Feed returns the newest published posts as a feed. Feeds of authors that do not exist are not found.
+func (s *Service) Feed(ctx context.Context, req models.FeedReq) (feed.Feed, error) {
+	if _, ok := feed.ContentTypes[req.Format]; !ok {
+		return feed.Feed{}, fmt.Errorf("%w: unknown feed format %q, formats are rss, atom and json", utils.ErrBadRequest, req.Format)
+	}
+	if req.Limit <= 0 {
+		req.Limit = DefaultLimit
+	} else if req.Limit > MaxLimit {
+		req.Limit = MaxLimit
+	}
+
+	now := time.Now()
+	filter := models.PostFilter{
+		Tag:       req.Tag,
+		Published: models.TimeRange{To: &now},
+		Sort:      []models.SortField{{Field: "published_at", Desc: true}},
+		Full:      req.Full,
+		Page:      models.PageReq{Limit: req.Limit},
+	}
+	f := feed.Feed{
+		Title:       s.site.Title,
+		Description: s.site.Description,
+		Link:        s.baseURL + site.HomePath,
+		FeedURL:     s.baseURL + site.FeedPath(req.Format),
+	}
+	switch {
+	case req.Author != "":
+		author, err := s.users.GetUserByUsername(req.Author)
+		if err != nil {
+			return feed.Feed{}, err
+		}
+		filter.Authors = []string{author.Username}
+		f.Title = author.Username + " – " + s.site.Title
+		f.Description = "Posts by " + author.Username
+		f.Link = s.baseURL + site.AuthorPath(author.Username)
+		f.FeedURL = s.baseURL + site.AuthorFeedPath(author.Username, req.Format)
+	case req.Tag != "":
+		tag := utils.NormalizeTag(req.Tag)
+		f.Title = "#" + tag + " – " + s.site.Title
+		f.Description = "Posts tagged " + tag
+		f.Link = s.baseURL + site.TagPath(tag)
+		f.FeedURL = s.baseURL + site.TagFeedPath(tag, req.Format)
+	}
+
+	posts, _, err := s.posts.GetPosts(ctx, filter)
+	if err != nil {
+		return feed.Feed{}, err
+	}
+	for _, post := range posts {
+		item := feed.Item{
+			ID:         s.baseURL + "/api/v1/posts/" + post.ID.Hex(),
+			Title:      post.Title,
+			Link:       s.baseURL + site.PostPath(post.Author.Username, post.Slug),
+			Author:     post.Author.Username,
+			AuthorLink: s.baseURL + site.AuthorPath(post.Author.Username),
+			Summary:    post.Excerpt,
+			Tags:       post.Tags,
+			Published:  post.PublishedAt,
+			Updated:    post.UpdatedAt,
+		}
+		if req.Full {
+			item.ContentHTML = post.ContentHTML
+		}
+		if item.Updated.Before(item.Published) {
+			item.Updated = item.Published
+		}
+		if item.Updated.After(f.Updated) {
+			f.Updated = item.Updated
+		}
+		f.Items = append(f.Items, item)
+	}
+	return f, nil
+}
@@ -0,0 +1,226 @@
+package follow
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/utils"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	AddFollow(follow repoModels.Follow) (bool, error)
+	RemoveFollow(followerID, followeeID primitive.ObjectID) (bool, error)
+	GetFollows(query repoModels.ListQuery) ([]repoModels.Follow, error)
+	CountFollows(filter interface{}) (int64, error)
+	GetFolloweeIDs(followerID primitive.ObjectID) ([]primitive.ObjectID, error)
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	GetUsers(query repoModels.ListQuery) ([]repoModels.User, error)
+	IncrementFollowCounts(followerID, followeeID primitive.ObjectID, delta int) error
+}
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetPosts(ctx context.Context, query repoModels.ListQuery) ([]repoModels.Post, error)
+	CountPosts(ctx context.Context, filter interface{}) (int64, error)
+}
+
+var (
+	// followKeys orders followers and followed users, the most recent follow first.
+	followKeys = []pagination.Key{{Field: "_id", Desc: true}}
+	// feedKeys orders the feed newest first.
+	feedKeys = []pagination.Key{{Field: "published_at", Desc: true}, {Field: "_id", Desc: true}}
+)
+
+type Service struct {
+	repo    Repository
+	users   UserRepository
+	posts   PostRepository
+	cursors *pagination.Codec
+}
+
+func New(repo Repository, users UserRepository, posts PostRepository, cursors *pagination.Codec) *Service {
+	return &Service{repo: repo, users: users, posts: posts, cursors: cursors}
+}
+
+// Follow makes the user follow the author, following an author twice changes nothing.
+func (s *Service) Follow(authorID primitive.ObjectID, access models.UserAccess) error {
+	if authorID == access.ID {
+		return fmt.Errorf("%w: you can not follow yourself", utils.ErrBadRequest)
+	}
+	if _, err := s.getUser(authorID); err != nil {
+		return err
+	}
+
+	added, err := s.repo.AddFollow(repoModels.Follow{
+		ID:         primitive.NewObjectID(),
+		FollowerID: access.ID,
+		FolloweeID: authorID,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil || !added {
+		return err
+	}
+	return s.users.IncrementFollowCounts(access.ID, authorID, 1)
+}
+
+// Unfollow stops the user following the author, unfollowing an author that is not followed changes nothing.
+func (s *Service) Unfollow(authorID primitive.ObjectID, access models.UserAccess) error {
+	removed, err := s.repo.RemoveFollow(access.ID, authorID)
+	if err != nil || !removed {
+		return err
+	}
+	return s.users.IncrementFollowCounts(access.ID, authorID, -1)
+}
+
+// GetFollowers lists a page of the users following the user, the most recent follower first.
+func (s *Service) GetFollowers(userID primitive.ObjectID, page models.PageReq) ([]models.FollowRes, *repoModels.ListMetaData, error) {
+	return s.list("followers", bson.M{"followee_id": userID}, page, func(f repoModels.Follow) primitive.ObjectID {
+		return f.FollowerID
+	})
+}
+
+// GetFollowing lists a page of the users the user follows, the most recently followed first.
+func (s *Service) GetFollowing(userID primitive.ObjectID, page models.PageReq) ([]models.FollowRes, *repoModels.ListMetaData, error) {
+	return s.list("following", bson.M{"follower_id": userID}, page, func(f repoModels.Follow) primitive.ObjectID {
+		return f.FolloweeID
+	})
+}
+
+// list pages through the follows matching filter and resolves the users other picks from them, deleted users
+// are left out.
+func (s *Service) list(listing string, filter bson.M, page models.PageReq, other func(repoModels.Follow) primitive.ObjectID) ([]models.FollowRes, *repoModels.ListMetaData, error) {
+	scope := pagination.Scope(listing, followKeys)
+	cursor, err := s.cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	seek, sort, err := pagination.Seek(followKeys, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := repoModels.ListQuery{Filter: filter, Sort: sort, Limit: page.Limit + 1}
+	if seek != nil {
+		query.Filter = bson.M{"$and": bson.A{filter, seek}}
+	}
+
+	follows, err := s.repo.GetFollows(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	follows, next, prev := pagination.Page(follows, page.Limit, cursor, func(f repoModels.Follow) []interface{} {
+		return []interface{}{f.ID}
+	})
+
+	ids := make([]primitive.ObjectID, len(follows))
+	for i, f := range follows {
+		ids[i] = other(f)
+	}
+	users, err := s.users.GetUsers(repoModels.ListQuery{
+		Filter: bson.M{"_id": bson.M{"$in": ids}, "deleted_at": bson.M{"$exists": false}},
+		Limit:  len(ids),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[primitive.ObjectID]repoModels.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	res := make([]models.FollowRes, 0, len(follows))
+	for _, f := range follows {
+		if user, ok := byID[other(f)]; ok {
+			res = append(res, models.FollowRes{User: repoModels.BasicUser{ID: user.ID, Username: user.Username}, FollowedAt: f.CreatedAt})
+		}
+	}
+
+	meta, err := s.meta(scope, page, next, prev, func() (int64, error) { return s.repo.CountFollows(filter) })
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, meta, nil
+}
+
+// GetFeed lists a page of the posts of the authors the user follows, newest first. The posts are read when
+// the feed is requested, a follow shows the earlier posts of the author too.
+func (s *Service) GetFeed(ctx context.Context, page models.PageReq, access models.UserAccess) ([]repoModels.Post, *repoModels.ListMetaData, error) {
+	authorIDs, err := s.repo.GetFolloweeIDs(access.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	filter := bson.M{"author._id": bson.M{"$in": authorIDs}, "deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$exists": true}}
+
+	scope := pagination.Scope("feed", feedKeys)
+	cursor, err := s.cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts := []repoModels.Post{}
+	var next, prev *pagination.Cursor
+	if len(authorIDs) > 0 {
+		seek, sort, err := pagination.Seek(feedKeys, cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		query := repoModels.ListQuery{Filter: filter, Sort: sort, Limit: page.Limit + 1}
+		if seek != nil {
+			query.Filter = bson.M{"$and": bson.A{filter, seek}}
+		}
+		if posts, err = s.posts.GetPosts(ctx, query); err != nil {
+			return nil, nil, err
+		}
+		posts, next, prev = pagination.Page(posts, page.Limit, cursor, func(post repoModels.Post) []interface{} {
+			return []interface{}{post.PublishedAt, post.ID}
+		})
+	}
+
+	meta, err := s.meta(scope, page, next, prev, func() (int64, error) {
+		if len(authorIDs) == 0 {
+			return 0, nil
+		}
+		return s.posts.CountPosts(ctx, filter)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return posts, meta, nil
+}
+
+// meta describes the page, count is only called when the total was asked for.
+func (s *Service) meta(scope string, page models.PageReq, next, prev *pagination.Cursor, count func() (int64, error)) (*repoModels.ListMetaData, error) {
+	var err error
+	meta := repoModels.ListMetaData{Limit: page.Limit}
+	if meta.NextCursor, err = s.cursors.Encode(scope, next); err != nil {
+		return nil, err
+	}
+	if meta.PrevCursor, err = s.cursors.Encode(scope, prev); err != nil {
+		return nil, err
+	}
+	if page.Total {
+		total, err := count()
+		if err != nil {
+			return nil, err
+		}
+		meta.Total = &total
+	}
+	return &meta, nil
+}
+
+// getUser returns the user unless it is deleted.
+func (s *Service) getUser(id primitive.ObjectID) (repoModels.User, error) {
+	user, err := s.users.GetUserByID(id)
+	if err == nil && user.DeletedAt != nil {
+		return repoModels.User{}, mongo.ErrNoDocuments
+	}
+	return user, err
+}
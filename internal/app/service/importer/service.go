@@ -0,0 +1,377 @@
+package importer
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	srvPost "blog-platform/internal/app/service/post"
+	"blog-platform/internal/importer"
+	"blog-platform/internal/render"
+	"blog-platform/internal/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// What an import did, or would do in a dry run, with an author or a post.
+const (
+	ActionMatched   = "matched"
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionUnchanged = "unchanged"
+	ActionSkipped   = "skipped"
+	ActionFailed    = "failed"
+)
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByUsername(username string) (repoModels.User, error)
+	GetUserByEmail(email string) (repoModels.User, error)
+	CreateUser(user repoModels.User) error
+}
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetPostByImport(source, id string) (repoModels.Post, error)
+	SlugTaken(authorID primitive.ObjectID, slug string, exclude primitive.ObjectID) (bool, error)
+	CreatePost(post repoModels.Post) error
+	UpdatePost(post repoModels.Post) error
+}
+
+//go:generate mockery --name=Indexer --case underscore
+type Indexer interface {
+	IndexPost(post repoModels.Post) error
+}
+
+// Options tune an import. Authors maps the author keys of the export to usernames, authors that are not mapped
+// are matched by email and then by username. CreateAuthors creates users for the authors that match nobody,
+// otherwise their posts fail. DefaultAuthor is the username of the author of the posts that name none. A dry run
+// reports what the import would do without writing anything.
+type Options struct {
+	Authors       map[string]string
+	CreateAuthors bool
+	DefaultAuthor string
+	DryRun        bool
+}
+
+// AuthorResult tells how an author of the export was mapped to a user.
+type AuthorResult struct {
+	Key      string
+	Username string
+	Action   string
+	Reason   string
+}
+
+// PostResult tells what happened to a post of the export, Slug and Author are those it has here.
+type PostResult struct {
+	ID     string
+	Title  string
+	Slug   string
+	Author string
+	Action string
+	Reason string
+}
+
+// Report lists the authors and posts of an import in the order of the export.
+type Report struct {
+	DryRun  bool
+	Authors []AuthorResult
+	Posts   []PostResult
+}
+
+// Count counts the posts with the action.
+func (r Report) Count(action string) int {
+	n := 0
+	for _, post := range r.Posts {
+		if post.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Service imports the exports of other blogs. Imported posts remember their original, importing the same export
+// again updates the posts that changed there and leaves the others alone.
+type Service struct {
+	users    UserRepository
+	posts    PostRepository
+	indexer  Indexer
+	renderer srvPost.Renderer
+}
+
+func New(users UserRepository, posts PostRepository, indexer Indexer, renderer srvPost.Renderer) *Service {
+	return &Service{users: users, posts: posts, indexer: indexer, renderer: renderer}
+}
+
+// Import stores the posts of the export. Posts that can not be imported, because their author is unknown or their
+// slug is taken by another post of the author, are reported as failed and the import goes on. Only failures of
+// the database end it.
+func (s *Service) Import(ctx context.Context, export importer.Export, opts Options) (Report, error) {
+	report := Report{DryRun: opts.DryRun}
+	for _, skipped := range export.Skipped {
+		report.Posts = append(report.Posts, PostResult{ID: skipped.ID, Title: skipped.Title, Action: ActionSkipped, Reason: skipped.Reason})
+	}
+
+	authors, err := s.mapAuthors(export, opts, &report)
+	if err != nil {
+		return report, err
+	}
+	for _, original := range export.Posts {
+		if err = ctx.Err(); err != nil {
+			return report, err
+		}
+		key := original.Author
+		if key == "" {
+			key = opts.DefaultAuthor
+		}
+		result := PostResult{ID: original.ID, Title: original.Title, Slug: original.Slug}
+		author, ok := authors[key]
+		switch {
+		case key == "":
+			result.Action, result.Reason = ActionFailed, "the post has no author, give a default author"
+		case !ok:
+			result.Action, result.Reason = ActionFailed, "author "+key+" is not mapped to a user"
+		default:
+			result.Author = author.Username
+			if err = s.importPost(export.Source, original, author, opts.DryRun, &result); err != nil {
+				return report, fmt.Errorf("post %s: %w", original.ID, err)
+			}
+		}
+		report.Posts = append(report.Posts, result)
+	}
+	return report, nil
+}
+
+// mapAuthors finds the users of the authors of the posts, keyed by the author keys of the export and by the
+// username of the default author. In a dry run the users that would be created are not stored.
+func (s *Service) mapAuthors(export importer.Export, opts Options, report *Report) (map[string]repoModels.BasicUser, error) {
+	known := map[string]importer.Author{}
+	for _, author := range export.Authors {
+		known[author.Key] = author
+	}
+	var keys []string
+	seen := map[string]bool{}
+	for _, post := range export.Posts {
+		if post.Author != "" && !seen[post.Author] {
+			seen[post.Author] = true
+			keys = append(keys, post.Author)
+		}
+	}
+
+	users := map[string]repoModels.BasicUser{}
+	if opts.DefaultAuthor != "" {
+		user, err := s.users.GetUserByUsername(opts.DefaultAuthor)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: default author %s does not exist", utils.ErrBadRequest, opts.DefaultAuthor)
+		} else if err != nil {
+			return nil, err
+		}
+		users[opts.DefaultAuthor] = repoModels.BasicUser{ID: user.ID, Username: user.Username}
+	}
+
+	for _, key := range keys {
+		author, ok := known[key]
+		if !ok {
+			author = importer.Author{Key: key}
+		}
+		result, user, err := s.mapAuthor(author, opts)
+		if err != nil {
+			return nil, err
+		}
+		if result.Action != ActionFailed {
+			users[key] = user
+		}
+		report.Authors = append(report.Authors, result)
+	}
+	return users, nil
+}
+
+func (s *Service) mapAuthor(author importer.Author, opts Options) (AuthorResult, repoModels.BasicUser, error) {
+	result := AuthorResult{Key: author.Key, Action: ActionMatched}
+	if username, ok := opts.Authors[author.Key]; ok {
+		user, err := s.users.GetUserByUsername(username)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			result.Action, result.Reason = ActionFailed, "user "+username+" does not exist"
+			return result, repoModels.BasicUser{}, nil
+		}
+		result.Username = user.Username
+		return result, repoModels.BasicUser{ID: user.ID, Username: user.Username}, err
+	}
+
+	if author.Email != "" {
+		user, err := s.users.GetUserByEmail(strings.ToLower(strings.TrimSpace(author.Email)))
+		if err == nil {
+			result.Username, result.Reason = user.Username, "same email"
+			return result, repoModels.BasicUser{ID: user.ID, Username: user.Username}, nil
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			return result, repoModels.BasicUser{}, err
+		}
+	}
+	user, err := s.users.GetUserByUsername(author.Key)
+	if err == nil {
+		result.Username, result.Reason = user.Username, "same username"
+		return result, repoModels.BasicUser{ID: user.ID, Username: user.Username}, nil
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return result, repoModels.BasicUser{}, err
+	}
+
+	if !opts.CreateAuthors {
+		result.Action, result.Reason = ActionFailed, "no user has the username or email, map the author or create it"
+		return result, repoModels.BasicUser{}, nil
+	}
+	// Created users have no password, like those of single sign-on, reset-password gives them one.
+	user = repoModels.User{
+		ID:        primitive.NewObjectID(),
+		Username:  author.Key,
+		Email:     strings.ToLower(strings.TrimSpace(author.Email)),
+		Role:      repoModels.RoleUser,
+		Status:    repoModels.UserStatusActive,
+		CreatedAt: time.Now(),
+	}
+	result.Action, result.Username = ActionCreated, user.Username
+	if !opts.DryRun {
+		if err = s.users.CreateUser(user); err != nil {
+			return result, repoModels.BasicUser{}, err
+		}
+	}
+	return result, repoModels.BasicUser{ID: user.ID, Username: user.Username}, nil
+}
+
+// importPost creates the post or updates the one imported from the same original before. Posts whose original
+// is unchanged since, or that were deleted here, are left alone. The hash catches edits that left the date of
+// the original as it was, such as Markdown files without an updated date.
+func (s *Service) importPost(source string, original importer.Post, author repoModels.BasicUser, dryRun bool, result *PostResult) error {
+	// Dates are stored in milliseconds, the original has to compare equal after a round trip.
+	original.UpdatedAt = original.UpdatedAt.Truncate(time.Millisecond)
+	hash := original.Hash()
+	post, err := s.posts.GetPostByImport(source, original.ID)
+	exists := err == nil
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		post = repoModels.Post{ID: primitive.NewObjectID(), Author: author}
+	case err != nil:
+		return err
+	case post.DeletedAt != nil:
+		result.Action, result.Reason, result.Slug, result.Author = ActionSkipped, "deleted here", post.Slug, post.Author.Username
+		return nil
+	case post.Import.UpdatedAt.Equal(original.UpdatedAt) && post.Import.Hash == hash:
+		result.Action, result.Slug, result.Author = ActionUnchanged, post.Slug, post.Author.Username
+		return nil
+	}
+
+	previousSlug := post.Slug
+	if reason := s.fill(&post, original); reason != "" {
+		result.Action, result.Reason = ActionFailed, reason
+		return nil
+	}
+	post.Import = &repoModels.PostImport{Source: source, ID: original.ID, UpdatedAt: original.UpdatedAt, Hash: hash}
+	result.Slug, result.Author = post.Slug, post.Author.Username
+	if original.Slug != "" && original.Slug != post.Slug {
+		result.Reason = "slug " + original.Slug + " is not valid here"
+	}
+
+	taken, err := s.posts.SlugTaken(post.Author.ID, post.Slug, post.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		result.Action, result.Reason = ActionFailed, "slug is taken by another post of "+post.Author.Username
+		return nil
+	}
+	if exists && previousSlug != post.Slug {
+		post.SlugHistory = append(post.SlugHistory, previousSlug)
+	}
+
+	result.Action = ActionCreated
+	if exists {
+		result.Action = ActionUpdated
+	}
+	if dryRun {
+		return nil
+	}
+	if exists {
+		err = s.posts.UpdatePost(post)
+	} else {
+		err = s.posts.CreatePost(post)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		result.Action, result.Reason = ActionFailed, "slug is taken by another post of "+post.Author.Username
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if s.indexer != nil {
+		if err = s.indexer.IndexPost(post); err != nil {
+			log.Printf("indexing post %s: %v", post.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// fill copies the original into the post and renders it, keeping its slug and dates. What the post service would
+// refuse is fitted in where that loses little: long excerpts are generated instead, long meta descriptions cut,
+// canonical URLs that are not absolute and extra tags dropped. The reason is returned for posts that can not be
+// imported.
+func (s *Service) fill(post *repoModels.Post, original importer.Post) string {
+	post.Title = strings.TrimSpace(original.Title)
+	if post.Title == "" {
+		return "the post has no title"
+	}
+	post.Slug = utils.Slugify(original.Slug)
+	if post.Slug == "" {
+		post.Slug = utils.Slugify(post.Title)
+	}
+	if post.Slug == "" {
+		return "no slug can be made of the title"
+	}
+	post.ContentFormat = original.ContentFormat
+	if !render.IsValidFormat(post.ContentFormat) {
+		return "unknown content format " + original.ContentFormat
+	}
+	post.Content = original.Content
+	post.Excerpt = strings.TrimSpace(original.Excerpt)
+	if len([]rune(post.Excerpt)) > srvPost.MaxExcerptLength {
+		post.Excerpt = ""
+	}
+	post.ExcerptManual = post.Excerpt != ""
+	if err := srvPost.RenderContent(s.renderer, post); err != nil {
+		return "rendering: " + err.Error()
+	}
+
+	post.Tags = utils.NormalizeTags(original.Tags)
+	if len(post.Tags) > srvPost.MaxTags {
+		post.Tags = post.Tags[:srvPost.MaxTags]
+	}
+	post.SEO.Description = strings.TrimSpace(original.Description)
+	if description := []rune(post.SEO.Description); len(description) > srvPost.MaxMetaDescriptionLength {
+		post.SEO.Description = strings.TrimSpace(string(description[:srvPost.MaxMetaDescriptionLength]))
+	}
+	post.SEO.CanonicalURL = ""
+	if u, err := url.Parse(strings.TrimSpace(original.CanonicalURL)); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		post.SEO.CanonicalURL = u.String()
+	}
+
+	// Exports without dates, which only happens with drafts, date the post to the import.
+	post.CreatedAt = original.CreatedAt
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
+	post.UpdatedAt = original.UpdatedAt
+	if post.UpdatedAt.IsZero() {
+		post.UpdatedAt = post.CreatedAt
+	}
+	// drafts keep a zero publication date, which leaves it out of the stored post
+	post.PublishedAt = time.Time{}
+	if !original.Draft {
+		post.PublishedAt = original.PublishedAt
+		if post.PublishedAt.IsZero() {
+			post.PublishedAt = post.CreatedAt
+		}
+	}
+	return ""
+}
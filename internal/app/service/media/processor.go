@@ -0,0 +1,287 @@
+package media
+
+import (
+	"blog-platform/config"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/imaging"
+	"blog-platform/internal/storage"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// queueSize bounds the images waiting for a worker, images that do not fit wait for the next sweep.
+	queueSize = 256
+	// sweepInterval is how often images left pending are queued again, and how long a worker may take on an
+	// image before it is considered gone when multiplied by staleFactor.
+	sweepInterval = time.Minute
+	staleFactor   = 10
+	// originalQuality is the JPEG quality originals are encoded with when they have to be turned upright.
+	originalQuality = 92
+	// Level of detail of the blurhash placeholders.
+	blurhashX, blurhashY = 4, 3
+)
+
+// processable lists the content types of images that are processed. GIFs are only measured, they would lose
+// their animation.
+var processable = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/gif":  true,
+}
+
+// Processor processes uploaded images on a pool of background workers: it strips their metadata, turns them
+// upright, resizes them into the configured variants and computes their blurhash. The state of the work is
+// kept on the media, so images queued when the server stops are picked up again when it starts.
+type Processor struct {
+	repo  Repository
+	store storage.Storage
+	cfg   config.ImageConfig
+	jobs  chan primitive.ObjectID
+}
+
+func NewProcessor(repo Repository, store storage.Storage, cfg config.ImageConfig) *Processor {
+	return &Processor{repo: repo, store: store, cfg: cfg, jobs: make(chan primitive.ObjectID, queueSize)}
+}
+
+// Start runs the workers and the sweep queueing the images left pending until ctx is done.
+func (p *Processor) Start(ctx context.Context) {
+	workers := p.cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go p.work(ctx)
+	}
+	go p.sweep(ctx)
+}
+
+// Enqueue queues the image for processing, when the queue is full the next sweep queues it.
+func (p *Processor) Enqueue(id primitive.ObjectID) {
+	select {
+	case p.jobs <- id:
+	default:
+		log.Printf("image queue is full, media %s waits for the next sweep", id.Hex())
+	}
+}
+
+func (p *Processor) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-p.jobs:
+			p.process(ctx, id)
+		}
+	}
+}
+
+// sweep queues pending images that are not in the queue, because it was full or the server restarted, and
+// gives images whose worker is gone another try.
+func (p *Processor) sweep(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := p.repo.RequeueStale(time.Now().Add(-staleFactor * sweepInterval)); err != nil {
+			log.Printf("requeueing stale images: %v", err)
+		}
+		// Images uploaded since the last sweep are still in the queue.
+		before := primitive.NewObjectIDFromTimestamp(time.Now().Add(-sweepInterval))
+		pending, err := p.repo.GetMedia(repoModels.ListQuery{
+			Filter: bson.M{"status": repoModels.MediaStatusPending, "_id": bson.M{"$lt": before}},
+			Sort:   bson.D{{Key: "_id", Value: 1}},
+			Limit:  queueSize - len(p.jobs),
+		})
+		if err != nil {
+			log.Printf("finding pending images: %v", err)
+		}
+		for _, m := range pending {
+			p.Enqueue(m.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// process claims the image, processes it and records the outcome. Variants stored for an image that was
+// deleted meanwhile are removed again.
+func (p *Processor) process(ctx context.Context, id primitive.ObjectID) {
+	claimed, err := p.repo.ClaimMedia(id)
+	if err != nil {
+		log.Printf("claiming media %s: %v", id.Hex(), err)
+		return
+	}
+	if !claimed {
+		return
+	}
+	media, err := p.repo.GetMediaByID(id)
+	if err != nil {
+		log.Printf("loading media %s: %v", id.Hex(), err)
+		return
+	}
+
+	err = p.processImage(ctx, &media)
+	if err != nil {
+		log.Printf("processing media %s: %v", id.Hex(), err)
+		err = p.repo.UpdateMediaFields(id, bson.M{"status": repoModels.MediaStatusFailed, "status_error": err.Error()})
+	} else {
+		err = p.repo.UpdateMediaFields(id, bson.M{
+			"status":       repoModels.MediaStatusReady,
+			"status_error": "",
+			"size":         media.Size,
+			"width":        media.Width,
+			"height":       media.Height,
+			"blurhash":     media.Blurhash,
+			"variants":     media.Variants,
+			"processed_at": time.Now(),
+		})
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			removeBlobs(p.store, variantKeys(media.Variants))
+			return
+		}
+	}
+	if err != nil {
+		log.Printf("recording the processing of media %s: %v", id.Hex(), err)
+	}
+}
+
+// processImage replaces the original with a copy without metadata, upright, and stores the variants.
+func (p *Processor) processImage(ctx context.Context, media *repoModels.Media) (err error) {
+	obj, err := p.store.Get(ctx, media.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	img, err := imaging.Decode(data, p.cfg.MaxPixels)
+	if errors.Is(err, imaging.ErrTooLarge) {
+		return fmt.Errorf("the image has more than %d pixels", p.cfg.MaxPixels)
+	} else if err != nil {
+		return fmt.Errorf("the image can not be decoded: %v", err)
+	}
+
+	if media.ContentType != "image/gif" {
+		var original []byte
+		if orientation := imaging.Orientation(data); orientation > 1 {
+			img = imaging.Orient(img, orientation)
+			var buf bytes.Buffer
+			if err = imaging.Encode(&buf, img, imaging.FormatJPEG, originalQuality); err != nil {
+				return err
+			}
+			original = buf.Bytes()
+		} else if original, err = imaging.StripMetadata(media.ContentType, data); err != nil {
+			return err
+		}
+		if !bytes.Equal(original, data) {
+			if err = p.store.Put(ctx, media.Key, bytes.NewReader(original), int64(len(original)), media.ContentType); err != nil {
+				return err
+			}
+			media.Size = int64(len(original))
+		}
+
+		var stored []string
+		defer func() {
+			if err != nil {
+				removeBlobs(p.store, stored)
+			}
+		}()
+		media.Variants, err = p.storeVariants(ctx, media.Key, img, &stored)
+		if err != nil {
+			return err
+		}
+	}
+
+	b := img.Bounds()
+	media.Width, media.Height = b.Dx(), b.Dy()
+	media.Blurhash = imaging.Blurhash(img, blurhashX, blurhashY)
+	return nil
+}
+
+// storeVariants resizes the image to each configured width smaller than itself and stores it in each format,
+// next to the original. The WebP encoder is lossless, a WebP variant is left out when the JPEG variant of the
+// same width is smaller, as it is for photos.
+func (p *Processor) storeVariants(ctx context.Context, key string, img image.Image, stored *[]string) ([]repoModels.MediaVariant, error) {
+	widths := append([]int(nil), p.cfg.Widths...)
+	sort.Ints(widths)
+	base := strings.TrimSuffix(key, path.Ext(key))
+
+	var variants []repoModels.MediaVariant
+	for _, width := range widths {
+		if width >= img.Bounds().Dx() {
+			break
+		}
+		resized := imaging.Resize(img, width)
+		encoded := map[string]*bytes.Buffer{}
+		for _, format := range p.cfg.Formats {
+			buf := &bytes.Buffer{}
+			if err := imaging.Encode(buf, resized, format, p.cfg.JPEGQuality); err != nil {
+				return nil, err
+			}
+			encoded[format] = buf
+		}
+		if webp, jpg := encoded[imaging.FormatWebP], encoded[imaging.FormatJPEG]; webp != nil && jpg != nil && webp.Len() > jpg.Len() {
+			delete(encoded, imaging.FormatWebP)
+		}
+
+		for _, format := range p.cfg.Formats {
+			buf, ok := encoded[format]
+			if !ok {
+				continue
+			}
+			variant := repoModels.MediaVariant{
+				Name:        strconv.Itoa(width) + imaging.Extension(format),
+				ContentType: imaging.ContentType(format),
+				Width:       resized.Bounds().Dx(),
+				Height:      resized.Bounds().Dy(),
+				Size:        int64(buf.Len()),
+			}
+			variant.Key = base + "/" + variant.Name
+			if err := p.store.Put(ctx, variant.Key, buf, variant.Size, variant.ContentType); err != nil {
+				return nil, err
+			}
+			*stored = append(*stored, variant.Key)
+			variants = append(variants, variant)
+		}
+	}
+	return variants, nil
+}
+
+func variantKeys(variants []repoModels.MediaVariant) []string {
+	keys := make([]string, len(variants))
+	for i, v := range variants {
+		keys[i] = v.Key
+	}
+	return keys
+}
+
+// removeBlobs deletes blobs, a failure leaves an orphaned blob behind and is only logged.
+func removeBlobs(store storage.Storage, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("deleting blob %s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package media
+
+import (
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/storage"
+	"blog-platform/internal/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxFilenameLength limits the stored name of an upload, in characters.
+const MaxFilenameLength = 255
+
+// AllowedTypes maps the content types accepted for uploads to the extension of their storage key. The type
+// is sniffed from the first bytes of the file, the name and the type given by the client are not trusted.
+var AllowedTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var (
+	ErrReferenced = fmt.Errorf("%w: the file is used by a post, remove it from the post first", utils.ErrBadRequest)
+	// ErrInvalidSignature is returned for download links that are not signed by this server or have expired.
+	ErrInvalidSignature = fmt.Errorf("%w: the download link is invalid or has expired", utils.ErrNotAllowed)
+)
+
+// mediaRef finds the links to media in the content of a post, the ids they contain are its references.
+var mediaRef = regexp.MustCompile(`/media/([0-9a-f]{24})\b`)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	CreateMedia(media repoModels.Media) error
+	GetMediaByID(id primitive.ObjectID) (repoModels.Media, error)
+	GetMedia(query repoModels.ListQuery) ([]repoModels.Media, error)
+	CountMedia(filter interface{}) (int64, error)
+	DeleteMedia(id primitive.ObjectID) (bool, error)
+	SetReferences(postID, ownerID primitive.ObjectID, ids []primitive.ObjectID) error
+	UpdateMediaFields(id primitive.ObjectID, set bson.M) error
+	ClaimMedia(id primitive.ObjectID) (bool, error)
+	RequeueStale(before time.Time) (int64, error)
+}
+
+// Queue hands uploaded images to the image processing.
+//
+//go:generate mockery --name=Queue --case underscore
+type Queue interface {
+	Enqueue(id primitive.ObjectID)
+}
+
+// mediaKeys orders the uploads of a user, newest first.
+var mediaKeys = []pagination.Key{{Field: "_id", Desc: true}}
+
+type Service struct {
+	repo    Repository
+	store   storage.Storage
+	queue   Queue
+	cfg     config.StorageConfig
+	baseURL string
+	secret  []byte
+	cursors *pagination.Codec
+}
+
+// New creates the service, without cfg.URLSecret download links are signed with a random secret and stop
+// working when the server restarts.
+func New(repo Repository, store storage.Storage, queue Queue, cfg config.StorageConfig, baseURL string, cursors *pagination.Codec) *Service {
+	secret := []byte(cfg.URLSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic(err)
+		}
+	}
+	return &Service{
+		repo:    repo,
+		store:   store,
+		queue:   queue,
+		cfg:     cfg,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  secret,
+		cursors: cursors,
+	}
+}
+
+// MaxUploadSize is the size limit of uploads in bytes.
+func (s *Service) MaxUploadSize() int64 {
+	return s.cfg.MaxUploadSize
+}
+
+// Upload stores size bytes of r as a file of the user. Its content type is sniffed from the content and has
+// to be one of AllowedTypes. Images are queued for processing.
+func (s *Service) Upload(ctx context.Context, r io.Reader, size int64, filename string, access models.UserAccess) (models.MediaRes, error) {
+	if size <= 0 {
+		return models.MediaRes{}, fmt.Errorf("%w: the file is empty", utils.ErrBadRequest)
+	}
+	if size > s.cfg.MaxUploadSize {
+		return models.MediaRes{}, fmt.Errorf("%w: the file is larger than %d bytes", utils.ErrBadRequest, s.cfg.MaxUploadSize)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return models.MediaRes{}, err
+	}
+	head = head[:n]
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	ext, ok := AllowedTypes[contentType]
+	if !ok {
+		return models.MediaRes{}, fmt.Errorf("%w: files of type %s can not be uploaded", utils.ErrBadRequest, contentType)
+	}
+
+	media := repoModels.Media{
+		ID:          primitive.NewObjectID(),
+		OwnerID:     access.ID,
+		Filename:    cleanFilename(filename, ext),
+		ContentType: contentType,
+		Size:        size,
+		References:  []primitive.ObjectID{},
+		CreatedAt:   time.Now(),
+	}
+	media.Key = access.ID.Hex() + "/" + media.ID.Hex() + ext
+	if processable[contentType] {
+		media.Status = repoModels.MediaStatusPending
+	}
+
+	if err = s.store.Put(ctx, media.Key, io.MultiReader(bytes.NewReader(head), r), size, contentType); err != nil {
+		return models.MediaRes{}, err
+	}
+	if err = s.repo.CreateMedia(media); err != nil {
+		removeBlobs(s.store, []string{media.Key})
+		return models.MediaRes{}, err
+	}
+	if media.Status == repoModels.MediaStatusPending {
+		s.queue.Enqueue(media.ID)
+	}
+	return s.response(media), nil
+}
+
+// GetMedia returns a file of the user, admins can see every file.
+func (s *Service) GetMedia(id primitive.ObjectID, access models.UserAccess) (models.MediaRes, error) {
+	media, err := s.getAndAuthorise(id, access)
+	if err != nil {
+		return models.MediaRes{}, err
+	}
+	return s.response(media), nil
+}
+
+// ListMedia lists a page of the files the user uploaded, newest first.
+func (s *Service) ListMedia(page models.PageReq, access models.UserAccess) ([]models.MediaRes, *repoModels.ListMetaData, error) {
+	filter := bson.M{"owner_id": access.ID}
+	scope := pagination.Scope("media", mediaKeys)
+	cursor, err := s.cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	seek, sort, err := pagination.Seek(mediaKeys, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := repoModels.ListQuery{Filter: filter, Sort: sort, Limit: page.Limit + 1}
+	if seek != nil {
+		query.Filter = bson.M{"$and": bson.A{filter, seek}}
+	}
+
+	media, err := s.repo.GetMedia(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	media, next, prev := pagination.Page(media, page.Limit, cursor, func(m repoModels.Media) []interface{} {
+		return []interface{}{m.ID}
+	})
+	res := make([]models.MediaRes, len(media))
+	for i, m := range media {
+		res[i] = s.response(m)
+	}
+
+	meta := repoModels.ListMetaData{Limit: page.Limit}
+	if meta.NextCursor, err = s.cursors.Encode(scope, next); err != nil {
+		return nil, nil, err
+	}
+	if meta.PrevCursor, err = s.cursors.Encode(scope, prev); err != nil {
+		return nil, nil, err
+	}
+	if page.Total {
+		total, err := s.repo.CountMedia(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.Total = &total
+	}
+	return res, &meta, nil
+}
+
+// DeleteMedia removes a file of the user, its bytes and its variants, files referenced by a post can not be
+// deleted.
+func (s *Service) DeleteMedia(id primitive.ObjectID, access models.UserAccess) error {
+	media, err := s.getAndAuthorise(id, access)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteMedia(id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrReferenced
+	}
+	removeBlobs(s.store, append(variantKeys(media.Variants), media.Key))
+	return nil
+}
+
+// Open returns the file, or one of its variants when a variant name is given, for download. Files referenced
+// by a post are public, the others need the expires and signature parameters of a signed link. With a backend
+// that presigns links, the file has a Redirect to download it from instead of a Body.
+func (s *Service) Open(ctx context.Context, id primitive.ObjectID, variant, expires, signature string) (models.MediaFile, error) {
+	media, err := s.repo.GetMediaByID(id)
+	if err != nil {
+		return models.MediaFile{}, err
+	}
+	if signature != "" || len(media.References) == 0 {
+		if err = s.verify(id, expires, signature); err != nil {
+			return models.MediaFile{}, err
+		}
+	}
+
+	file := models.MediaFile{Filename: media.Filename, ContentType: media.ContentType}
+	key := media.Key
+	if variant != "" {
+		found := false
+		for _, v := range media.Variants {
+			if v.Name == variant {
+				key, file.ContentType, found = v.Key, v.ContentType, true
+				file.Filename = strings.TrimSuffix(media.Filename, path.Ext(media.Filename)) + "-" + v.Name
+			}
+		}
+		if !found {
+			return models.MediaFile{}, mongo.ErrNoDocuments
+		}
+	}
+
+	if presigner, ok := s.store.(storage.Presigner); ok {
+		file.Redirect, err = presigner.PresignGet(key, s.cfg.URLTTL)
+		return file, err
+	}
+	obj, err := s.store.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.MediaFile{}, mongo.ErrNoDocuments
+	} else if err != nil {
+		return models.MediaFile{}, err
+	}
+	file.Body, file.Size = obj.Body, obj.Size
+	return file, nil
+}
+
+// TrackReferences records the media the content and the link preview image of the post link to, only files of
+// the post author count. Failures are only logged, the post itself is stored.
+func (s *Service) TrackReferences(post repoModels.Post) {
+	var ids []primitive.ObjectID
+	if post.DeletedAt == nil {
+		seen := map[string]bool{}
+		for _, m := range mediaRef.FindAllStringSubmatch(post.Content+"\n"+post.SEO.Image, -1) {
+			if seen[m[1]] {
+				continue
+			}
+			seen[m[1]] = true
+			if id, err := primitive.ObjectIDFromHex(m[1]); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if err := s.repo.SetReferences(post.ID, post.Author.ID, ids); err != nil {
+		log.Printf("tracking the media of post %s: %v", post.ID.Hex(), err)
+	}
+}
+
+// ReleaseReferences drops the references of a deleted post.
+func (s *Service) ReleaseReferences(postID primitive.ObjectID) {
+	if err := s.repo.SetReferences(postID, primitive.NilObjectID, nil); err != nil {
+		log.Printf("releasing the media of post %s: %v", postID.Hex(), err)
+	}
+}
+
+func (s *Service) getAndAuthorise(id primitive.ObjectID, access models.UserAccess) (repoModels.Media, error) {
+	media, err := s.repo.GetMediaByID(id)
+	if err != nil {
+		return media, err
+	}
+	if media.OwnerID != access.ID && !access.IsAdmin() {
+		return media, utils.ErrNotAllowed
+	}
+	return media, nil
+}
+
+func (s *Service) response(media repoModels.Media) models.MediaRes {
+	link := s.baseURL + "/api/v1/media/" + media.ID.Hex() + "/file"
+	expires := time.Now().Add(s.cfg.URLTTL).Truncate(time.Second)
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	res := models.MediaRes{
+		Media:        media,
+		URL:          link + "?expires=" + exp + "&signature=" + s.sign(media.ID, exp),
+		URLExpiresAt: expires,
+		EmbedURL:     link,
+	}
+
+	// srcset attributes per variant type, for the img and source elements of responsive images.
+	for _, v := range media.Variants {
+		if res.Srcset == nil {
+			res.Srcset = map[string]string{}
+		}
+		candidate := link + "/" + v.Name + " " + strconv.Itoa(v.Width) + "w"
+		if set := res.Srcset[v.ContentType]; set != "" {
+			candidate = set + ", " + candidate
+		}
+		res.Srcset[v.ContentType] = candidate
+	}
+	return res
+}
+
+func (s *Service) sign(id primitive.ObjectID, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id.Hex() + "." + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) verify(id primitive.ObjectID, expires, signature string) error {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(s.sign(id, expires)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// cleanFilename keeps the base name of the upload without control characters, named after the sniffed type.
+func cleanFilename(name, ext string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' {
+			return -1
+		}
+		return r
+	}, path.Base(strings.ReplaceAll(name, "\\", "/")))
+	name = strings.TrimSuffix(name, path.Ext(name))
+	if name == "" || name == "." {
+		name = "file"
+	}
+	for utf8.RuneCountInString(name)+len(ext) > MaxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name + ext
+}
@@ -0,0 +1,205 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/storage"
+	"blog-platform/internal/storage/s3test"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeRepo keeps the media in a map, only what Upload and Open use is implemented.
+type fakeRepo map[primitive.ObjectID]repoModels.Media
+
+func (f fakeRepo) CreateMedia(media repoModels.Media) error {
+	f[media.ID] = media
+	return nil
+}
+
+func (f fakeRepo) GetMediaByID(id primitive.ObjectID) (repoModels.Media, error) {
+	if media, ok := f[id]; ok {
+		return media, nil
+	}
+	return repoModels.Media{}, mongo.ErrNoDocuments
+}
+
+func (f fakeRepo) GetMedia(repoModels.ListQuery) ([]repoModels.Media, error) { return nil, nil }
+
+func (f fakeRepo) CountMedia(interface{}) (int64, error) { return 0, nil }
+
+func (f fakeRepo) DeleteMedia(primitive.ObjectID) (bool, error) { return false, nil }
+
+func (f fakeRepo) SetReferences(primitive.ObjectID, primitive.ObjectID, []primitive.ObjectID) error {
+	return nil
+}
+
+func (f fakeRepo) UpdateMediaFields(primitive.ObjectID, bson.M) error { return nil }
+
+func (f fakeRepo) ClaimMedia(primitive.ObjectID) (bool, error) { return false, nil }
+
+func (f fakeRepo) RequeueStale(time.Time) (int64, error) { return 0, nil }
+
+type fakeQueue struct{}
+
+func (fakeQueue) Enqueue(primitive.ObjectID) {}
+
+var pdf = []byte("%PDF-1.4\n%test document\n")
+
+func newTestService(t *testing.T, store storage.Storage, secret string) (*Service, fakeRepo) {
+	t.Helper()
+	repo := fakeRepo{}
+	cfg := config.StorageConfig{MaxUploadSize: 1 << 20, URLSecret: secret, URLTTL: time.Hour}
+	return New(repo, store, fakeQueue{}, cfg, "https://blog.example.com", nil), repo
+}
+
+// upload stores the test PDF and returns its media and the expires and signature parameters of its link.
+func upload(t *testing.T, s *Service) (res models.MediaRes, expires, signature string) {
+	t.Helper()
+	res, err := s.Upload(context.Background(), bytes.NewReader(pdf), int64(len(pdf)), "doc.pdf", models.UserAccess{ID: primitive.NewObjectID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := url.Parse(res.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, link.Query().Get("expires"), link.Query().Get("signature")
+}
+
+// tamper changes the last digit of a hex signature.
+func tamper(signature string) string {
+	last := "0"
+	if signature[len(signature)-1] == '0' {
+		last = "1"
+	}
+	return signature[:len(signature)-1] + last
+}
+
+func readAll(t *testing.T, file models.MediaFile) string {
+	t.Helper()
+	defer file.Body.Close()
+	data, err := io.ReadAll(file.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestOpenChecksSignedLinks(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, repo := newTestService(t, store, "secret")
+	res, expires, signature := upload(t, s)
+	if expires == "" || signature == "" {
+		t.Fatalf("the link %s is not signed", res.URL)
+	}
+
+	file, err := s.Open(context.Background(), res.ID, "", expires, signature)
+	if err != nil {
+		t.Fatalf("a signed link was rejected: %v", err)
+	}
+	if got := readAll(t, file); got != string(pdf) || file.ContentType != "application/pdf" {
+		t.Errorf("opened %q of type %q", got, file.ContentType)
+	}
+
+	other, otherExpires, otherSignature := upload(t, s)
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	later := strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10)
+	for name, link := range map[string][2]string{
+		"no signature":                  {"", ""},
+		"a changed signature":           {expires, tamper(signature)},
+		"a later expiry":                {later, signature},
+		"the signature of another file": {otherExpires, otherSignature},
+		"an expired signature":          {past, s.sign(res.ID, past)},
+		"an invalid expiry":             {"soon", s.sign(res.ID, "soon")},
+	} {
+		if _, err = s.Open(context.Background(), res.ID, "", link[0], link[1]); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("a link with %s was not rejected: %v", name, err)
+		}
+	}
+	if _, err = s.Open(context.Background(), other.ID, "", otherExpires, otherSignature); err != nil {
+		t.Errorf("the link of the other file was rejected: %v", err)
+	}
+
+	// without a URL secret, links stop working when the server restarts
+	restarted := New(repo, store, fakeQueue{}, config.StorageConfig{URLTTL: time.Hour}, "https://blog.example.com", nil)
+	if _, err = restarted.Open(context.Background(), res.ID, "", expires, signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("a link signed with another secret was accepted: %v", err)
+	}
+}
+
+func TestOpenReferencedMedia(t *testing.T) {
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, repo := newTestService(t, store, "secret")
+	res, _, signature := upload(t, s)
+	media := repo[res.ID]
+	media.References = []primitive.ObjectID{primitive.NewObjectID()}
+	repo[res.ID] = media
+
+	// files used by posts are public, the embed link has no signature
+	file, err := s.Open(context.Background(), res.ID, "", "", "")
+	if err != nil {
+		t.Fatalf("a referenced file needs a signature: %v", err)
+	}
+	readAll(t, file)
+
+	// a signature that is given is still checked
+	if _, err = s.Open(context.Background(), res.ID, "", "0", signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("an invalid signature was accepted for a referenced file: %v", err)
+	}
+}
+
+func TestOpenRedirectsToPresignedLink(t *testing.T) {
+	creds := storage.Credentials{AccessKey: "test", SecretKey: "test-secret", Region: "us-east-1"}
+	server := httptest.NewServer(s3test.New("", "media", creds).Handler())
+	defer server.Close()
+	store, err := storage.NewS3(config.S3Config{Endpoint: server.URL, Bucket: "media", AccessKey: creds.AccessKey,
+		SecretKey: creds.SecretKey, Region: creds.Region, PathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := newTestService(t, store, "secret")
+	res, expires, signature := upload(t, s)
+	if _, err = s.Open(context.Background(), res.ID, "", expires, tamper(signature)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("an invalid signature was accepted: %v", err)
+	}
+	file, err := s.Open(context.Background(), res.ID, "", expires, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Body != nil || file.Redirect == "" {
+		t.Fatalf("expected a redirect to the bucket, got %+v", file)
+	}
+	download, err := server.Client().Get(file.Redirect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer download.Body.Close()
+	data, err := io.ReadAll(download.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pdf) {
+		t.Errorf("the presigned link returned %q", data)
+	}
+}
@@ -0,0 +1,238 @@
+package oidc
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/oidc"
+	"blog-platform/internal/utils"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+const LoginTTL = 10 * time.Minute
+
+var ErrLoginFailed = fmt.Errorf("%w: single sign-on failed", utils.ErrBadRequest)
+
+// roleRank orders roles so a user in several mapped groups gets the most privileged one.
+var roleRank = map[string]int{repoModels.RoleUser: 1, repoModels.RoleAdmin: 2}
+
+//go:generate mockery --name=Client --case underscore
+type Client interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier, loginHint string) (string, error)
+	Exchange(ctx context.Context, code, verifier, nonce string) (oidc.Claims, error)
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	CreateUser(user repoModels.User) error
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	GetUserByIdentity(issuer, subject string) (repoModels.User, error)
+	GetUserByEmail(email string) (repoModels.User, error)
+	GetUserByUsername(username string) (repoModels.User, error)
+	AddIdentity(id primitive.ObjectID, identity repoModels.Identity) error
+	UpdateUserFields(id primitive.ObjectID, set, unset bson.M) error
+}
+
+//go:generate mockery --name=LoginRepository --case underscore
+type LoginRepository interface {
+	CreateLogin(login repoModels.OIDCLogin) error
+	ConsumeLogin(id string) (repoModels.OIDCLogin, error)
+}
+
+//go:generate mockery --name=SessionCreator --case underscore
+type SessionCreator interface {
+	CreateSession(user repoModels.User, method string, info models.ClientInfo) (models.SessionRes, string, error)
+}
+
+type Service struct {
+	client      Client
+	users       UserRepository
+	logins      LoginRepository
+	sessions    SessionCreator
+	roleMapping map[string]string
+}
+
+func New(client Client, users UserRepository, logins LoginRepository, sessions SessionCreator, roleMapping map[string]string) *Service {
+	return &Service{client: client, users: users, logins: logins, sessions: sessions, roleMapping: roleMapping}
+}
+
+// StartLogin stores the state, nonce and PKCE verifier of a new login and returns the provider URL to redirect to.
+// The browser token of the returned login belongs in a cookie of the browser, only that browser can finish the
+// login, so nobody can sign a victim in to their own account by passing on the callback URL.
+func (s *Service) StartLogin(ctx context.Context, loginHint string) (models.OIDCLoginStart, error) {
+	state, err := utils.NewToken(32)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	nonce, err := utils.NewToken(32)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	verifier, err := utils.NewToken(48)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	browserToken, err := utils.NewToken(32)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+
+	now := time.Now()
+	login := repoModels.OIDCLogin{
+		ID:           utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		BrowserHash:  utils.HashToken(browserToken),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(LoginTTL),
+	}
+	if err = s.logins.CreateLogin(login); err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+
+	redirectURL, err := s.client.AuthCodeURL(ctx, state, nonce, verifier, loginHint)
+	if err != nil {
+		return models.OIDCLoginStart{}, err
+	}
+	return models.OIDCLoginStart{RedirectURL: redirectURL, BrowserToken: browserToken, ExpiresAt: login.ExpiresAt}, nil
+}
+
+// FinishLogin redeems the code, provisions or links the user and starts a session for them. browserToken is the
+// one StartLogin returned, read back from the cookie of the browser. The returned token belongs in the session
+// cookie.
+func (s *Service) FinishLogin(ctx context.Context, code, state, browserToken string, info models.ClientInfo) (models.SessionRes, string, error) {
+	login, err := s.logins.ConsumeLogin(utils.HashToken(state))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return models.SessionRes{}, "", fmt.Errorf("%w: unknown or expired state", ErrLoginFailed)
+	}
+	if err != nil {
+		return models.SessionRes{}, "", err
+	}
+	if browserToken == "" || subtle.ConstantTimeCompare([]byte(utils.HashToken(browserToken)), []byte(login.BrowserHash)) != 1 {
+		return models.SessionRes{}, "", fmt.Errorf("%w: the login was started in another browser", ErrLoginFailed)
+	}
+
+	claims, err := s.client.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		return models.SessionRes{}, "", fmt.Errorf("%w: %v", ErrLoginFailed, err)
+	}
+
+	user, err := s.resolveUser(claims)
+	if err != nil {
+		return models.SessionRes{}, "", err
+	}
+	if !user.CanAuthenticate(time.Now()) {
+		return models.SessionRes{}, "", fmt.Errorf("%w: account is %s", utils.ErrNotAllowed, user.Status)
+	}
+
+	return s.sessions.CreateSession(user, repoModels.SessionMethodOIDC, info)
+}
+
+// resolveUser finds the user linked to the provider account, links an existing user with the same verified
+// email address, or creates a new user. When a role mapping is configured the provider groups decide the role.
+func (s *Service) resolveUser(claims oidc.Claims) (repoModels.User, error) {
+	role, mapped := s.mapRole(claims.Groups)
+
+	user, err := s.users.GetUserByIdentity(claims.Issuer, claims.Subject)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		user, err = s.linkOrCreate(claims, role)
+	}
+	if err != nil {
+		return user, err
+	}
+
+	if mapped && user.Role != role {
+		if err = s.users.UpdateUserFields(user.ID, bson.M{"role": role}, nil); err != nil {
+			return user, err
+		}
+		user.Role = role
+	}
+	return user, nil
+}
+
+func (s *Service) linkOrCreate(claims oidc.Claims, role string) (repoModels.User, error) {
+	now := time.Now()
+	identity := repoModels.Identity{Issuer: claims.Issuer, Subject: claims.Subject, LinkedAt: now}
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+
+	// only trust addresses the provider verified, otherwise anyone could take over an account by its address
+	if email != "" && claims.EmailVerified {
+		user, err := s.users.GetUserByEmail(email)
+		if err == nil {
+			if err = s.users.AddIdentity(user.ID, identity); err != nil {
+				return user, err
+			}
+			if user.EmailVerifiedAt == nil {
+				err = s.users.UpdateUserFields(user.ID, bson.M{"email_verified_at": now}, nil)
+			}
+			return user, err
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return user, err
+		}
+	}
+
+	username, err := s.availableUsername(claims)
+	if err != nil {
+		return repoModels.User{}, err
+	}
+	user := repoModels.User{
+		ID:         primitive.NewObjectID(),
+		Username:   username,
+		Email:      email,
+		Role:       role,
+		Status:     repoModels.UserStatusActive,
+		Identities: []repoModels.Identity{identity},
+		CreatedAt:  now,
+	}
+	if email != "" && claims.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+	return user, s.users.CreateUser(user)
+}
+
+// availableUsername derives a username from the claims, adding a number when it is taken.
+func (s *Service) availableUsername(claims oidc.Claims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 2; i < 100; i++ {
+		_, err := s.users.GetUserByUsername(candidate)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", fmt.Errorf("no username available for %q", base)
+}
+
+// mapRole returns the most privileged role mapped from the groups, ok is false when no mapping is configured.
+func (s *Service) mapRole(groups []string) (string, bool) {
+	if len(s.roleMapping) == 0 {
+		return repoModels.RoleUser, false
+	}
+
+	role := repoModels.RoleUser
+	for _, group := range groups {
+		if mapped, ok := s.roleMapping[group]; ok && roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	return role, true
+}
@@ -0,0 +1,181 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/oidc"
+	"blog-platform/internal/oidc/oidctest"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type fakeUsers struct {
+	users map[primitive.ObjectID]repoModels.User
+}
+
+func (f *fakeUsers) CreateUser(user repoModels.User) error {
+	f.users[user.ID] = user
+	return nil
+}
+
+func (f *fakeUsers) GetUserByID(id primitive.ObjectID) (repoModels.User, error) {
+	if u, ok := f.users[id]; ok {
+		return u, nil
+	}
+	return repoModels.User{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeUsers) GetUserByIdentity(issuer, subject string) (repoModels.User, error) {
+	for _, u := range f.users {
+		for _, identity := range u.Identities {
+			if identity.Issuer == issuer && identity.Subject == subject {
+				return u, nil
+			}
+		}
+	}
+	return repoModels.User{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeUsers) GetUserByEmail(email string) (repoModels.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return repoModels.User{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeUsers) GetUserByUsername(username string) (repoModels.User, error) {
+	for _, u := range f.users {
+		if u.Username == username {
+			return u, nil
+		}
+	}
+	return repoModels.User{}, mongo.ErrNoDocuments
+}
+
+func (f *fakeUsers) AddIdentity(id primitive.ObjectID, identity repoModels.Identity) error {
+	u := f.users[id]
+	u.Identities = append(u.Identities, identity)
+	f.users[id] = u
+	return nil
+}
+
+func (f *fakeUsers) UpdateUserFields(primitive.ObjectID, bson.M, bson.M) error {
+	return nil
+}
+
+type fakeLogins map[string]repoModels.OIDCLogin
+
+func (f fakeLogins) CreateLogin(login repoModels.OIDCLogin) error {
+	f[login.ID] = login
+	return nil
+}
+
+func (f fakeLogins) ConsumeLogin(id string) (repoModels.OIDCLogin, error) {
+	login, ok := f[id]
+	if !ok {
+		return login, mongo.ErrNoDocuments
+	}
+	delete(f, id)
+	return login, nil
+}
+
+type fakeSessions struct{}
+
+func (fakeSessions) CreateSession(user repoModels.User, _ string, _ models.ClientInfo) (models.SessionRes, string, error) {
+	return models.SessionRes{User: user}, "session-token", nil
+}
+
+// newTestService runs the oidctest provider with alice as its user and a service signing in through it.
+func newTestService(t *testing.T) (*Service, *httptest.Server) {
+	t.Helper()
+	var handler http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := oidctest.New(server.URL, "blog", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.AddUser(oidctest.User{Subject: "sub-alice", PreferredUsername: "alice", Email: "alice@example.com", EmailVerified: true})
+	handler = provider.Handler()
+
+	client := oidc.NewClient(oidc.Config{
+		IssuerURL:    server.URL,
+		ClientID:     "blog",
+		ClientSecret: "secret",
+		RedirectURL:  "https://blog.example.com/callback",
+	}, "", server.Client())
+	users := &fakeUsers{users: map[primitive.ObjectID]repoModels.User{}}
+	return New(client, users, fakeLogins{}, fakeSessions{}, nil), server
+}
+
+// signIn starts a login and lets the provider sign alice in, it returns the login with the code and state.
+func signIn(t *testing.T, s *Service, server *httptest.Server) (login models.OIDCLoginStart, code, state string) {
+	t.Helper()
+	login, err := s.StartLogin(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if login.BrowserToken == "" || login.ExpiresAt.IsZero() {
+		t.Fatalf("login has no browser token: %+v", login)
+	}
+	browser := server.Client()
+	browser.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	res, err := browser.Get(login.RedirectURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return login, location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestFinishLoginInStartingBrowser(t *testing.T) {
+	s, server := newTestService(t)
+	login, code, state := signIn(t, s, server)
+
+	res, token, err := s.FinishLogin(context.Background(), code, state, login.BrowserToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" || res.User.Username != "alice" || res.User.Email != "alice@example.com" {
+		t.Errorf("unexpected session %+v with token %q", res, token)
+	}
+
+	if _, _, err = s.FinishLogin(context.Background(), code, state, login.BrowserToken, models.ClientInfo{}); !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("a login was finished twice: %v", err)
+	}
+}
+
+func TestFinishLoginRejectsOtherBrowser(t *testing.T) {
+	s, server := newTestService(t)
+
+	// the attacker signs in and passes the callback URL on to a victim whose browser started a login of its own
+	_, code, state := signIn(t, s, server)
+	victim, _, _ := signIn(t, s, server)
+	if _, _, err := s.FinishLogin(context.Background(), code, state, victim.BrowserToken, models.ClientInfo{}); !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("the login was finished in another browser: %v", err)
+	}
+
+	// or to a browser that started no login at all
+	_, code, state = signIn(t, s, server)
+	if _, _, err := s.FinishLogin(context.Background(), code, state, "", models.ClientInfo{}); !errors.Is(err, ErrLoginFailed) {
+		t.Errorf("the login was finished without a browser token: %v", err)
+	}
+}
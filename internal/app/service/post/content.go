@@ -0,0 +1,30 @@
+package post
+
+import (
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/render"
+)
+
+// RenderContent renders the content of the post and derives its plain text, word count, reading time, table of
+// contents and, unless the author wrote one, its excerpt.
+func RenderContent(renderer Renderer, post *repoModels.Post) error {
+	contentHTML, err := renderer.Render(post.ContentFormat, post.Content)
+	if err != nil {
+		return err
+	}
+	summary := render.Summarize(contentHTML)
+
+	post.ContentHTML = contentHTML
+	post.RenderVersion = render.Version
+	post.ContentText = render.PlainText(contentHTML)
+	post.WordCount = summary.WordCount
+	post.ReadingTime = summary.ReadingTime
+	post.TOC = make([]repoModels.TOCEntry, len(summary.Headings))
+	for i, h := range summary.Headings {
+		post.TOC[i] = repoModels.TOCEntry{Level: h.Level, ID: h.ID, Text: h.Text}
+	}
+	if !post.ExcerptManual {
+		post.Excerpt = summary.Excerpt
+	}
+	return nil
+}
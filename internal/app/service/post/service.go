@@ -3,61 +3,316 @@ package post
 import (
 	"blog-platform/internal/app/controller/models"
 	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/render"
+	"blog-platform/internal/utils"
 	"context"
 	"errors"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	// MaxTags limits the number of tags on a post.
+	MaxTags = 10
+	// MaxExcerptLength limits excerpts written by the author, in characters.
+	MaxExcerptLength = 500
+	// MaxMetaDescriptionLength limits the meta description of a post, search engines cut longer ones.
+	MaxMetaDescriptionLength = 300
+)
+
+// summaryProjection leaves the body out of the post listing.
+var summaryProjection = bson.M{"content": 0, "content_html": 0, "content_text": 0, "toc": 0}
+
+var ErrSlugTaken = fmt.Errorf("%w: slug is already used by another post", utils.ErrBadRequest)
+
 //go:generate mockery --name=Repository --case underscore
 type Repository interface {
 	CreatePost(post repoModels.Post) error
-	GetPosts(ctx context.Context, filter interface{}, offset, limit int) ([]repoModels.Post, *repoModels.ListMetaData, error)
+	GetPosts(ctx context.Context, query repoModels.ListQuery) ([]repoModels.Post, error)
+	CountPosts(ctx context.Context, filter interface{}) (int64, error)
 	GetPostByID(id primitive.ObjectID) (repoModels.Post, error)
+	GetPostBySlug(username, slug string) (repoModels.Post, error)
+	GetPostByOldSlug(username, slug string) (repoModels.Post, error)
+	SlugTaken(authorID primitive.ObjectID, slug string, exclude primitive.ObjectID) (bool, error)
 	UpdatePost(post repoModels.Post) error
 	DeletePost(id primitive.ObjectID) error
 }
 
+//go:generate mockery --name=CategoryService --case underscore
+type CategoryService interface {
+	GetCategory(ref string) (repoModels.Category, error)
+	Subtree(ref string) ([]primitive.ObjectID, error)
+}
+
+// Indexer keeps the full-text search index in sync with post writes.
+//
+//go:generate mockery --name=Indexer --case underscore
+type Indexer interface {
+	IndexPost(post repoModels.Post) error
+	RemovePost(id primitive.ObjectID) error
+}
+
+// Renderer turns the content of a post into sanitized HTML.
+//
+//go:generate mockery --name=Renderer --case underscore
+type Renderer interface {
+	Render(format, source string) (string, error)
+}
+
+// MediaTracker keeps the references from posts to uploaded media in sync with post writes.
+//
+//go:generate mockery --name=MediaTracker --case underscore
+type MediaTracker interface {
+	TrackReferences(post repoModels.Post)
+	ReleaseReferences(postID primitive.ObjectID)
+}
+
 type Service struct {
-	repo Repository
+	repo       Repository
+	categories CategoryService
+	indexer    Indexer
+	renderer   Renderer
+	media      MediaTracker
+	cursors    *pagination.Codec
 }
 
-func New(repo Repository) *Service {
-	return &Service{repo}
+func New(repo Repository, categories CategoryService, indexer Indexer, renderer Renderer, media MediaTracker, cursors *pagination.Codec) *Service {
+	return &Service{repo: repo, categories: categories, indexer: indexer, renderer: renderer, media: media, cursors: cursors}
 }
 
-func (s *Service) CreatePost(post repoModels.Post) error {
-	return s.repo.CreatePost(post)
+// CreatePost stores a new post, its slug is generated from the title unless one was requested.
+func (s *Service) CreatePost(post repoModels.Post) (repoModels.Post, error) {
+	if err := s.prepare(&post); err != nil {
+		return repoModels.Post{}, err
+	}
+	requested := post.Slug
+	post.Slug = ""
+	if err := s.assignSlug(&post, requested); err != nil {
+		return repoModels.Post{}, err
+	}
+	if err := slugConflict(s.repo.CreatePost(post)); err != nil {
+		return repoModels.Post{}, err
+	}
+	s.index(post)
+	s.media.TrackReferences(post)
+	return post, nil
 }
 
-func (s *Service) GetPosts(ctx context.Context, username, date string, page, limit int) ([]repoModels.Post, *repoModels.ListMetaData, error) {
-	offset := (page - 1) * limit
+// GetPosts lists a page of the posts matching the filter, newest first unless another order is requested.
+// Drafts are left out. The page cursor only fits the order it was created for.
+func (s *Service) GetPosts(ctx context.Context, postFilter models.PostFilter) ([]repoModels.Post, *repoModels.ListMetaData, error) {
 	filter := bson.M{"deleted_at": bson.M{"$exists": false}}
 
-	if username != "" {
-		filter["author.username"] = username
+	switch len(postFilter.Authors) {
+	case 0:
+	case 1:
+		filter["author.username"] = postFilter.Authors[0]
+	default:
+		filter["author.username"] = bson.M{"$in": postFilter.Authors}
+	}
+	addTimeRange(filter, "created_at", postFilter.Created)
+	addTimeRange(filter, "updated_at", postFilter.Updated)
+	addTimeRange(filter, "published_at", postFilter.Published)
+	if postFilter.Published.IsZero() {
+		// drafts have no publication date, a range leaves them out already
+		filter["published_at"] = bson.M{"$exists": true}
+	}
+
+	tagConditions := bson.M{}
+	if all := utils.NormalizeTags(append([]string{postFilter.Tag}, postFilter.AllTags...)); len(all) > 0 {
+		tagConditions["$all"] = all
+	}
+	if anyOf := utils.NormalizeTags(postFilter.AnyTags); len(anyOf) > 0 {
+		tagConditions["$in"] = anyOf
+	}
+	if len(tagConditions) > 0 {
+		filter["tags"] = tagConditions
+	}
+
+	if postFilter.Category != "" {
+		ids, err := s.categories.Subtree(postFilter.Category)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter["category_id"] = bson.M{"$in": ids}
+	}
+
+	keys := sortKeys(postFilter.Sort)
+	scope := pagination.Scope("posts", keys)
+	cursor, err := s.cursors.Decode(scope, postFilter.Page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	seek, sort, err := pagination.Seek(keys, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := repoModels.ListQuery{Filter: filter, Sort: sort, Limit: postFilter.Page.Limit + 1}
+	if seek != nil {
+		query.Filter = bson.M{"$and": bson.A{filter, seek}}
+	}
+	if len(postFilter.Fields) > 0 {
+		// the sort keys are needed for the cursors even when they are not selected
+		projection := bson.M{}
+		for _, field := range postFilter.Fields {
+			projection[models.PostFields[field]] = 1
+		}
+		for _, key := range keys {
+			// projecting author.username next to author would be a path collision
+			if top, _, _ := strings.Cut(key.Field, "."); projection[top] == nil {
+				projection[key.Field] = 1
+			}
+		}
+		query.Projection = projection
+	} else if !postFilter.Full {
+		query.Projection = summaryProjection
+	}
+
+	posts, err := s.repo.GetPosts(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	posts, next, prev := pagination.Page(posts, postFilter.Page.Limit, cursor, func(post repoModels.Post) []interface{} {
+		return sortValues(post, keys)
+	})
+
+	meta := repoModels.ListMetaData{Limit: postFilter.Page.Limit}
+	if meta.NextCursor, err = s.cursors.Encode(scope, next); err != nil {
+		return nil, nil, err
+	}
+	if meta.PrevCursor, err = s.cursors.Encode(scope, prev); err != nil {
+		return nil, nil, err
+	}
+	if postFilter.Page.Total {
+		total, err := s.repo.CountPosts(ctx, filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.Total = &total
+	}
+	return posts, &meta, nil
+}
+
+func addTimeRange(filter bson.M, field string, r models.TimeRange) {
+	if r.IsZero() {
+		return
+	}
+	cond := bson.M{}
+	if r.From != nil {
+		cond["$gte"] = r.From
 	}
-	if date != "" {
-		startDate, _ := time.Parse("2006-01-02", date)
-		endDate := startDate.AddDate(0, 0, 1)
-		filter["created_at"] = bson.M{"$gte": startDate, "$lt": endDate}
+	if r.To != nil {
+		cond["$lt"] = r.To
 	}
+	filter[field] = cond
+}
 
-	return s.repo.GetPosts(ctx, filter, offset, limit)
+// sortKeys turns the requested order into pagination keys, _id breaks ties so every post has its own position.
+func sortKeys(fields []models.SortField) []pagination.Key {
+	if len(fields) == 0 {
+		fields = []models.SortField{{Field: "created_at", Desc: true}}
+	}
+	keys := make([]pagination.Key, 0, len(fields)+1)
+	for _, f := range fields {
+		keys = append(keys, pagination.Key{Field: models.PostSortFields[f.Field], Desc: f.Desc})
+	}
+	return append(keys, pagination.Key{Field: "_id", Desc: keys[len(keys)-1].Desc})
 }
 
-func (s *Service) GetPostByID(id primitive.ObjectID) (repoModels.Post, error) {
-	return s.repo.GetPostByID(id)
+// sortValues returns the values of the post at the document paths of the keys.
+func sortValues(post repoModels.Post, keys []pagination.Key) []interface{} {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		switch key.Field {
+		case "_id":
+			values[i] = post.ID
+		case "created_at":
+			values[i] = post.CreatedAt
+		case "updated_at":
+			values[i] = post.UpdatedAt
+		case "published_at":
+			values[i] = post.PublishedAt
+		case "title":
+			values[i] = post.Title
+		case "slug":
+			values[i] = post.Slug
+		case "author.username":
+			values[i] = post.Author.Username
+		case "popularity":
+			values[i] = post.Popularity
+		}
+	}
+	return values
 }
 
-func (s *Service) UpdatePost(post repoModels.Post, access models.UserAccess) error {
-	err := s.GetPostAndAuthorise(post.ID, access)
+// GetPostByID finds the post, drafts are only found by their author and admins.
+func (s *Service) GetPostByID(id primitive.ObjectID, access models.UserAccess) (repoModels.Post, error) {
+	post, err := s.repo.GetPostByID(id)
 	if err != nil {
-		return err
+		return post, err
+	}
+	if post.IsDraft() && post.Author.ID != access.ID && !access.IsAdmin() {
+		return repoModels.Post{}, mongo.ErrNoDocuments
 	}
+	return post, nil
+}
 
-	return s.repo.UpdatePost(post)
+// GetPostBySlug finds the post of the author by its slug. When the slug is an old one of the post, moved
+// is true and the returned post carries the current slug.
+func (s *Service) GetPostBySlug(username, slug string) (post repoModels.Post, moved bool, err error) {
+	post, err = s.repo.GetPostBySlug(username, slug)
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return post, false, err
+	}
+	post, err = s.repo.GetPostByOldSlug(username, slug)
+	return post, err == nil, err
+}
+
+// UpdatePost replaces the editable fields of the post, author and creation time are kept. The slug only
+// changes when a new one is requested, retitling a post does not break its links. Published turns drafts
+// into posts and back.
+func (s *Service) UpdatePost(id primitive.ObjectID, req models.PostReq, access models.UserAccess) (repoModels.Post, error) {
+	err := s.GetPostAndAuthorise(id, access)
+	if err != nil {
+		return repoModels.Post{}, err
+	}
+
+	post, err := s.repo.GetPostByID(id)
+	if err != nil {
+		return repoModels.Post{}, err
+	}
+	post.Title = req.Title
+	post.Content = req.Content
+	post.Excerpt = req.Excerpt
+	if req.ContentFormat != "" {
+		post.ContentFormat = req.ContentFormat
+	}
+	post.Tags = req.Tags
+	post.CategoryID = req.CategoryID
+	post.SEO = req.SEO
+	post.UpdatedAt = time.Now()
+	post.PublishedAt = models.PublishedAt(req.Published, post.PublishedAt, post.UpdatedAt)
+	if err = s.prepare(&post); err != nil {
+		return repoModels.Post{}, err
+	}
+	if err = s.assignSlug(&post, req.Slug); err != nil {
+		return repoModels.Post{}, err
+	}
+
+	if err = slugConflict(s.repo.UpdatePost(post)); err != nil {
+		return repoModels.Post{}, err
+	}
+	s.index(post)
+	s.media.TrackReferences(post)
+	return post, nil
 }
 
 func (s *Service) DeletePost(id primitive.ObjectID, access models.UserAccess) error {
@@ -66,7 +321,14 @@ func (s *Service) DeletePost(id primitive.ObjectID, access models.UserAccess) er
 		return err
 	}
 
-	return s.repo.DeletePost(id)
+	if err = s.repo.DeletePost(id); err != nil {
+		return err
+	}
+	if err = s.indexer.RemovePost(id); err != nil {
+		log.Printf("removing post %s from the search index: %v", id.Hex(), err)
+	}
+	s.media.ReleaseReferences(id)
+	return nil
 }
 
 func (s *Service) GetPostAndAuthorise(id primitive.ObjectID, access models.UserAccess) error {
@@ -75,9 +337,137 @@ func (s *Service) GetPostAndAuthorise(id primitive.ObjectID, access models.UserA
 		return err
 	}
 
-	if post.Author.ID == access.ID || *access.Role == "admin" {
+	if post.Author.ID == access.ID || access.IsAdmin() {
 		return nil
 	}
 
-	return errors.New("not allowed")
+	return utils.ErrNotAllowed
+}
+
+// index updates the search index after a write, failures are only logged because the post itself is stored
+// and the index can be rebuilt with the reindex command.
+func (s *Service) index(post repoModels.Post) {
+	if err := s.indexer.IndexPost(post); err != nil {
+		log.Printf("indexing post %s: %v", post.ID.Hex(), err)
+	}
+}
+
+// prepare normalizes the tags of the post, checks its category exists and renders its content, which is
+// markdown unless another format is given. An excerpt given with the post is kept, otherwise one is generated.
+func (s *Service) prepare(post *repoModels.Post) error {
+	if post.ContentFormat == "" {
+		post.ContentFormat = render.FormatMarkdown
+	}
+	if !render.IsValidFormat(post.ContentFormat) {
+		return fmt.Errorf("%w: content_format must be markdown, html or plain", utils.ErrBadRequest)
+	}
+	post.Excerpt = strings.TrimSpace(post.Excerpt)
+	if len([]rune(post.Excerpt)) > MaxExcerptLength {
+		return fmt.Errorf("%w: an excerpt can have at most %d characters", utils.ErrBadRequest, MaxExcerptLength)
+	}
+	post.ExcerptManual = post.Excerpt != ""
+	if err := RenderContent(s.renderer, post); err != nil {
+		return err
+	}
+
+	post.Tags = utils.NormalizeTags(post.Tags)
+	if len(post.Tags) > MaxTags {
+		return fmt.Errorf("%w: a post can have at most %d tags", utils.ErrBadRequest, MaxTags)
+	}
+	if err := prepareSEO(&post.SEO); err != nil {
+		return err
+	}
+
+	if post.CategoryID != nil {
+		_, err := s.categories.GetCategory(post.CategoryID.Hex())
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: unknown category", utils.ErrBadRequest)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareSEO trims the SEO fields and checks them. The canonical URL has to be absolute, the image may also be
+// a path on this server, such as the embed URL of an upload.
+func prepareSEO(seo *repoModels.PostSEO) error {
+	seo.Description = strings.TrimSpace(seo.Description)
+	if len([]rune(seo.Description)) > MaxMetaDescriptionLength {
+		return fmt.Errorf("%w: a meta description can have at most %d characters", utils.ErrBadRequest, MaxMetaDescriptionLength)
+	}
+	seo.CanonicalURL = strings.TrimSpace(seo.CanonicalURL)
+	if seo.CanonicalURL != "" && !isHTTPURL(seo.CanonicalURL) {
+		return fmt.Errorf("%w: canonical_url must be an absolute http or https URL", utils.ErrBadRequest)
+	}
+	seo.Image = strings.TrimSpace(seo.Image)
+	if seo.Image != "" && !isHTTPURL(seo.Image) && !(strings.HasPrefix(seo.Image, "/") && !strings.HasPrefix(seo.Image, "//")) {
+		return fmt.Errorf("%w: the seo image must be an http or https URL or a path on this server", utils.ErrBadRequest)
+	}
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// assignSlug sets the requested slug, which has to be free, or generates one from the title for posts without
+// a slug. Generated slugs get a number appended until they are free. The replaced slug is kept in the history.
+func (s *Service) assignSlug(post *repoModels.Post, requested string) error {
+	slug := utils.Slugify(requested)
+	if requested != "" && slug == "" {
+		return fmt.Errorf("%w: slug must contain letters or digits", utils.ErrBadRequest)
+	}
+	if slug == post.Slug || (slug == "" && post.Slug != "") {
+		return nil
+	}
+
+	if slug != "" {
+		taken, err := s.repo.SlugTaken(post.Author.ID, slug, post.ID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrSlugTaken
+		}
+	} else {
+		base := utils.Slugify(post.Title)
+		if base == "" {
+			base = "post"
+		}
+		slug = base
+		for i := 2; ; i++ {
+			taken, err := s.repo.SlugTaken(post.Author.ID, slug, post.ID)
+			if err != nil {
+				return err
+			}
+			if !taken {
+				break
+			}
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+	}
+
+	history := post.SlugHistory[:0]
+	for _, old := range post.SlugHistory {
+		if old != slug {
+			history = append(history, old)
+		}
+	}
+	if post.Slug != "" {
+		history = append(history, post.Slug)
+	}
+	post.SlugHistory = history
+	post.Slug = slug
+	return nil
+}
+
+// slugConflict reports a slug taken by a concurrent request as ErrSlugTaken.
+func slugConflict(err error) error {
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrSlugTaken
+	}
+	return err
 }
@@ -0,0 +1,165 @@
+package reaction
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxEmoji limits the number of emoji readers can choose from.
+	MaxEmoji = 20
+	// maxEmojiLength is generous enough for emoji made of several code points, like flags and families.
+	maxEmojiLength = 16
+)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	AddReaction(reaction repoModels.Reaction) (bool, error)
+	RemoveReaction(postID, userID primitive.ObjectID, emoji string) (bool, error)
+	GetUserEmoji(postID, userID primitive.ObjectID) ([]string, error)
+}
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetPostByID(id primitive.ObjectID) (repoModels.Post, error)
+	IncrementReaction(id primitive.ObjectID, emoji string, delta int) error
+}
+
+//go:generate mockery --name=SettingsRepository --case underscore
+type SettingsRepository interface {
+	GetReactionSettings() (repoModels.ReactionSettings, error)
+	SaveReactionSettings(settings repoModels.ReactionSettings) error
+}
+
+type Service struct {
+	repo     Repository
+	posts    PostRepository
+	settings SettingsRepository
+}
+
+func New(repo Repository, posts PostRepository, settings SettingsRepository) *Service {
+	return &Service{repo: repo, posts: posts, settings: settings}
+}
+
+// GetReactions returns the reaction counts of the post and the emoji the user reacted with.
+func (s *Service) GetReactions(postID primitive.ObjectID, access models.UserAccess) (models.ReactionsRes, error) {
+	post, err := s.getPost(postID, access)
+	if err != nil {
+		return models.ReactionsRes{}, err
+	}
+	mine, err := s.repo.GetUserEmoji(postID, access.ID)
+	if err != nil {
+		return models.ReactionsRes{}, err
+	}
+
+	reactions := post.Reactions
+	if reactions == nil {
+		reactions = map[string]int64{}
+	}
+	return models.ReactionsRes{PostID: post.ID, Reactions: reactions, Count: post.ReactionCount, Mine: mine}, nil
+}
+
+// React adds the reaction of the user with the emoji, reacting twice with the same emoji changes nothing.
+func (s *Service) React(postID primitive.ObjectID, emoji string, access models.UserAccess) (models.ReactionsRes, error) {
+	if _, err := s.getPost(postID, access); err != nil {
+		return models.ReactionsRes{}, err
+	}
+	settings, err := s.settings.GetReactionSettings()
+	if err != nil {
+		return models.ReactionsRes{}, err
+	}
+	if !settings.Allows(emoji) {
+		return models.ReactionsRes{}, fmt.Errorf("%w: can not react with %q, the reactions are %s",
+			utils.ErrBadRequest, emoji, strings.Join(settings.Emoji, " "))
+	}
+
+	added, err := s.repo.AddReaction(repoModels.Reaction{
+		ID:        primitive.NewObjectID(),
+		PostID:    postID,
+		UserID:    access.ID,
+		Emoji:     emoji,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return models.ReactionsRes{}, err
+	}
+	if added {
+		if err = s.posts.IncrementReaction(postID, emoji, 1); err != nil {
+			return models.ReactionsRes{}, err
+		}
+	}
+	return s.GetReactions(postID, access)
+}
+
+// Unreact removes the reaction of the user with the emoji, removing a reaction that does not exist changes
+// nothing. Reactions with emoji that are no longer offered can still be removed.
+func (s *Service) Unreact(postID primitive.ObjectID, emoji string, access models.UserAccess) (models.ReactionsRes, error) {
+	if _, err := s.getPost(postID, access); err != nil {
+		return models.ReactionsRes{}, err
+	}
+
+	removed, err := s.repo.RemoveReaction(postID, access.ID, emoji)
+	if err != nil {
+		return models.ReactionsRes{}, err
+	}
+	if removed {
+		if err = s.posts.IncrementReaction(postID, emoji, -1); err != nil {
+			return models.ReactionsRes{}, err
+		}
+	}
+	return s.GetReactions(postID, access)
+}
+
+func (s *Service) GetReactionSettings() (repoModels.ReactionSettings, error) {
+	return s.settings.GetReactionSettings()
+}
+
+// SetReactionSettings replaces the emoji readers can react with. Existing reactions with emoji that are left
+// out keep counting.
+func (s *Service) SetReactionSettings(req models.ReactionSettingsReq) (repoModels.ReactionSettings, error) {
+	settings := repoModels.ReactionSettings{ID: repoModels.ReactionSettingsID, Emoji: []string{}}
+	seen := map[string]bool{}
+	for _, emoji := range req.Emoji {
+		emoji = strings.TrimSpace(emoji)
+		if err := checkEmoji(emoji); err != nil {
+			return repoModels.ReactionSettings{}, err
+		}
+		if !seen[emoji] {
+			seen[emoji] = true
+			settings.Emoji = append(settings.Emoji, emoji)
+		}
+	}
+	if len(settings.Emoji) == 0 || len(settings.Emoji) > MaxEmoji {
+		return repoModels.ReactionSettings{}, fmt.Errorf("%w: offer between 1 and %d emoji", utils.ErrBadRequest, MaxEmoji)
+	}
+	return settings, s.settings.SaveReactionSettings(settings)
+}
+
+// checkEmoji rejects what can not be a key of the reaction counts on the post document.
+func checkEmoji(emoji string) error {
+	switch {
+	case emoji == "":
+		return fmt.Errorf("%w: an emoji can not be empty", utils.ErrBadRequest)
+	case utf8.RuneCountInString(emoji) > maxEmojiLength:
+		return fmt.Errorf("%w: %q is too long for an emoji", utils.ErrBadRequest, emoji)
+	case strings.ContainsAny(emoji, ".$ /"):
+		return fmt.Errorf("%w: %q can not contain dots, dollar signs, slashes or spaces", utils.ErrBadRequest, emoji)
+	}
+	return nil
+}
+
+// getPost returns the post unless it is deleted or a draft of another user.
+func (s *Service) getPost(id primitive.ObjectID, access models.UserAccess) (repoModels.Post, error) {
+	post, err := s.posts.GetPostByID(id)
+	if err == nil && (post.DeletedAt != nil || post.IsDraft() && post.Author.ID != access.ID) {
+		return repoModels.Post{}, mongo.ErrNoDocuments
+	}
+	return post, err
+}
@@ -0,0 +1,148 @@
+package search
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/search"
+	"blog-platform/internal/utils"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const maxLimit = 100
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetPostsByIDs(ctx context.Context, ids []primitive.ObjectID) ([]repoModels.Post, error)
+	EachPost(ctx context.Context, filter interface{}, fn func(repoModels.Post) error) error
+}
+
+type Service struct {
+	index search.Index
+	posts PostRepository
+}
+
+func New(index search.Index, posts PostRepository) *Service {
+	return &Service{index: index, posts: posts}
+}
+
+func (s *Service) Search(ctx context.Context, req models.SearchReq) (models.SearchRes, error) {
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.Limit < 1 {
+		req.Limit = 10
+	}
+	req.Limit = min(req.Limit, maxLimit)
+
+	q := search.Query{
+		Terms:  search.ParseQuery(req.Q),
+		Tags:   utils.NormalizeTags(req.Tags),
+		Author: req.Author,
+		Offset: (req.Page - 1) * req.Limit,
+		Limit:  req.Limit,
+	}
+	var err error
+	if q.From, err = parseDate("from", req.From, 0); err != nil {
+		return models.SearchRes{}, err
+	}
+	if q.To, err = parseDate("to", req.To, 1); err != nil {
+		return models.SearchRes{}, err
+	}
+
+	result, err := s.index.Search(ctx, q)
+	if err != nil {
+		return models.SearchRes{}, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		if id, err := primitive.ObjectIDFromHex(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	posts, err := s.posts.GetPostsByIDs(ctx, ids)
+	if err != nil {
+		return models.SearchRes{}, err
+	}
+	byID := make(map[string]repoModels.Post, len(posts))
+	for _, p := range posts {
+		byID[p.ID.Hex()] = p
+	}
+
+	res := models.SearchRes{Total: result.Total, Page: req.Page, Limit: req.Limit, Hits: []models.SearchHit{}, Facets: result.Facets}
+	for _, hit := range result.Hits {
+		// the index may briefly lag behind a delete
+		post, ok := byID[hit.ID]
+		if !ok || post.DeletedAt != nil || post.IsDraft() {
+			continue
+		}
+		res.Hits = append(res.Hits, models.SearchHit{Post: post, Score: hit.Score, Highlights: hit.Highlights})
+	}
+	return res, nil
+}
+
+// IndexPost adds or replaces the post in the index, deleted posts and drafts are removed from it.
+func (s *Service) IndexPost(post repoModels.Post) error {
+	if post.DeletedAt != nil || post.IsDraft() {
+		return s.RemovePost(post.ID)
+	}
+	return s.index.Index(context.Background(), document(post))
+}
+
+func (s *Service) RemovePost(id primitive.ObjectID) error {
+	return s.index.Delete(context.Background(), id.Hex())
+}
+
+// ReindexPosts indexes the posts matching filter again, for changes made to many posts at once.
+func (s *Service) ReindexPosts(ctx context.Context, filter interface{}) error {
+	return s.posts.EachPost(ctx, filter, func(post repoModels.Post) error {
+		if post.IsDraft() {
+			return s.index.Delete(ctx, post.ID.Hex())
+		}
+		return s.index.Index(ctx, document(post))
+	})
+}
+
+// Rebuild empties the index and indexes every post that is published and not deleted, it returns the number of
+// posts.
+func (s *Service) Rebuild(ctx context.Context) (int, error) {
+	if err := s.index.Reset(ctx); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := s.posts.EachPost(ctx, bson.M{"deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$exists": true}}, func(post repoModels.Post) error {
+		n++
+		return s.index.Index(ctx, document(post))
+	})
+	return n, err
+}
+
+// document indexes the plain text of the rendered content, markup and markdown syntax are neither searched nor
+// highlighted.
+func document(post repoModels.Post) search.Document {
+	return search.Document{
+		ID:        post.ID.Hex(),
+		Title:     post.Title,
+		Content:   post.ContentText,
+		Tags:      post.Tags,
+		Author:    post.Author.Username,
+		CreatedAt: post.CreatedAt,
+	}
+}
+
+// parseDate reads a 2006-01-02 date, days is added so an inclusive end date becomes an exclusive bound.
+func parseDate(name, value string, days int) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a date like 2006-01-02", utils.ErrBadRequest, name)
+	}
+	t = t.AddDate(0, 0, days)
+	return &t, nil
+}
@@ -0,0 +1,173 @@
+package seo
+
+import (
+	"blog-platform/config"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/site"
+	"blog-platform/internal/sitemap"
+	"context"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Kinds of pages listed in sitemaps.
+const (
+	KindPosts   = "posts"
+	KindAuthors = "authors"
+)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	GetPosts(ctx context.Context, query repoModels.ListQuery) ([]repoModels.Post, error)
+	CountPosts(ctx context.Context, filter interface{}) (int64, error)
+	GetLastUpdated(ctx context.Context, query repoModels.ListQuery) (time.Time, error)
+	GetAuthorActivity(ctx context.Context, filter interface{}) ([]repoModels.AuthorActivity, error)
+}
+
+type Service struct {
+	repo    Repository
+	cfg     config.SEOConfig
+	baseURL string
+}
+
+func New(repo Repository, cfg config.SEOConfig, baseURL string) *Service {
+	if cfg.SitemapPageSize <= 0 || cfg.SitemapPageSize > sitemap.MaxURLs {
+		cfg.SitemapPageSize = sitemap.MaxURLs
+	}
+	return &Service{repo: repo, cfg: cfg, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Robots returns the robots.txt of the site.
+func (s *Service) Robots() string {
+	var b strings.Builder
+	b.WriteString("User-agent: *\n")
+	if !s.cfg.AllowIndexing {
+		b.WriteString("Disallow: /\n")
+		return b.String()
+	}
+	if len(s.cfg.RobotsDisallow) == 0 {
+		b.WriteString("Disallow:\n")
+	}
+	for _, path := range s.cfg.RobotsDisallow {
+		b.WriteString("Disallow: " + path + "\n")
+	}
+	b.WriteString("\nSitemap: " + s.baseURL + site.SitemapIndexPath + "\n")
+	return b.String()
+}
+
+// indexablePosts matches the published posts search engines may list. Posts with a canonical URL are left out,
+// their canonical page is elsewhere.
+func indexablePosts(now time.Time) bson.M {
+	return bson.M{
+		"deleted_at":        bson.M{"$exists": false},
+		"published_at":      bson.M{"$lte": now},
+		"seo.noindex":       bson.M{"$ne": true},
+		"seo.canonical_url": bson.M{"$in": bson.A{nil, ""}},
+	}
+}
+
+// publishedPosts matches the published posts, an author has a page once one of their posts is published.
+func publishedPosts(now time.Time) bson.M {
+	return bson.M{"deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$lte": now}}
+}
+
+// postOrder lists posts oldest first, so new posts go to the last sitemap and the others stay as they are.
+var postOrder = bson.D{{Key: "published_at", Value: 1}, {Key: "_id", Value: 1}}
+
+// SitemapIndex lists the sitemaps, each with the latest change of its pages. Only the number of posts and the
+// latest change of each sitemap are read, not the posts.
+func (s *Service) SitemapIndex(ctx context.Context) ([]sitemap.URL, error) {
+	now := time.Now()
+	count, err := s.repo.CountPosts(ctx, indexablePosts(now))
+	if err != nil {
+		return nil, err
+	}
+	authors, err := s.repo.GetAuthorActivity(ctx, publishedPosts(now))
+	if err != nil {
+		return nil, err
+	}
+
+	var sitemaps []sitemap.URL
+	size := s.cfg.SitemapPageSize
+	for start := 0; int64(start) < count; start += size {
+		lastModified, err := s.repo.GetLastUpdated(ctx, repoModels.ListQuery{
+			Filter: indexablePosts(now),
+			Sort:   postOrder,
+			Skip:   start,
+			Limit:  size,
+		})
+		if err != nil {
+			return nil, err
+		}
+		sitemaps = append(sitemaps, sitemap.URL{Loc: s.baseURL + site.SitemapPath(KindPosts, start/size+1), LastModified: lastModified})
+	}
+	for start := 0; start < len(authors); start += size {
+		page := sitemap.URL{Loc: s.baseURL + site.SitemapPath(KindAuthors, start/size+1)}
+		for _, author := range authors[start:min(start+size, len(authors))] {
+			page.LastModified = latest(page.LastModified, author.LastUpdated)
+		}
+		sitemaps = append(sitemaps, page)
+	}
+	return sitemaps, nil
+}
+
+// Sitemap lists a page, counting from 1, of the pages of kind. The first page always exists, possibly empty,
+// the pages after the last are not found.
+func (s *Service) Sitemap(ctx context.Context, kind string, page int) ([]sitemap.URL, error) {
+	if page < 1 {
+		return nil, mongo.ErrNoDocuments
+	}
+	size := s.cfg.SitemapPageSize
+	urls := []sitemap.URL{}
+	switch kind {
+	case KindPosts:
+		posts, err := s.repo.GetPosts(ctx, repoModels.ListQuery{
+			Filter:     indexablePosts(time.Now()),
+			Sort:       postOrder,
+			Projection: bson.M{"author.username": 1, "slug": 1, "updated_at": 1, "published_at": 1},
+			Skip:       (page - 1) * size,
+			Limit:      size,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, post := range posts {
+			urls = append(urls, sitemap.URL{
+				Loc:          s.baseURL + site.PostPath(post.Author.Username, post.Slug),
+				LastModified: latest(post.UpdatedAt, post.PublishedAt),
+			})
+		}
+	case KindAuthors:
+		authors, err := s.repo.GetAuthorActivity(ctx, publishedPosts(time.Now()))
+		if err != nil {
+			return nil, err
+		}
+		if start := (page - 1) * size; start < len(authors) {
+			for _, author := range authors[start:min(start+size, len(authors))] {
+				urls = append(urls, sitemap.URL{
+					Loc:          s.baseURL + site.AuthorPath(author.Username),
+					LastModified: author.LastUpdated,
+				})
+			}
+		}
+	default:
+		return nil, mongo.ErrNoDocuments
+	}
+	if len(urls) == 0 && page > 1 {
+		return nil, mongo.ErrNoDocuments
+	}
+	return urls, nil
+}
+
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, candidate := range times {
+		if candidate.After(t) {
+			t = candidate
+		}
+	}
+	return t
+}
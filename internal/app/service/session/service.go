@@ -0,0 +1,183 @@
+package session
+
+import (
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+var ErrInvalidSession = fmt.Errorf("%w: session expired or revoked", utils.ErrUnauthorized)
+
+//go:generate mockery --name=Repository --case underscore
+type Repository interface {
+	CreateSession(session repoModels.Session) error
+	GetSessionByTokenHash(hash string) (repoModels.Session, error)
+	GetSessionByID(id primitive.ObjectID) (repoModels.Session, error)
+	GetActiveSessions(userID primitive.ObjectID, now time.Time) ([]repoModels.Session, error)
+	TouchSession(id primitive.ObjectID, now, idleExpiresAt time.Time) error
+	DeleteSession(id primitive.ObjectID) error
+	DeleteUserSessions(userID, keep primitive.ObjectID) (int64, error)
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	GetUserByUsername(username string) (repoModels.User, error)
+}
+
+//go:generate mockery --name=SecondFactor --case underscore
+type SecondFactor interface {
+	Verify(user repoModels.User, code string) error
+}
+
+type Service struct {
+	repo         Repository
+	users        UserRepository
+	secondFactor SecondFactor
+	cfg          config.SessionConfig
+}
+
+func New(repo Repository, users UserRepository, secondFactor SecondFactor, cfg config.SessionConfig) *Service {
+	return &Service{repo: repo, users: users, secondFactor: secondFactor, cfg: cfg}
+}
+
+// Login checks the password and, for enrolled users, the second factor before starting a session.
+// The returned token belongs in the session cookie.
+func (s *Service) Login(req models.LoginReq, info models.ClientInfo) (models.SessionRes, string, error) {
+	user, err := s.users.GetUserByUsername(req.Username)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return models.SessionRes{}, "", err
+	}
+	if err != nil || user.Password == "" || user.Password != req.Password || user.DeletedAt != nil {
+		return models.SessionRes{}, "", fmt.Errorf("%w: invalid username or password", utils.ErrUnauthorized)
+	}
+	if !user.CanAuthenticate(time.Now()) {
+		return models.SessionRes{}, "", fmt.Errorf("%w: account is %s", utils.ErrNotAllowed, user.Status)
+	}
+	if user.HasTwoFactor() {
+		if err = s.secondFactor.Verify(user, req.OTP); err != nil {
+			return models.SessionRes{}, "", fmt.Errorf("%w: %w", utils.ErrUnauthorized, err)
+		}
+	}
+
+	return s.CreateSession(user, repoModels.SessionMethodPassword, info)
+}
+
+// CreateSession starts a session for an already authenticated user.
+func (s *Service) CreateSession(user repoModels.User, method string, info models.ClientInfo) (models.SessionRes, string, error) {
+	token, err := utils.NewToken(32)
+	if err != nil {
+		return models.SessionRes{}, "", err
+	}
+	csrf, err := utils.NewToken(32)
+	if err != nil {
+		return models.SessionRes{}, "", err
+	}
+
+	now := time.Now()
+	session := repoModels.Session{
+		ID:            primitive.NewObjectID(),
+		UserID:        user.ID,
+		TokenHash:     utils.HashToken(token),
+		CSRFToken:     csrf,
+		Method:        method,
+		UserAgent:     info.UserAgent,
+		IP:            info.IP,
+		CreatedAt:     now,
+		LastSeenAt:    now,
+		IdleExpiresAt: now.Add(s.cfg.IdleTimeout),
+		ExpiresAt:     now.Add(s.cfg.AbsoluteTimeout),
+	}
+	if err = s.repo.CreateSession(session); err != nil {
+		return models.SessionRes{}, "", err
+	}
+
+	return models.SessionRes{User: user, CSRFToken: csrf, ExpiresAt: session.ExpiresAt}, token, nil
+}
+
+// Authenticate resolves the session cookie to its user and extends the idle timeout.
+func (s *Service) Authenticate(token string) (repoModels.User, repoModels.Session, error) {
+	session, err := s.repo.GetSessionByTokenHash(utils.HashToken(token))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return repoModels.User{}, session, ErrInvalidSession
+	}
+	if err != nil {
+		return repoModels.User{}, session, err
+	}
+
+	now := time.Now()
+	if !session.IsActive(now) {
+		return repoModels.User{}, session, ErrInvalidSession
+	}
+
+	user, err := s.users.GetUserByID(session.UserID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return user, session, ErrInvalidSession
+	}
+	if err != nil {
+		return user, session, err
+	}
+
+	idle := now.Add(s.cfg.IdleTimeout)
+	if idle.After(session.ExpiresAt) {
+		idle = session.ExpiresAt
+	}
+	return user, session, s.repo.TouchSession(session.ID, now, idle)
+}
+
+// CheckCSRF compares the synchronizer token sent by the client with the one of the session.
+func (s *Service) CheckCSRF(session repoModels.Session, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(session.CSRFToken), []byte(token)) == 1
+}
+
+// GetSession returns the state of the current session, so a reloaded page can pick up the CSRF token again.
+func (s *Service) GetSession(current primitive.ObjectID) (models.SessionRes, error) {
+	session, err := s.repo.GetSessionByID(current)
+	if err != nil {
+		return models.SessionRes{}, err
+	}
+	user, err := s.users.GetUserByID(session.UserID)
+	if err != nil {
+		return models.SessionRes{}, err
+	}
+	return models.SessionRes{User: user, CSRFToken: session.CSRFToken, ExpiresAt: session.ExpiresAt}, nil
+}
+
+func (s *Service) Logout(current primitive.ObjectID) error {
+	return s.repo.DeleteSession(current)
+}
+
+// GetSessions lists the active sessions of the user, current marks the one making the request.
+func (s *Service) GetSessions(access models.UserAccess, current primitive.ObjectID) ([]repoModels.Session, error) {
+	sessions, err := s.repo.GetActiveSessions(access.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	return sessions, nil
+}
+
+func (s *Service) RevokeSession(id primitive.ObjectID, access models.UserAccess) error {
+	session, err := s.repo.GetSessionByID(id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != access.ID && !access.IsAdmin() {
+		return utils.ErrNotAllowed
+	}
+	return s.repo.DeleteSession(id)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session.
+func (s *Service) RevokeOtherSessions(access models.UserAccess, current primitive.ObjectID) (int64, error) {
+	return s.repo.DeleteUserSessions(access.ID, current)
+}
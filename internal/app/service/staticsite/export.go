@@ -0,0 +1,495 @@
+package staticsite
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	srvSEO "blog-platform/internal/app/service/seo"
+	"blog-platform/internal/feed"
+	"blog-platform/internal/site"
+	"blog-platform/internal/sitemap"
+	"blog-platform/internal/theme"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// manifestName is the file in the export directory recording what the previous export wrote.
+const manifestName = ".export-manifest.json"
+
+// manifestVersion is raised whenever the layout of the export changes, so the next export starts afresh.
+const manifestVersion = 3
+
+// manifest records an export. Posts holds the fingerprints of what the pages of the exported posts show and Media
+// those of the copied media, both by ID, Files lists every file written, as slash separated paths below the export
+// directory.
+type manifest struct {
+	Version     int               `json:"version"`
+	Fingerprint string            `json:"fingerprint"`
+	Posts       map[string]string `json:"posts"`
+	Media       map[string]string `json:"media"`
+	Files       []string          `json:"files"`
+}
+
+func readManifest(dir string) (manifest, error) {
+	var m manifest
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	} else if err != nil {
+		return m, err
+	}
+	if err = json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", manifestName, err)
+	}
+	return m, nil
+}
+
+// exportedMedia is a file copied into the export, with the names of its copied variants.
+type exportedMedia struct {
+	file     string
+	variants map[string]bool
+}
+
+// export is a run of Service.Export.
+type export struct {
+	*Service
+	ctx    context.Context
+	dir    string
+	prev   manifest
+	next   manifest
+	files  map[string]bool
+	media  map[string]exportedMedia
+	report Report
+}
+
+// copyMedia copies the files linked by the posts, and their variants, to media/<id>/. Only files referenced by
+// one of the posts are copied, the others are not public.
+func (e *export) copyMedia(posts []repoModels.Post) error {
+	e.media = map[string]exportedMedia{}
+	if e.store == nil {
+		return nil
+	}
+	published := map[primitive.ObjectID]bool{}
+	var ids []string
+	seen := map[string]bool{}
+	for _, post := range posts {
+		published[post.ID] = true
+		for _, match := range e.mediaLink.FindAllStringSubmatch(post.ContentHTML+" "+post.SEO.Image, -1) {
+			if !seen[match[2]] {
+				seen[match[2]] = true
+				ids = append(ids, match[2])
+			}
+		}
+	}
+	sort.Strings(ids)
+
+	for _, hex := range ids {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		media, err := e.Service.media.GetMediaByID(id)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		public := false
+		for _, ref := range media.References {
+			public = public || published[ref]
+		}
+		if !public {
+			continue
+		}
+
+		exported := exportedMedia{file: "file" + strings.ToLower(path.Ext(media.Key)), variants: map[string]bool{}}
+		keys := map[string]string{exported.file: media.Key}
+		fingerprint := media.Key
+		for _, v := range media.Variants {
+			if !mediaName(v.Name) {
+				continue
+			}
+			exported.variants[v.Name] = true
+			keys[v.Name] = v.Key
+			fingerprint += "\n" + v.Name + "=" + v.Key
+		}
+		e.next.Media[hex] = fingerprint
+		keep := e.prev.Media[hex] == fingerprint
+		for name := range keys {
+			keep = keep && e.exists(path.Join("media", hex, name))
+		}
+		for name, key := range keys {
+			rel := path.Join("media", hex, name)
+			if keep {
+				e.keep(rel)
+			} else if err = e.copyBlob(rel, key); err != nil {
+				return fmt.Errorf("%s: %w", hex, err)
+			}
+		}
+		e.media[hex] = exported
+	}
+	return nil
+}
+
+// mediaName tells whether a variant name can be used as a file name as it is.
+func mediaName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+func (e *export) copyBlob(rel, key string) error {
+	obj, err := e.store.Get(e.ctx, key)
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+	return e.writeFrom(rel, obj.Body)
+}
+
+// rewriteMedia points the links to copied media at their copies.
+func (e *export) rewriteMedia(data []byte) []byte {
+	return e.mediaLink.ReplaceAllFunc(data, func(match []byte) []byte {
+		groups := e.mediaLink.FindSubmatch(match)
+		exported, ok := e.media[string(groups[2])]
+		name := exported.file
+		if variant := string(groups[3]); variant != "" {
+			ok, name = ok && exported.variants[variant], variant
+		}
+		if !ok {
+			// Left to the server, which serves the file on its own address.
+			link := strings.TrimPrefix(string(match[len(groups[1]):]), e.serverURL)
+			return []byte(string(groups[1]) + e.serverURL + link)
+		}
+		return []byte(string(groups[1]) + e.baseURL + "/media/" + string(groups[2]) + "/" + name)
+	})
+}
+
+// writePages renders the pages of the posts, the home page and the pages of the authors and tags.
+func (e *export) writePages(posts []repoModels.Post) error {
+	var authors, tags []string
+	byAuthor := map[string][]repoModels.Post{}
+	byTag := map[string][]repoModels.Post{}
+	for _, post := range posts {
+		if err := e.writePost(post); err != nil {
+			return err
+		}
+		username := post.Author.Username
+		if _, ok := byAuthor[username]; !ok {
+			authors = append(authors, username)
+		}
+		byAuthor[username] = append(byAuthor[username], post)
+		for _, tag := range post.Tags {
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], post)
+		}
+	}
+	sort.Strings(authors)
+	sort.Strings(tags)
+
+	if err := e.writeList(theme.PageHome, site.HomePath, "", "", posts); err != nil {
+		return err
+	}
+	for _, author := range authors {
+		if err := e.writeList(theme.PageAuthor, site.AuthorPath(author), author, "", byAuthor[author]); err != nil {
+			return err
+		}
+	}
+	for _, tag := range tags {
+		if err := e.writeList(theme.PageTag, site.TagPath(tag), "", tag, byTag[tag]); err != nil {
+			return err
+		}
+	}
+	return e.render("404.html", theme.PageError, e.pages.Error(http.StatusNotFound))
+}
+
+// writePost renders the page of a post, unless the previous export has it and what it shows is the same. The
+// updated_at of the post alone would miss renamed tags and content that reindex rendered again.
+func (e *export) writePost(post repoModels.Post) error {
+	rel, err := pageFile(site.PostPath(post.Author.Username, post.Slug))
+	if err != nil {
+		return err
+	}
+	page := e.pages.PostPage(post)
+	data, err := json.Marshal(page)
+	if err != nil {
+		return fmt.Errorf("%s: %w", rel, err)
+	}
+	sum := sha256.Sum256(data)
+	id, fingerprint := post.ID.Hex(), hex.EncodeToString(sum[:])
+	e.next.Posts[id] = fingerprint
+	if e.prev.Posts[id] == fingerprint && e.exists(rel) {
+		e.keep(rel)
+		return nil
+	}
+	return e.render(rel, theme.PagePost, page)
+}
+
+// writeList renders a list of posts at listPath, in pages of the page size. The first page is the list itself,
+// the others follow at site.PagedPath.
+func (e *export) writeList(page, listPath, author, tag string, posts []repoModels.Post) error {
+	pages := max(1, (len(posts)+e.pageSize-1)/e.pageSize)
+	for n := 1; n <= pages; n++ {
+		list := e.pages.ListPage(author, tag, n > 1)
+		list.Posts = posts[(n-1)*e.pageSize : min(n*e.pageSize, len(posts))]
+		if n > 1 {
+			list.PrevURL = site.PagedPath(listPath, n-1)
+		}
+		if n < pages {
+			list.NextURL = site.PagedPath(listPath, n+1)
+		}
+		rel, err := pageFile(site.PagedPath(listPath, n))
+		if err != nil {
+			return err
+		}
+		if err = e.render(rel, page, list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFeeds writes the feeds of all posts and those of every author and tag, in each format.
+func (e *export) writeFeeds(posts []repoModels.Post) error {
+	reqs := []models.FeedReq{{}}
+	authors := map[string]bool{}
+	tags := map[string]bool{}
+	for _, post := range posts {
+		authors[post.Author.Username] = true
+		for _, tag := range post.Tags {
+			tags[tag] = true
+		}
+	}
+	for _, author := range sortedKeys(authors) {
+		reqs = append(reqs, models.FeedReq{Author: author})
+	}
+	for _, tag := range sortedKeys(tags) {
+		reqs = append(reqs, models.FeedReq{Tag: tag})
+	}
+
+	for _, req := range reqs {
+		for _, format := range []string{feed.FormatRSS, feed.FormatAtom, feed.FormatJSON} {
+			req.Format, req.Full = format, true
+			f, err := e.feeds.Feed(e.ctx, req)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				// The author of the posts no longer exists.
+				break
+			} else if err != nil {
+				return err
+			}
+
+			feedPath := site.FeedPath(format)
+			switch {
+			case req.Author != "":
+				feedPath = site.AuthorFeedPath(req.Author, format)
+			case req.Tag != "":
+				feedPath = site.TagFeedPath(req.Tag, format)
+			}
+			rel, err := localFile(feedPath)
+			if err != nil {
+				return err
+			}
+			var buf bytes.Buffer
+			if err = feed.Write(&buf, f, format); err != nil {
+				return err
+			}
+			if err = e.write(rel, e.rewriteMedia(buf.Bytes())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSEO writes robots.txt, the sitemap index and every sitemap.
+func (e *export) writeSEO() error {
+	if err := e.write("robots.txt", []byte(e.seo.Robots())); err != nil {
+		return err
+	}
+	index, err := e.seo.SitemapIndex(e.ctx)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = sitemap.WriteIndex(&buf, index); err != nil {
+		return err
+	}
+	if err = e.write(strings.TrimPrefix(site.SitemapIndexPath, "/"), buf.Bytes()); err != nil {
+		return err
+	}
+
+	for _, kind := range []string{srvSEO.KindPosts, srvSEO.KindAuthors} {
+		for page := 1; ; page++ {
+			urls, err := e.seo.Sitemap(e.ctx, kind, page)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				break
+			} else if err != nil {
+				return err
+			}
+			buf.Reset()
+			if err = sitemap.WriteSitemap(&buf, urls); err != nil {
+				return err
+			}
+			if err = e.write(strings.TrimPrefix(site.SitemapPath(kind, page), "/"), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeAssets copies the static files of the theme.
+func (e *export) writeAssets() error {
+	names, err := e.theme.Assets()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		data, err := e.theme.Asset(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		rel, err := localFile(site.AssetPath(name))
+		if err != nil {
+			return err
+		}
+		if err = e.write(rel, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish removes the files of the previous export that were not written this time and records the export.
+func (e *export) finish() error {
+	old, _ := readManifest(e.dir)
+	for _, rel := range old.Files {
+		if e.files[rel] || !filepath.IsLocal(filepath.FromSlash(rel)) {
+			continue
+		}
+		err := os.Remove(filepath.Join(e.dir, filepath.FromSlash(rel)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		e.report.Removed++
+		// Directories left empty go as well, removing one that is not empty fails and ends the walk up.
+		for parent := path.Dir(rel); parent != "."; parent = path.Dir(parent) {
+			if os.Remove(filepath.Join(e.dir, filepath.FromSlash(parent))) != nil {
+				break
+			}
+		}
+	}
+
+	e.next.Files = sortedKeys(e.files)
+	data, err := json.MarshalIndent(e.next, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(e.dir, manifestName), bytes.NewReader(append(data, '\n')))
+}
+
+func (e *export) render(rel, page string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := e.theme.Render(&buf, page, data); err != nil {
+		return fmt.Errorf("%s: %w", rel, err)
+	}
+	return e.write(rel, e.rewriteMedia(buf.Bytes()))
+}
+
+// write writes the file at rel, unless it already has the content.
+func (e *export) write(rel string, data []byte) error {
+	e.files[rel] = true
+	name := filepath.Join(e.dir, filepath.FromSlash(rel))
+	if current, err := os.ReadFile(name); err == nil && bytes.Equal(current, data) {
+		e.report.Unchanged++
+		return nil
+	}
+	e.report.Written++
+	return writeFile(name, bytes.NewReader(data))
+}
+
+// writeFrom writes the file at rel from r.
+func (e *export) writeFrom(rel string, r io.Reader) error {
+	e.files[rel] = true
+	e.report.Written++
+	return writeFile(filepath.Join(e.dir, filepath.FromSlash(rel)), r)
+}
+
+// keep keeps the file at rel from the previous export.
+func (e *export) keep(rel string) {
+	e.files[rel] = true
+	e.report.Unchanged++
+}
+
+func (e *export) exists(rel string) bool {
+	info, err := os.Stat(filepath.Join(e.dir, filepath.FromSlash(rel)))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// writeFile replaces the file at name, through a temporary file so an interrupted export leaves no partial files.
+func writeFile(name string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// pageFile is the file holding the page at urlPath, static hosts serve the index.html of a directory at its path.
+func pageFile(urlPath string) (string, error) {
+	if urlPath == site.HomePath {
+		return "index.html", nil
+	}
+	rel, err := localFile(urlPath)
+	return path.Join(rel, "index.html"), err
+}
+
+// localFile is the slash separated file of urlPath below the export directory.
+func localFile(urlPath string) (string, error) {
+	rel, err := url.PathUnescape(strings.TrimPrefix(urlPath, "/"))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(filepath.FromSlash(rel)) || strings.Contains(rel, `\`) {
+		return "", fmt.Errorf("%q is not a path below the export directory", urlPath)
+	}
+	return rel, nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,196 @@
+package staticsite
+
+import (
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/feed"
+	"blog-platform/internal/sitemap"
+	"blog-platform/internal/storage"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	EachPost(ctx context.Context, filter interface{}, fn func(repoModels.Post) error) error
+}
+
+//go:generate mockery --name=MediaRepository --case underscore
+type MediaRepository interface {
+	GetMediaByID(id primitive.ObjectID) (repoModels.Media, error)
+}
+
+// PageService puts together the pages, as the reading site shows them.
+//
+//go:generate mockery --name=PageService --case underscore
+type PageService interface {
+	ListPage(author, tag string, further bool) models.PostListPage
+	PostPage(post repoModels.Post) models.PostPage
+	Error(status int) models.ErrorPage
+}
+
+//go:generate mockery --name=FeedService --case underscore
+type FeedService interface {
+	Feed(ctx context.Context, req models.FeedReq) (feed.Feed, error)
+}
+
+//go:generate mockery --name=SEOService --case underscore
+type SEOService interface {
+	Robots() string
+	SitemapIndex(ctx context.Context) ([]sitemap.URL, error)
+	Sitemap(ctx context.Context, kind string, page int) ([]sitemap.URL, error)
+}
+
+//go:generate mockery --name=Theme --case underscore
+type Theme interface {
+	Render(w io.Writer, page string, data interface{}) error
+	Asset(name string) ([]byte, error)
+	Assets() ([]string, error)
+	Fingerprint() (string, error)
+}
+
+// Report counts the files of an export: written ones were created or changed, unchanged ones kept as they were
+// and removed ones belonged to pages that are gone.
+type Report struct {
+	Written   int
+	Unchanged int
+	Removed   int
+}
+
+// Service exports the reading site as static files: the pages of the published posts, authors and tags, the
+// feeds, the sitemaps and the media of the posts. Search needs the server and is left out.
+type Service struct {
+	posts     PostRepository
+	media     MediaRepository
+	store     storage.Storage
+	pages     PageService
+	feeds     FeedService
+	seo       SEOService
+	theme     Theme
+	site      config.SiteConfig
+	pageSize  int
+	baseURL   string
+	serverURL string
+	mediaLink *regexp.Regexp
+}
+
+// New creates the export for a site at baseURL, the links to the media served by the API at serverURL are
+// replaced by links to their copies. Without a store the media are not copied and their links stay as they are.
+func New(posts PostRepository, media MediaRepository, store storage.Storage, pages PageService, feeds FeedService, seo SEOService, theme Theme, site config.SiteConfig, pageSize int, baseURL, serverURL string) *Service {
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	serverURL = strings.TrimRight(serverURL, "/")
+	return &Service{
+		posts:     posts,
+		media:     media,
+		store:     store,
+		pages:     pages,
+		feeds:     feeds,
+		seo:       seo,
+		theme:     theme,
+		site:      site,
+		pageSize:  pageSize,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		serverURL: serverURL,
+		// Links start an attribute, a srcset candidate or a JSON string, which may be escaped in feeds.
+		mediaLink: regexp.MustCompile(`(^|[\s"'(=,;])(?:` + regexp.QuoteMeta(serverURL) + `)?/api/v1/media/([0-9a-f]{24})/file(?:/([A-Za-z0-9._-]+))?`),
+	}
+}
+
+// Export writes the site into dir. Pages of posts that show the same as in the previous export into dir are
+// kept, as are the copies of media that did not change, unless full is set. Other files are only written when
+// their content changes and the files of the previous export that are no longer part of the site are removed.
+// The output only depends on the content, exporting the same content twice gives the same files.
+func (s *Service) Export(ctx context.Context, dir string, full bool) (Report, error) {
+	fingerprint, err := s.fingerprint()
+	if err != nil {
+		return Report{}, err
+	}
+	e := &export{
+		Service: s,
+		ctx:     ctx,
+		dir:     dir,
+		files:   map[string]bool{},
+		next: manifest{
+			Version:     manifestVersion,
+			Fingerprint: fingerprint,
+			Posts:       map[string]string{},
+			Media:       map[string]string{},
+		},
+	}
+	if !full {
+		if e.prev, err = readManifest(dir); err != nil {
+			return Report{}, err
+		}
+	}
+	if e.prev.Version != manifestVersion || e.prev.Fingerprint != fingerprint {
+		e.prev = manifest{}
+	}
+
+	posts, err := s.publishedPosts(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	if err = e.copyMedia(posts); err != nil {
+		return e.report, fmt.Errorf("media: %w", err)
+	}
+	if err = e.writePages(posts); err != nil {
+		return e.report, fmt.Errorf("pages: %w", err)
+	}
+	if err = e.writeFeeds(posts); err != nil {
+		return e.report, fmt.Errorf("feeds: %w", err)
+	}
+	if err = e.writeSEO(); err != nil {
+		return e.report, fmt.Errorf("sitemaps: %w", err)
+	}
+	if err = e.writeAssets(); err != nil {
+		return e.report, fmt.Errorf("theme: %w", err)
+	}
+	if err = e.finish(); err != nil {
+		return e.report, err
+	}
+	return e.report, nil
+}
+
+// fingerprint changes with everything the pages of posts depend on besides the posts, so they are all rendered
+// again when it does.
+func (s *Service) fingerprint() (string, error) {
+	themeFingerprint, err := s.theme.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range []string{themeFingerprint, s.site.Title, s.site.Description, s.baseURL, s.serverURL, fmt.Sprint(s.store != nil)} {
+		fmt.Fprintf(h, "%d:%s\n", len(part), part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// publishedPosts loads the published posts, newest first as the lists show them.
+func (s *Service) publishedPosts(ctx context.Context) ([]repoModels.Post, error) {
+	filter := bson.M{"deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$lte": time.Now()}}
+	var posts []repoModels.Post
+	err := s.posts.EachPost(ctx, filter, func(post repoModels.Post) error {
+		posts = append(posts, post)
+		return nil
+	})
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].PublishedAt.Equal(posts[j].PublishedAt) {
+			return posts[i].PublishedAt.After(posts[j].PublishedAt)
+		}
+		return posts[i].ID.Hex() > posts[j].ID.Hex()
+	})
+	return posts, err
+}
@@ -0,0 +1,82 @@
+package tag
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/utils"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"log"
+	"regexp"
+	"strings"
+)
+
+//go:generate mockery --name=PostRepository --case underscore
+type PostRepository interface {
+	GetTagCounts(ctx context.Context, filter interface{}) ([]repoModels.TagCount, error)
+	ReplaceTags(ctx context.Context, from []string, to string) (int64, error)
+}
+
+//go:generate mockery --name=Reindexer --case underscore
+type Reindexer interface {
+	ReindexPosts(ctx context.Context, filter interface{}) error
+}
+
+type Service struct {
+	posts     PostRepository
+	reindexer Reindexer
+}
+
+func New(posts PostRepository, reindexer Reindexer) *Service {
+	return &Service{posts: posts, reindexer: reindexer}
+}
+
+// GetTags lists the tags of the published posts that are not deleted with their usage counts, optionally only
+// the tags starting with prefix.
+func (s *Service) GetTags(ctx context.Context, prefix string) ([]repoModels.TagCount, error) {
+	filter := bson.M{"deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$exists": true}}
+	if prefix = utils.NormalizeTag(prefix); prefix != "" {
+		filter["tags"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+	}
+
+	tags, err := s.posts.GetTagCounts(ctx, filter)
+	if err != nil || prefix == "" {
+		return tags, err
+	}
+	// the match keeps whole posts, drop their other tags
+	matching := tags[:0]
+	for _, t := range tags {
+		if strings.HasPrefix(t.Name, prefix) {
+			matching = append(matching, t)
+		}
+	}
+	return matching, nil
+}
+
+func (s *Service) RenameTag(ctx context.Context, tag string, req models.RenameTagReq) (models.TagChangeRes, error) {
+	return s.MergeTags(ctx, models.MergeTagsReq{Tags: []string{tag}, Into: req.Name})
+}
+
+// MergeTags replaces the tags with the target tag on every post, renaming a tag to an existing one merges them.
+func (s *Service) MergeTags(ctx context.Context, req models.MergeTagsReq) (models.TagChangeRes, error) {
+	into := utils.NormalizeTag(req.Into)
+	if into == "" {
+		return models.TagChangeRes{}, fmt.Errorf("%w: target tag is empty", utils.ErrBadRequest)
+	}
+	from := utils.NormalizeTags(req.Tags)
+	if len(from) == 0 {
+		return models.TagChangeRes{}, fmt.Errorf("%w: no tags to merge", utils.ErrBadRequest)
+	}
+
+	posts, err := s.posts.ReplaceTags(ctx, from, into)
+	if err != nil {
+		return models.TagChangeRes{}, err
+	}
+	if posts > 0 {
+		if err = s.reindexer.ReindexPosts(ctx, bson.M{"tags": into, "deleted_at": bson.M{"$exists": false}}); err != nil {
+			log.Printf("reindexing posts tagged %q: %v", into, err)
+		}
+	}
+	return models.TagChangeRes{Tag: into, Posts: posts}, nil
+}
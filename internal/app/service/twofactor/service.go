@@ -0,0 +1,261 @@
+package twofactor
+
+import (
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/totp"
+	"blog-platform/internal/utils"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"time"
+)
+
+const (
+	Issuer            = "Blog Platform"
+	RecoveryCodeCount = 10
+	QRCodeSize        = 256
+)
+
+var (
+	ErrCodeRequired = errors.New("two-factor code required")
+	ErrInvalidCode  = errors.New("invalid two-factor code")
+)
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	UpdateUserFields(id primitive.ObjectID, set, unset bson.M) error
+	AdvanceTwoFactorStep(id primitive.ObjectID, step int64) (bool, error)
+	UseRecoveryCode(id primitive.ObjectID, hash string) (bool, error)
+}
+
+//go:generate mockery --name=SettingsRepository --case underscore
+type SettingsRepository interface {
+	GetSecuritySettings() (repoModels.SecuritySettings, error)
+	SaveSecuritySettings(settings repoModels.SecuritySettings) error
+}
+
+//go:generate mockery --name=SessionRepository --case underscore
+type SessionRepository interface {
+	DeleteUserSessions(userID, keep primitive.ObjectID) (int64, error)
+}
+
+type Service struct {
+	users    UserRepository
+	settings SettingsRepository
+	sessions SessionRepository
+}
+
+func New(users UserRepository, settings SettingsRepository, sessions SessionRepository) *Service {
+	return &Service{users: users, settings: settings, sessions: sessions}
+}
+
+// Enroll starts an enrollment by storing a new, not yet enabled, secret. Calling it again replaces a pending secret.
+func (s *Service) Enroll(access models.UserAccess) (models.TwoFactorEnrollmentRes, error) {
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return models.TwoFactorEnrollmentRes{}, err
+	}
+	if user.HasTwoFactor() {
+		return models.TwoFactorEnrollmentRes{}, fmt.Errorf("%w: two-factor authentication already enabled", utils.ErrBadRequest)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return models.TwoFactorEnrollmentRes{}, err
+	}
+	err = s.users.UpdateUserFields(user.ID, bson.M{"two_factor": repoModels.TwoFactor{Secret: secret}}, nil)
+	if err != nil {
+		return models.TwoFactorEnrollmentRes{}, err
+	}
+
+	return models.TwoFactorEnrollmentRes{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(Issuer, user.Username, secret),
+	}, nil
+}
+
+// QRCode renders the provisioning URI of a pending enrollment as PNG, the secret is not shown again once enabled.
+func (s *Service) QRCode(access models.UserAccess) ([]byte, error) {
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || user.TwoFactor.Enabled {
+		return nil, fmt.Errorf("%w: no pending two-factor enrollment", utils.ErrBadRequest)
+	}
+
+	return qrcode.Encode(totp.ProvisioningURI(Issuer, user.Username, user.TwoFactor.Secret), qrcode.Medium, QRCodeSize)
+}
+
+// Confirm enables two-factor authentication once the user proves the authenticator works, the returned
+// recovery codes are only shown this once.
+func (s *Service) Confirm(access models.UserAccess, code string) ([]string, error) {
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || user.TwoFactor.Enabled {
+		return nil, fmt.Errorf("%w: no pending two-factor enrollment", utils.ErrBadRequest)
+	}
+
+	now := time.Now()
+	step, ok := totp.Validate(user.TwoFactor.Secret, code, now)
+	if !ok {
+		return nil, fmt.Errorf("%w: %w", utils.ErrBadRequest, ErrInvalidCode)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = s.users.UpdateUserFields(user.ID, bson.M{"two_factor": repoModels.TwoFactor{
+		Secret:        user.TwoFactor.Secret,
+		Enabled:       true,
+		ConfirmedAt:   &now,
+		LastStep:      step,
+		RecoveryCodes: hashes,
+	}}, nil)
+	return codes, err
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, it needs a current code from the authenticator.
+func (s *Service) RegenerateRecoveryCodes(access models.UserAccess, code string) ([]string, error) {
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.HasTwoFactor() {
+		return nil, fmt.Errorf("%w: two-factor authentication is not enabled", utils.ErrBadRequest)
+	}
+	if _, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now()); !ok {
+		return nil, fmt.Errorf("%w: %w", utils.ErrBadRequest, ErrInvalidCode)
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	return codes, s.users.UpdateUserFields(user.ID, bson.M{"two_factor.recovery_codes": hashes}, nil)
+}
+
+// Disable removes the enrollment of the calling user, not allowed when their role requires two-factor authentication.
+func (s *Service) Disable(access models.UserAccess, code string) error {
+	user, err := s.users.GetUserByID(access.ID)
+	if err != nil {
+		return err
+	}
+	if user.TwoFactor == nil {
+		return fmt.Errorf("%w: two-factor authentication is not enabled", utils.ErrBadRequest)
+	}
+	if user.TwoFactor.Enabled {
+		required, err := s.EnrollmentRequired(user)
+		if err != nil {
+			return err
+		}
+		if required {
+			return fmt.Errorf("%w: two-factor authentication is required for role %s", utils.ErrNotAllowed, user.Role)
+		}
+		if err = s.Verify(user, code); err != nil {
+			return fmt.Errorf("%w: %w", utils.ErrBadRequest, err)
+		}
+	}
+
+	return s.users.UpdateUserFields(user.ID, nil, bson.M{"two_factor": ""})
+}
+
+// Reset lets an admin remove the enrollment of a user who lost their authenticator and recovery codes. The user
+// is signed out of every browser session, whoever holds one may be the reason for the reset.
+func (s *Service) Reset(id primitive.ObjectID) error {
+	if _, err := s.users.GetUserByID(id); err != nil {
+		return err
+	}
+	if err := s.users.UpdateUserFields(id, nil, bson.M{"two_factor": ""}); err != nil {
+		return err
+	}
+	_, err := s.sessions.DeleteUserSessions(id, primitive.NilObjectID)
+	return err
+}
+
+// Verify checks the second factor of an enrolled user, code is either a TOTP code or an unused recovery code.
+// Since every Basic-auth request carries a code, reusing the current step is accepted while older steps are not.
+func (s *Service) Verify(user repoModels.User, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrCodeRequired
+	}
+
+	if step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now()); ok {
+		advanced, err := s.users.AdvanceTwoFactorStep(user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	used, err := s.users.UseRecoveryCode(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// EnrollmentRequired reports whether the user's role requires two-factor authentication.
+func (s *Service) EnrollmentRequired(user repoModels.User) (bool, error) {
+	settings, err := s.settings.GetSecuritySettings()
+	if err != nil {
+		return false, err
+	}
+	return settings.RequiresTwoFactor(user.Role), nil
+}
+
+func (s *Service) GetPolicy() (repoModels.SecuritySettings, error) {
+	return s.settings.GetSecuritySettings()
+}
+
+// SetPolicy sets the roles that must use two-factor authentication, users of those roles without an
+// enrollment can only reach the enrollment endpoints afterwards.
+func (s *Service) SetPolicy(roles []string) (repoModels.SecuritySettings, error) {
+	for _, role := range roles {
+		if !repoModels.IsValidRole(role) {
+			return repoModels.SecuritySettings{}, fmt.Errorf("%w: unknown role %q", utils.ErrBadRequest, role)
+		}
+	}
+
+	settings, err := s.settings.GetSecuritySettings()
+	if err != nil {
+		return settings, err
+	}
+	settings.TwoFactorRequiredFor = roles
+	return settings, s.settings.SaveSecuritySettings(settings)
+}
+
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < RecoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err = rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(enc.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -3,48 +3,167 @@ package user
 import (
 	"blog-platform/internal/app/controller/models"
 	repoModels "blog-platform/internal/app/repositories/models"
-	"errors"
+	"blog-platform/internal/pagination"
+	"blog-platform/internal/utils"
+	"context"
+	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"strings"
+	"time"
 )
 
 type Repository interface {
 	CreateUser(user repoModels.User) error
-	GetUsers(filter interface{}, offset, limit int) ([]repoModels.User, error)
+	GetUsers(query repoModels.ListQuery) ([]repoModels.User, error)
+	CountUsers(filter interface{}) (int64, error)
 	GetUserByID(id primitive.ObjectID) (repoModels.User, error)
+	GetUserByUsername(username string) (repoModels.User, error)
 	UpdateUser(user repoModels.User) error
+	UpdateUserFields(id primitive.ObjectID, set, unset bson.M) error
 	DeleteUser(id primitive.ObjectID) error
 }
 
+//go:generate mockery --name=SessionRepository --case underscore
+type SessionRepository interface {
+	DeleteUserSessions(userID, keep primitive.ObjectID) (int64, error)
+}
+
+// AuthorRepository holds content carrying a copy of the username of its author, posts and comments.
+//
+//go:generate mockery --name=AuthorRepository --case underscore
+type AuthorRepository interface {
+	RenameAuthor(ctx context.Context, authorID primitive.ObjectID, username string) (int64, error)
+}
+
+//go:generate mockery --name=Reindexer --case underscore
+type Reindexer interface {
+	ReindexPosts(ctx context.Context, filter interface{}) error
+}
+
+// userKeys orders the user listings by creation, ids of new users are always larger.
+// ErrUsernameTaken and ErrEmailTaken reject an update to a username or email address another user has.
+var (
+	ErrUsernameTaken = fmt.Errorf("%w: username is already used by another user", utils.ErrBadRequest)
+	ErrEmailTaken    = fmt.Errorf("%w: email address is already used by another user", utils.ErrBadRequest)
+)
+
+var userKeys = []pagination.Key{{Field: "_id"}}
+
 type Service struct {
-	repo Repository
+	repo      Repository
+	sessions  SessionRepository
+	posts     AuthorRepository
+	comments  AuthorRepository
+	reindexer Reindexer
+	cursors   *pagination.Codec
 }
 
-func New(repo Repository) *Service {
-	return &Service{repo}
+func New(repo Repository, sessions SessionRepository, posts, comments AuthorRepository, reindexer Reindexer, cursors *pagination.Codec) *Service {
+	return &Service{repo: repo, sessions: sessions, posts: posts, comments: comments, reindexer: reindexer, cursors: cursors}
 }
 
 func (s *Service) CreateUser(user repoModels.User) error {
 	return s.repo.CreateUser(user)
 }
 
-func (s *Service) GetUsers(page, limit int) ([]repoModels.User, error) {
-	offset := (page - 1) * limit
+func (s *Service) GetUsers(page models.PageReq) ([]repoModels.User, *repoModels.ListMetaData, error) {
 	filter := bson.M{"deleted_at": bson.M{"$exists": false}}
-	return s.repo.GetUsers(filter, offset, limit)
+	return s.list("users", filter, page)
 }
 
 func (s *Service) GetUserByID(id primitive.ObjectID) (repoModels.User, error) {
 	return s.repo.GetUserByID(id)
 }
 
-func (s *Service) UpdateUser(user repoModels.User, access models.UserAccess) error {
-	err := s.GetUserAndAuthorise(user.ID, access)
+// UpdateUser applies a self-service update, only the profile fields of the request are copied so
+// role, status and password can not be changed through this path. A new username is copied to the posts and
+// comments of the user, the links and filters by username follow the rename and the old name carries nothing.
+// A username or email address another user has is rejected with ErrUsernameTaken or ErrEmailTaken.
+func (s *Service) UpdateUser(id primitive.ObjectID, req models.UserUpdateReq, access models.UserAccess) (repoModels.User, error) {
+	err := s.GetUserAndAuthorise(id, access)
+	if err != nil {
+		return repoModels.User{}, err
+	}
+
+	user, err := s.repo.GetUserByID(id)
+	if err != nil {
+		return repoModels.User{}, err
+	}
+	oldUsername := user.Username
+	if req.Username != "" && req.Username != user.Username {
+		other, err := s.repo.GetUserByUsername(req.Username)
+		if err == nil && other.ID != user.ID {
+			return repoModels.User{}, ErrUsernameTaken
+		} else if err != nil && err != mongo.ErrNoDocuments {
+			return repoModels.User{}, err
+		}
+		user.Username = req.Username
+	}
+	emailChanged := false
+	if email := strings.ToLower(strings.TrimSpace(req.Email)); email != "" && email != user.Email {
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
+	}
+
+	if err = s.repo.UpdateUser(user); mongo.IsDuplicateKeyError(err) {
+		// the address belongs to another user, or a concurrent update took the name first
+		if emailChanged {
+			return repoModels.User{}, ErrEmailTaken
+		}
+		return repoModels.User{}, ErrUsernameTaken
+	} else if err != nil {
+		return repoModels.User{}, err
+	}
+	if user.Username != oldUsername {
+		if err = s.renameAuthor(user); err != nil {
+			return repoModels.User{}, err
+		}
+	}
+	return user, nil
+}
+
+// renameAuthor copies the username of the user to their posts and comments and updates the search index, which
+// filters by author.
+func (s *Service) renameAuthor(user repoModels.User) error {
+	ctx := context.Background()
+	posts, err := s.posts.RenameAuthor(ctx, user.ID, user.Username)
 	if err != nil {
 		return err
 	}
+	if _, err = s.comments.RenameAuthor(ctx, user.ID, user.Username); err != nil {
+		return err
+	}
+	if posts > 0 {
+		if err = s.reindexer.ReindexPosts(ctx, bson.M{"author._id": user.ID, "deleted_at": bson.M{"$exists": false}}); err != nil {
+			log.Printf("reindexing the posts of user %s: %v", user.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of the calling user and signs them out of every other browser session,
+// current is the session of the request, if it was made with one.
+func (s *Service) ChangePassword(req models.ChangePasswordReq, access models.UserAccess, current primitive.ObjectID) error {
+	user, err := s.repo.GetUserByID(access.ID)
+	if err != nil {
+		return err
+	}
+	if user.Password != req.OldPassword {
+		return utils.ErrNotAllowed
+	}
+	if req.NewPassword == "" || req.NewPassword == req.OldPassword {
+		return fmt.Errorf("%w: new password must be set and differ from the old one", utils.ErrBadRequest)
+	}
 
-	return s.repo.UpdateUser(user)
+	if err = s.repo.UpdateUserFields(user.ID, bson.M{"password": req.NewPassword}, bson.M{"must_change_password": ""}); err != nil {
+		return err
+	}
+	_, err = s.sessions.DeleteUserSessions(user.ID, current)
+	return err
 }
 
 func (s *Service) DeleteUser(id primitive.ObjectID, access models.UserAccess) error {
@@ -62,9 +181,160 @@ func (s *Service) GetUserAndAuthorise(id primitive.ObjectID, access models.UserA
 		return err
 	}
 
-	if user.ID == access.ID || *access.Role == "admin" {
+	if user.ID == access.ID || access.IsAdmin() {
 		return nil
 	}
 
-	return errors.New("not allowed")
+	return utils.ErrNotAllowed
+}
+
+// ListUsers is the admin listing, unlike GetUsers it can filter by role and status and includes deleted users on request.
+func (s *Service) ListUsers(role, status string, includeDeleted bool, page models.PageReq) ([]repoModels.User, *repoModels.ListMetaData, error) {
+	filter := bson.M{}
+	if !includeDeleted {
+		filter["deleted_at"] = bson.M{"$exists": false}
+	}
+	if role != "" {
+		filter["role"] = role
+	}
+	switch status {
+	case "":
+	case repoModels.UserStatusActive:
+		// documents created before statuses existed have no status field
+		filter["status"] = bson.M{"$in": bson.A{repoModels.UserStatusActive, nil}}
+	case repoModels.UserStatusSuspended, repoModels.UserStatusBanned:
+		filter["status"] = status
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown status %q", utils.ErrBadRequest, status)
+	}
+
+	return s.list("admin-users", filter, page)
+}
+
+// list reads a page of the users matching filter, listing keeps cursors of one listing out of the other.
+func (s *Service) list(listing string, filter bson.M, page models.PageReq) ([]repoModels.User, *repoModels.ListMetaData, error) {
+	scope := pagination.Scope(listing, userKeys)
+	cursor, err := s.cursors.Decode(scope, page.Cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+	seek, sort, err := pagination.Seek(userKeys, cursor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := repoModels.ListQuery{Filter: filter, Sort: sort, Limit: page.Limit + 1}
+	if seek != nil {
+		query.Filter = bson.M{"$and": bson.A{filter, seek}}
+	}
+	users, err := s.repo.GetUsers(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	users, next, prev := pagination.Page(users, page.Limit, cursor, func(user repoModels.User) []interface{} {
+		return []interface{}{user.ID}
+	})
+
+	meta := repoModels.ListMetaData{Limit: page.Limit}
+	if meta.NextCursor, err = s.cursors.Encode(scope, next); err != nil {
+		return nil, nil, err
+	}
+	if meta.PrevCursor, err = s.cursors.Encode(scope, prev); err != nil {
+		return nil, nil, err
+	}
+	if page.Total {
+		total, err := s.repo.CountUsers(filter)
+		if err != nil {
+			return nil, nil, err
+		}
+		meta.Total = &total
+	}
+	return users, &meta, nil
+}
+
+func (s *Service) SetRole(id primitive.ObjectID, role string, access models.UserAccess) error {
+	if !repoModels.IsValidRole(role) {
+		return fmt.Errorf("%w: unknown role %q", utils.ErrBadRequest, role)
+	}
+	if id == access.ID && role != repoModels.RoleAdmin {
+		return fmt.Errorf("%w: admins can not demote themselves", utils.ErrBadRequest)
+	}
+	if _, err := s.repo.GetUserByID(id); err != nil {
+		return err
+	}
+
+	return s.repo.UpdateUserFields(id, bson.M{"role": role}, nil)
+}
+
+func (s *Service) Suspend(id primitive.ObjectID, req models.UserStatusReq, access models.UserAccess) error {
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		return fmt.Errorf("%w: until must be in the future", utils.ErrBadRequest)
+	}
+	set := bson.M{"status": repoModels.UserStatusSuspended, "status_reason": req.Reason}
+	unset := bson.M{}
+	if req.Until != nil {
+		set["suspended_until"] = req.Until
+	} else {
+		unset["suspended_until"] = ""
+	}
+
+	return s.setStatus(id, set, unset, access)
+}
+
+func (s *Service) Unsuspend(id primitive.ObjectID, access models.UserAccess) error {
+	user, err := s.repo.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	if user.Status == repoModels.UserStatusBanned {
+		return fmt.Errorf("%w: user is banned", utils.ErrBadRequest)
+	}
+
+	return s.setStatus(id, bson.M{"status": repoModels.UserStatusActive},
+		bson.M{"status_reason": "", "suspended_until": ""}, access)
+}
+
+func (s *Service) Ban(id primitive.ObjectID, req models.UserStatusReq, access models.UserAccess) error {
+	return s.setStatus(id, bson.M{"status": repoModels.UserStatusBanned, "status_reason": req.Reason},
+		bson.M{"suspended_until": ""}, access)
+}
+
+// setStatus signs the user out of every browser session unless they become active.
+func (s *Service) setStatus(id primitive.ObjectID, set, unset bson.M, access models.UserAccess) error {
+	if id == access.ID {
+		return fmt.Errorf("%w: admins can not change their own status", utils.ErrBadRequest)
+	}
+	if _, err := s.repo.GetUserByID(id); err != nil {
+		return err
+	}
+
+	if err := s.repo.UpdateUserFields(id, set, unset); err != nil {
+		return err
+	}
+	if set["status"] == repoModels.UserStatusActive {
+		return nil
+	}
+	_, err := s.sessions.DeleteUserSessions(id, primitive.NilObjectID)
+	return err
+}
+
+// ForcePasswordReset replaces the password with the given one, or a generated one when empty, and flags
+// the account so the user has to change it before using any other endpoint. The user is signed out of every
+// browser session. The temporary password is returned.
+func (s *Service) ForcePasswordReset(id primitive.ObjectID, password string) (string, error) {
+	if _, err := s.repo.GetUserByID(id); err != nil {
+		return "", err
+	}
+	if password == "" {
+		var err error
+		if password, err = utils.NewToken(12); err != nil {
+			return "", err
+		}
+	}
+
+	if err := s.repo.UpdateUserFields(id, bson.M{"password": password, "must_change_password": true}, nil); err != nil {
+		return "", err
+	}
+	_, err := s.sessions.DeleteUserSessions(id, primitive.NilObjectID)
+	return password, err
 }
@@ -0,0 +1,230 @@
+package web
+
+import (
+	"blog-platform/config"
+	"blog-platform/internal/app/controller/models"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/feed"
+	"blog-platform/internal/site"
+	"blog-platform/internal/utils"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//go:generate mockery --name=PostService --case underscore
+type PostService interface {
+	GetPosts(ctx context.Context, postFilter models.PostFilter) ([]repoModels.Post, *repoModels.ListMetaData, error)
+	GetPostBySlug(username, slug string) (repoModels.Post, bool, error)
+}
+
+//go:generate mockery --name=SearchService --case underscore
+type SearchService interface {
+	Search(ctx context.Context, req models.SearchReq) (models.SearchRes, error)
+}
+
+//go:generate mockery --name=UserRepository --case underscore
+type UserRepository interface {
+	GetUserByUsername(username string) (repoModels.User, error)
+}
+
+// Service puts together the pages of the reading site. Only published posts are shown.
+type Service struct {
+	posts    PostService
+	search   SearchService
+	users    UserRepository
+	site     config.SiteConfig
+	pageSize int
+	baseURL  string
+}
+
+func New(posts PostService, search SearchService, users UserRepository, site config.SiteConfig, pageSize int, baseURL string) *Service {
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	return &Service{posts: posts, search: search, users: users, site: site, pageSize: pageSize, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Home is a page of the newest posts.
+func (s *Service) Home(ctx context.Context, cursor string) (models.PostListPage, error) {
+	page := s.ListPage("", "", cursor != "")
+	return page, s.listPosts(ctx, &page, models.PostFilter{}, cursor)
+}
+
+// Author is a page of the newest posts of an author, authors that do not exist are not found.
+func (s *Service) Author(ctx context.Context, username, cursor string) (models.PostListPage, error) {
+	author, err := s.users.GetUserByUsername(username)
+	if err != nil {
+		return models.PostListPage{}, err
+	}
+	page := s.ListPage(author.Username, "", cursor != "")
+	return page, s.listPosts(ctx, &page, models.PostFilter{Authors: []string{author.Username}}, cursor)
+}
+
+// Tag is a page of the newest posts with a tag, tags without published posts are not found.
+func (s *Service) Tag(ctx context.Context, tag, cursor string) (models.PostListPage, error) {
+	tag = utils.NormalizeTag(tag)
+	if tag == "" {
+		return models.PostListPage{}, mongo.ErrNoDocuments
+	}
+	page := s.ListPage("", tag, cursor != "")
+	if err := s.listPosts(ctx, &page, models.PostFilter{Tag: tag}, cursor); err != nil {
+		return page, err
+	}
+	if len(page.Posts) == 0 && cursor == "" {
+		return page, mongo.ErrNoDocuments
+	}
+	return page, nil
+}
+
+func (s *Service) listPosts(ctx context.Context, page *models.PostListPage, filter models.PostFilter, cursor string) error {
+	now := time.Now()
+	filter.Published = models.TimeRange{To: &now}
+	filter.Sort = []models.SortField{{Field: "published_at", Desc: true}}
+	filter.Page = models.PageReq{Limit: s.pageSize, Cursor: cursor}
+	posts, meta, err := s.posts.GetPosts(ctx, filter)
+	if err != nil {
+		return err
+	}
+	page.Posts = posts
+	if meta != nil {
+		page.Metadata = *meta
+		if meta.PrevCursor != "" {
+			page.PrevURL = "?cursor=" + url.QueryEscape(meta.PrevCursor)
+		}
+		if meta.NextCursor != "" {
+			page.NextURL = "?cursor=" + url.QueryEscape(meta.NextCursor)
+		}
+	}
+	return nil
+}
+
+// ListPage is a list page without its posts: the home page, or the page of the author or the tag when either
+// is set. Pages further down the list are left out of search engines, their addresses do not last.
+func (s *Service) ListPage(author, tag string, further bool) models.PostListPage {
+	page := models.PostListPage{
+		Heading: s.site.Title,
+		Author:  author,
+		Tag:     tag,
+		Meta: models.PageMeta{
+			Title:        s.site.Title,
+			Description:  s.site.Description,
+			CanonicalURL: s.baseURL + site.HomePath,
+			Type:         "website",
+			NoIndex:      further,
+			FeedURL:      s.baseURL + site.FeedPath(feed.FormatAtom),
+		},
+	}
+	switch {
+	case author != "":
+		page.Heading = "Posts by " + author
+		page.Meta.Title = author + " – " + s.site.Title
+		page.Meta.Description = page.Heading
+		page.Meta.CanonicalURL = s.baseURL + site.AuthorPath(author)
+		page.Meta.FeedURL = s.baseURL + site.AuthorFeedPath(author, feed.FormatAtom)
+	case tag != "":
+		page.Heading = "Posts tagged " + tag
+		page.Meta.Title = "#" + tag + " – " + s.site.Title
+		page.Meta.Description = page.Heading
+		page.Meta.CanonicalURL = s.baseURL + site.TagPath(tag)
+		page.Meta.FeedURL = s.baseURL + site.TagFeedPath(tag, feed.FormatAtom)
+	}
+	return page
+}
+
+// Post is the page of a published post. When the slug is an old one of the post, moved is true and the page
+// carries the post with its current slug.
+func (s *Service) Post(ctx context.Context, username, slug string) (page models.PostPage, moved bool, err error) {
+	post, moved, err := s.posts.GetPostBySlug(username, slug)
+	if err != nil {
+		return page, false, err
+	}
+	if post.DeletedAt != nil || post.IsDraft() || post.PublishedAt.After(time.Now()) {
+		return page, false, mongo.ErrNoDocuments
+	}
+	return s.PostPage(post), moved, nil
+}
+
+// PostPage is the page showing the post, its SEO fields take precedence over what is derived from the post.
+func (s *Service) PostPage(post repoModels.Post) models.PostPage {
+	meta := models.PageMeta{
+		Title:        post.Title + " – " + s.site.Title,
+		Description:  post.SEO.Description,
+		CanonicalURL: post.SEO.CanonicalURL,
+		Image:        post.SEO.Image,
+		Type:         "article",
+		NoIndex:      post.SEO.NoIndex,
+		FeedURL:      s.baseURL + site.AuthorFeedPath(post.Author.Username, feed.FormatAtom),
+		Published:    post.PublishedAt,
+	}
+	if meta.Description == "" {
+		meta.Description = post.Excerpt
+	}
+	if meta.CanonicalURL == "" {
+		meta.CanonicalURL = s.baseURL + site.PostPath(post.Author.Username, post.Slug)
+	}
+	if strings.HasPrefix(meta.Image, "/") {
+		meta.Image = s.baseURL + meta.Image
+	}
+	return models.PostPage{Meta: meta, Post: post}
+}
+
+// Search is the search page, it only searches once a query is given. Result pages are left out of search
+// engines.
+func (s *Service) Search(ctx context.Context, req models.SearchReq) (models.SearchPage, error) {
+	page := models.SearchPage{
+		Query: req,
+		Meta: models.PageMeta{
+			Title:        "Search – " + s.site.Title,
+			Description:  s.site.Description,
+			CanonicalURL: s.baseURL + site.SearchPath,
+			Type:         "website",
+			NoIndex:      true,
+			FeedURL:      s.baseURL + site.FeedPath(feed.FormatAtom),
+		},
+	}
+	req.Q = strings.TrimSpace(req.Q)
+	if req.Q == "" && len(req.Tags) == 0 && req.Author == "" {
+		return page, nil
+	}
+	req.Limit = s.pageSize
+	res, err := s.search.Search(ctx, req)
+	if err != nil {
+		return page, err
+	}
+	if req.Q != "" {
+		page.Meta.Title = req.Q + " – " + page.Meta.Title
+	}
+	page.Result = res
+	if res.Page > 1 {
+		page.PrevPage = res.Page - 1
+	}
+	if int64(res.Page*res.Limit) < res.Total {
+		page.NextPage = res.Page + 1
+	}
+	return page, nil
+}
+
+// Error is the page telling the reader that a page could not be shown with status.
+func (s *Service) Error(status int) models.ErrorPage {
+	message := "Something went wrong on our side, please try again later."
+	switch status {
+	case http.StatusNotFound:
+		message = "There is nothing here, the page may have moved or never existed."
+	case http.StatusBadRequest:
+		message = "The address of this page is not valid."
+	}
+	return models.ErrorPage{
+		Status:  status,
+		Message: message,
+		Meta: models.PageMeta{
+			Title:   http.StatusText(status) + " – " + s.site.Title,
+			Type:    "website",
+			NoIndex: true,
+		},
+	}
+}
@@ -0,0 +1,75 @@
+// Package feed writes syndication feeds for feed readers: RSS 2.0, Atom 1.0 and JSON Feed 1.1.
+package feed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats, named after the file extension of the feed.
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// ContentTypes maps the formats to the content type they are served with.
+var ContentTypes = map[string]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// Feed is a list of entries, newest first. Link is the page the feed belongs to, FeedURL the address the feed
+// itself is served at. Updated is the latest update of an item.
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	FeedURL     string
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item is an entry of a feed. ID is a stable URL identifying it, Link its page. ContentHTML is left empty when
+// the feed only carries the Summary, which is plain text.
+type Item struct {
+	ID          string
+	Title       string
+	Link        string
+	Author      string
+	AuthorLink  string
+	Summary     string
+	ContentHTML string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// Write writes the feed in the format.
+func Write(w io.Writer, f Feed, format string) error {
+	switch format {
+	case FormatRSS:
+		return writeRSS(w, f)
+	case FormatAtom:
+		return writeAtom(w, f)
+	case FormatJSON:
+		return writeJSON(w, f)
+	}
+	return fmt.Errorf("feed: unknown format %q", format)
+}
+
+// ETag identifies a version of the feed in a format, it changes whenever an item is added, removed or updated.
+// variant tells apart feeds of the same items written differently, such as with or without content.
+func (f Feed) ETag(format, variant string) string {
+	h := sha256.New()
+	io.WriteString(h, format+"\n"+variant+"\n"+f.Title+"\n"+f.Description+"\n")
+	for _, item := range f.Items {
+		io.WriteString(h, item.ID+"\n"+strconv.FormatInt(item.Updated.UnixNano(), 10)+"\n")
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
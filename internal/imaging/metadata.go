@@ -0,0 +1,188 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader   = []byte("Exif\x00\x00")
+
+	errMalformed = errors.New("imaging: malformed image")
+)
+
+// JPEG markers.
+const (
+	markerSOI  = 0xd8
+	markerSOS  = 0xda
+	markerAPP1 = 0xe1
+	markerAPPD = 0xed
+	markerCOM  = 0xfe
+)
+
+// Orientation returns the EXIF orientation of a JPEG image, 1 (upright) when it has none.
+func Orientation(data []byte) int {
+	orientation := 1
+	_, _ = eachJPEGSegment(data, func(marker byte, segment []byte) bool {
+		if marker != markerAPP1 || !bytes.HasPrefix(segment[4:], exifHeader) {
+			return true
+		}
+		if o, ok := tiffOrientation(segment[4+len(exifHeader):]); ok {
+			orientation = o
+		}
+		return false
+	})
+	return orientation
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of the TIFF structure EXIF data is stored in.
+func tiffOrientation(tiff []byte) (int, bool) {
+	if len(tiff) < 8 {
+		return 0, false
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			o := int(order.Uint16(tiff[entry+8:]))
+			return o, o >= 1 && o <= 8
+		}
+	}
+	return 0, false
+}
+
+// StripMetadata removes EXIF, XMP, IPTC and text metadata from a JPEG, PNG or WebP image without touching
+// the pixels, color profiles are kept. Other content is returned as it is.
+func StripMetadata(contentType string, data []byte) ([]byte, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+	return data, nil
+}
+
+// eachJPEGSegment calls fn with the marker and the bytes of every segment before the image data, starting at
+// the 0xff of the marker. It stops when fn returns false and returns the offset of the start of scan segment.
+func eachJPEGSegment(data []byte, fn func(marker byte, segment []byte) bool) (int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != markerSOI {
+		return 0, errMalformed
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return 0, errMalformed
+		}
+		marker := data[i+1]
+		if marker == 0xff {
+			// Fill byte before a marker.
+			i++
+			continue
+		}
+		if marker == markerSOS {
+			return i, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return 0, errMalformed
+		}
+		if !fn(marker, data[i:end]) {
+			return i, nil
+		}
+		i = end
+	}
+	return 0, errMalformed
+}
+
+// stripJPEG drops the EXIF and XMP (APP1), IPTC (APP13) and comment segments.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := append(make([]byte, 0, len(data)), data[:2]...)
+	sos, err := eachJPEGSegment(data, func(marker byte, segment []byte) bool {
+		if marker != markerAPP1 && marker != markerAPPD && marker != markerCOM {
+			out = append(out, segment...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[sos:]...), nil
+}
+
+// stripPNG drops the eXIf, text and modification time chunks.
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errMalformed
+	}
+	out := append(make([]byte, 0, len(data)), pngSignature...)
+	for i := len(pngSignature); i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// stripWebP drops the EXIF and XMP chunks and clears their flags in the extended header.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errMalformed
+	}
+	out := append(make([]byte, 0, len(data)), data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return nil, errMalformed
+		}
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		end := i + 8 + size + size%2
+		if end > len(data) || end < i {
+			return nil, errMalformed
+		}
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= 0x08 | 0x04
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
@@ -0,0 +1,215 @@
+package importer
+
+import (
+	"blog-platform/internal/render"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ghostExport is the JSON file Ghost exports its database to, older versions put the data at the top level.
+type ghostExport struct {
+	DB []struct {
+		Data ghostData `json:"data"`
+	} `json:"db"`
+	Data *ghostData `json:"data"`
+}
+
+type ghostData struct {
+	Posts []struct {
+		ID              ghostID   `json:"id"`
+		Title           string    `json:"title"`
+		Slug            string    `json:"slug"`
+		HTML            string    `json:"html"`
+		Plaintext       string    `json:"plaintext"`
+		CustomExcerpt   string    `json:"custom_excerpt"`
+		Status          string    `json:"status"`
+		Type            string    `json:"type"`
+		Page            ghostFlag `json:"page"`
+		AuthorID        ghostID   `json:"author_id"`
+		MetaDescription string    `json:"meta_description"`
+		CanonicalURL    string    `json:"canonical_url"`
+		CreatedAt       string    `json:"created_at"`
+		UpdatedAt       string    `json:"updated_at"`
+		PublishedAt     string    `json:"published_at"`
+	} `json:"posts"`
+	PostsMeta []struct {
+		PostID          ghostID `json:"post_id"`
+		MetaDescription string  `json:"meta_description"`
+	} `json:"posts_meta"`
+	Users []struct {
+		ID    ghostID `json:"id"`
+		Name  string  `json:"name"`
+		Slug  string  `json:"slug"`
+		Email string  `json:"email"`
+	} `json:"users"`
+	Tags []struct {
+		ID         ghostID `json:"id"`
+		Name       string  `json:"name"`
+		Visibility string  `json:"visibility"`
+	} `json:"tags"`
+	PostsTags    []ghostLink `json:"posts_tags"`
+	PostsAuthors []struct {
+		PostID    ghostID `json:"post_id"`
+		AuthorID  ghostID `json:"author_id"`
+		SortOrder int     `json:"sort_order"`
+	} `json:"posts_authors"`
+}
+
+type ghostLink struct {
+	PostID    ghostID `json:"post_id"`
+	TagID     ghostID `json:"tag_id"`
+	SortOrder int     `json:"sort_order"`
+}
+
+// ghostID is the ID of a record, a string in recent exports and a number in old ones.
+type ghostID string
+
+func (id *ghostID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = ghostID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("ghost ID %s: %w", data, err)
+	}
+	*id = ghostID(n.String())
+	return nil
+}
+
+// ghostFlag is a boolean column, which old exports write as 0 or 1.
+type ghostFlag bool
+
+func (f *ghostFlag) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*f = true
+	case "false", "0", "null":
+		*f = false
+	default:
+		return fmt.Errorf("ghost flag %s is not a boolean", data)
+	}
+	return nil
+}
+
+// ReadGhost reads a Ghost export. Published posts, including those sent as newsletters, and scheduled posts are
+// published at their date, drafts stay drafts. Posts with several authors are imported for the primary one.
+// Internal tags, whose names start with #, are left out.
+func ReadGhost(path string) (Export, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Export{}, err
+	}
+	var file ghostExport
+	if err = json.Unmarshal(raw, &file); err != nil {
+		return Export{}, fmt.Errorf("reading Ghost export: %w", err)
+	}
+	var data ghostData
+	switch {
+	case len(file.DB) > 0:
+		data = file.DB[0].Data
+	case file.Data != nil:
+		data = *file.Data
+	default:
+		return Export{}, fmt.Errorf("reading Ghost export: no data")
+	}
+
+	export := Export{Source: FormatGhost}
+	authorKeys := map[ghostID]string{}
+	for _, u := range data.Users {
+		authorKeys[u.ID] = u.Slug
+		export.Authors = append(export.Authors, Author{Key: u.Slug, Name: u.Name, Email: u.Email})
+	}
+	primaryAuthors := map[ghostID]ghostID{}
+	primaryOrder := map[ghostID]int{}
+	for _, pa := range data.PostsAuthors {
+		if order, ok := primaryOrder[pa.PostID]; !ok || pa.SortOrder < order {
+			primaryAuthors[pa.PostID], primaryOrder[pa.PostID] = pa.AuthorID, pa.SortOrder
+		}
+	}
+	tagNames := map[ghostID]string{}
+	for _, t := range data.Tags {
+		if t.Visibility != "internal" && !strings.HasPrefix(t.Name, "#") {
+			tagNames[t.ID] = t.Name
+		}
+	}
+	postTags := map[ghostID][]ghostLink{}
+	for _, pt := range data.PostsTags {
+		postTags[pt.PostID] = append(postTags[pt.PostID], pt)
+	}
+	descriptions := map[ghostID]string{}
+	for _, meta := range data.PostsMeta {
+		descriptions[meta.PostID] = meta.MetaDescription
+	}
+
+	for _, p := range data.Posts {
+		if p.Page || (p.Type != "" && p.Type != "post") {
+			export.Skipped = append(export.Skipped, Skipped{ID: string(p.ID), Title: p.Title, Reason: "page is not a post"})
+			continue
+		}
+		post := Post{
+			ID:            string(p.ID),
+			Title:         p.Title,
+			Slug:          p.Slug,
+			Content:       p.HTML,
+			ContentFormat: render.FormatHTML,
+			Excerpt:       p.CustomExcerpt,
+			Description:   firstString(descriptions[p.ID], p.MetaDescription),
+			CanonicalURL:  p.CanonicalURL,
+		}
+		if post.Content == "" && p.Plaintext != "" {
+			post.Content, post.ContentFormat = p.Plaintext, render.FormatPlain
+		}
+
+		author := primaryAuthors[p.ID]
+		if author == "" {
+			author = p.AuthorID
+		}
+		post.Author = authorKeys[author]
+
+		links := postTags[p.ID]
+		sort.SliceStable(links, func(i, j int) bool { return links[i].SortOrder < links[j].SortOrder })
+		for _, link := range links {
+			if name, ok := tagNames[link.TagID]; ok {
+				post.Tags = append(post.Tags, name)
+			}
+		}
+
+		switch p.Status {
+		case "published", "sent", "scheduled":
+			post.PublishedAt = ghostDate(p.PublishedAt)
+		case "draft":
+			post.Draft = true
+		default:
+			export.Skipped = append(export.Skipped, Skipped{ID: post.ID, Title: post.Title, Reason: "status " + p.Status})
+			continue
+		}
+		post.CreatedAt = firstTime(ghostDate(p.CreatedAt), post.PublishedAt)
+		post.UpdatedAt = firstTime(ghostDate(p.UpdatedAt), post.CreatedAt)
+		if !post.Draft && post.PublishedAt.IsZero() {
+			post.PublishedAt = post.CreatedAt
+		}
+		export.Posts = append(export.Posts, post)
+	}
+	return export, nil
+}
+
+// ghostDate parses a date of a Ghost export, recent versions write RFC 3339 and older ones SQL dates in UTC.
+func ghostDate(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
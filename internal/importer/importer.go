@@ -0,0 +1,95 @@
+// Package importer reads the posts and authors of other blogging platforms from their exports: WordPress WXR
+// files, Ghost JSON exports and directories of Markdown files with YAML frontmatter.
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Formats of the exports that can be read.
+const (
+	FormatWordPress = "wordpress"
+	FormatGhost     = "ghost"
+	FormatMarkdown  = "markdown"
+)
+
+// Export is what was read from an export. Source names the blog it comes from, together with the IDs of the
+// posts it identifies them across imports. Skipped lists the entries that are not posts, such as pages.
+type Export struct {
+	Source  string
+	Authors []Author
+	Posts   []Post
+	Skipped []Skipped
+}
+
+// Author is an author of the blog, Key is how the posts refer to them: the login on WordPress, the slug on
+// Ghost and the author field of the frontmatter.
+type Author struct {
+	Key   string
+	Name  string
+	Email string
+}
+
+// Post is a post as the blog had it. Content is written in ContentFormat, a render format. Posts are published
+// at PublishedAt, which may be in the future, unless they are drafts. Excerpt is only set when the author wrote
+// one, Description and CanonicalURL are its SEO fields.
+type Post struct {
+	ID            string
+	Title         string
+	Slug          string
+	Content       string
+	ContentFormat string
+	Excerpt       string
+	Tags          []string
+	Author        string
+	Draft         bool
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	PublishedAt   time.Time
+	Description   string
+	CanonicalURL  string
+}
+
+// Hash fingerprints everything read of the post, exports do not always date the edits of a post.
+func (p Post) Hash() string {
+	data, err := json.Marshal(p)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Skipped is an entry of the export that is not imported, with the reason.
+type Skipped struct {
+	ID     string
+	Title  string
+	Reason string
+}
+
+// Read reads the export at path, a file for WordPress and Ghost and a directory for Markdown.
+func Read(format, path string) (Export, error) {
+	switch format {
+	case FormatWordPress:
+		return ReadWXR(path)
+	case FormatGhost:
+		return ReadGhost(path)
+	case FormatMarkdown:
+		return ReadMarkdown(path)
+	}
+	return Export{}, fmt.Errorf("unknown import format %q, formats are wordpress, ghost and markdown", format)
+}
+
+// firstTime returns the first of the times that is set.
+func firstTime(times ...time.Time) time.Time {
+	for _, t := range times {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"blog-platform/internal/render"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// frontmatter holds the fields the common static site generators (Jekyll, Hugo, Eleventy) put in front of the
+// Markdown of a post.
+type frontmatter struct {
+	ID           string     `yaml:"id"`
+	Title        string     `yaml:"title"`
+	Slug         string     `yaml:"slug"`
+	Author       string     `yaml:"author"`
+	Date         time.Time  `yaml:"date"`
+	Updated      time.Time  `yaml:"updated"`
+	LastMod      time.Time  `yaml:"lastmod"`
+	Draft        bool       `yaml:"draft"`
+	Published    *bool      `yaml:"published"`
+	Tags         stringList `yaml:"tags"`
+	Categories   stringList `yaml:"categories"`
+	Excerpt      string     `yaml:"excerpt"`
+	Summary      string     `yaml:"summary"`
+	Description  string     `yaml:"description"`
+	CanonicalURL string     `yaml:"canonical_url"`
+}
+
+// stringList is a list in the frontmatter, which may also be given as a single comma separated string.
+type stringList []string
+
+func (l *stringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = nil
+		for _, s := range strings.Split(value.Value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				*l = append(*l, s)
+			}
+		}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// datedName matches the names of Jekyll posts, which start with their date.
+var datedName = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// ReadMarkdown reads the .md and .markdown files below dir, each is a post starting with YAML frontmatter
+// between --- lines. The path of the file identifies the post unless the frontmatter has an id. Without a slug
+// the name of the file is used, without a date the date a Jekyll file name starts with, and the title falls
+// back to the name as well. Posts are drafts when draft is true or published is false. Files that can not be
+// read as posts are skipped.
+func ReadMarkdown(dir string) (Export, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && name != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if ext := strings.ToLower(filepath.Ext(name)); !d.IsDir() && (ext == ".md" || ext == ".markdown") {
+			names = append(names, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return Export{}, err
+	}
+	sort.Strings(names)
+
+	export := Export{Source: FormatMarkdown}
+	authors := map[string]bool{}
+	for _, name := range names {
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return Export{}, err
+		}
+		rel = filepath.ToSlash(rel)
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			return Export{}, err
+		}
+		post, err := markdownPost(rel, raw)
+		if err != nil {
+			export.Skipped = append(export.Skipped, Skipped{ID: rel, Reason: err.Error()})
+			continue
+		}
+		if post.Author != "" && !authors[post.Author] {
+			authors[post.Author] = true
+			export.Authors = append(export.Authors, Author{Key: post.Author})
+		}
+		export.Posts = append(export.Posts, post)
+	}
+	return export, nil
+}
+
+func markdownPost(rel string, raw []byte) (Post, error) {
+	raw = bytes.TrimPrefix(bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n")), []byte("\ufeff"))
+	var meta frontmatter
+	content := raw
+	if rest, ok := bytes.CutPrefix(raw, []byte("---\n")); ok {
+		header, body, found := bytes.Cut(rest, []byte("\n---\n"))
+		if empty, ok := bytes.CutPrefix(rest, []byte("---\n")); ok {
+			header, body, found = nil, empty, true
+		}
+		if !found {
+			header, found = bytes.CutSuffix(rest, []byte("\n---"))
+		}
+		if !found {
+			return Post{}, fmt.Errorf("frontmatter is not closed by a --- line")
+		}
+		if err := yaml.Unmarshal(header, &meta); err != nil {
+			return Post{}, fmt.Errorf("frontmatter: %w", err)
+		}
+		content = body
+	}
+
+	name := strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+	if name == "index" && path.Dir(rel) != "." {
+		// Page bundles keep the post in the index.md of a directory named like it.
+		name = path.Base(path.Dir(rel))
+	}
+	var nameDate time.Time
+	if m := datedName.FindStringSubmatch(name); m != nil {
+		nameDate, _ = time.Parse("2006-01-02", m[1])
+		name = m[2]
+	}
+
+	post := Post{
+		ID:            firstString(meta.ID, rel),
+		Title:         firstString(meta.Title, name),
+		Slug:          firstString(meta.Slug, name),
+		Content:       strings.TrimSpace(string(content)),
+		ContentFormat: render.FormatMarkdown,
+		Excerpt:       firstString(meta.Excerpt, meta.Summary),
+		Tags:          append(meta.Tags, meta.Categories...),
+		Author:        strings.TrimSpace(meta.Author),
+		Draft:         meta.Draft || (meta.Published != nil && !*meta.Published),
+		Description:   meta.Description,
+		CanonicalURL:  meta.CanonicalURL,
+	}
+	post.CreatedAt = firstTime(meta.Date, nameDate).UTC()
+	post.UpdatedAt = firstTime(meta.Updated, meta.LastMod, post.CreatedAt).UTC()
+	if post.CreatedAt.IsZero() {
+		return Post{}, fmt.Errorf("no date in the frontmatter or the file name")
+	}
+	if !post.Draft {
+		post.PublishedAt = post.CreatedAt
+	}
+	return post, nil
+}
@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"blog-platform/internal/render"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// wxrDocument is a WordPress eXtended RSS file. The elements are matched by their local names, the namespaces
+// carry the version of the format. Content and excerpt are both "encoded" elements, told apart by namespace.
+type wxrDocument struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		BaseBlogURL string `xml:"base_blog_url"`
+		Authors     []struct {
+			Login       string `xml:"author_login"`
+			Email       string `xml:"author_email"`
+			DisplayName string `xml:"author_display_name"`
+		} `xml:"author"`
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title   string `xml:"title"`
+	Creator string `xml:"creator"`
+	Encoded []struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	} `xml:"encoded"`
+	PostID          string `xml:"post_id"`
+	PostDate        string `xml:"post_date"`
+	PostDateGMT     string `xml:"post_date_gmt"`
+	PostModified    string `xml:"post_modified"`
+	PostModifiedGMT string `xml:"post_modified_gmt"`
+	PostName        string `xml:"post_name"`
+	Status          string `xml:"status"`
+	PostType        string `xml:"post_type"`
+	Categories      []struct {
+		Domain string `xml:"domain,attr"`
+		Name   string `xml:",chardata"`
+	} `xml:"category"`
+	Meta []struct {
+		Key   string `xml:"meta_key"`
+		Value string `xml:"meta_value"`
+	} `xml:"postmeta"`
+}
+
+// wxrDateLayout is the layout of the dates in WXR files, unset dates are all zeros.
+const wxrDateLayout = "2006-01-02 15:04:05"
+
+// ReadWXR reads a WordPress export. The posts keep their status: published and scheduled posts are published
+// at their date, drafts, pending and private posts become drafts and trashed posts are skipped. Tags and
+// categories, other than Uncategorized, both become tags, and Yoast meta descriptions and canonical URLs are
+// taken over.
+func ReadWXR(path string) (Export, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Export{}, err
+	}
+	defer f.Close()
+
+	var doc wxrDocument
+	dec := xml.NewDecoder(f)
+	// WXR files are written by PHP, which does not always declare the encoding it uses in the XML header.
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	dec.Strict = false
+	if err = dec.Decode(&doc); err != nil {
+		return Export{}, fmt.Errorf("reading WXR: %w", err)
+	}
+
+	ch := doc.Channel
+	export := Export{Source: FormatWordPress + ":" + strings.TrimRight(firstString(ch.BaseBlogURL, ch.Link), "/")}
+	for _, a := range ch.Authors {
+		export.Authors = append(export.Authors, Author{Key: a.Login, Name: a.DisplayName, Email: a.Email})
+	}
+	for _, item := range ch.Items {
+		post, reason := item.post()
+		if reason != "" {
+			if item.PostType != "attachment" && item.PostType != "nav_menu_item" {
+				export.Skipped = append(export.Skipped, Skipped{ID: item.PostID, Title: item.Title, Reason: reason})
+			}
+			continue
+		}
+		export.Posts = append(export.Posts, post)
+	}
+	return export, nil
+}
+
+func (item wxrItem) post() (Post, string) {
+	if item.PostType != "post" {
+		return Post{}, item.PostType + " is not a post"
+	}
+	post := Post{
+		ID:            item.PostID,
+		Title:         item.Title,
+		Author:        item.Creator,
+		ContentFormat: render.FormatHTML,
+	}
+	switch item.Status {
+	case "publish", "future":
+	case "draft", "pending", "private":
+		post.Draft = true
+	default:
+		return Post{}, "status " + item.Status
+	}
+	post.Slug, _ = url.PathUnescape(item.PostName)
+
+	for _, encoded := range item.Encoded {
+		switch {
+		case strings.Contains(encoded.XMLName.Space, "excerpt"):
+			post.Excerpt = strings.TrimSpace(encoded.Value)
+		case strings.Contains(encoded.XMLName.Space, "content"):
+			post.Content = autoParagraphs(encoded.Value)
+		}
+	}
+	for _, c := range item.Categories {
+		if (c.Domain == "post_tag" || c.Domain == "category") && !strings.EqualFold(c.Name, "Uncategorized") {
+			post.Tags = append(post.Tags, c.Name)
+		}
+	}
+	for _, meta := range item.Meta {
+		switch meta.Key {
+		case "_yoast_wpseo_metadesc":
+			post.Description = meta.Value
+		case "_yoast_wpseo_canonical":
+			post.CanonicalURL = meta.Value
+		}
+	}
+
+	published := firstTime(wxrDate(item.PostDateGMT), wxrDate(item.PostDate))
+	post.UpdatedAt = firstTime(wxrDate(item.PostModifiedGMT), wxrDate(item.PostModified), published)
+	post.CreatedAt = firstTime(published, post.UpdatedAt)
+	if !post.Draft {
+		post.PublishedAt = published
+	}
+	return post, ""
+}
+
+// wxrDate parses a date of a WXR file, which are given without zone. The GMT ones are UTC, the others are taken
+// to be as well, as the time zone of the blog is not part of the export.
+func wxrDate(s string) time.Time {
+	t, err := time.Parse(wxrDateLayout, strings.TrimSpace(s))
+	if err != nil || t.Year() < 1970 {
+		return time.Time{}
+	}
+	return t
+}
+
+var (
+	wxrParagraphBreak = regexp.MustCompile(`\n\s*\n`)
+	// wxrBlock matches the start of chunks that are HTML blocks or comments, which are not wrapped in paragraphs.
+	wxrBlock = regexp.MustCompile(`(?i)^<(!--|/?(p|div|h[1-6]|ul|ol|li|dl|blockquote|pre|figure|table|hr|section|iframe|form|address|details)\b)`)
+)
+
+// autoParagraphs adds the paragraphs WordPress leaves out of the stored content of classic posts, where blank
+// lines separate them and single newlines break lines. Block editor content already has them, and so does the
+// preformatted text, which is kept as it is.
+func autoParagraphs(content string) string {
+	content = strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	if strings.Contains(content, "<!-- wp:") {
+		return content
+	}
+	chunks := wxrParagraphBreak.Split(content, -1)
+	inPre := false
+	for i, chunk := range chunks {
+		trimmed := strings.TrimSpace(chunk)
+		if !inPre && trimmed != "" && !wxrBlock.MatchString(trimmed) {
+			chunks[i] = "<p>" + strings.ReplaceAll(trimmed, "\n", "<br>\n") + "</p>"
+		}
+		lower := strings.ToLower(chunk)
+		if open, closed := strings.LastIndex(lower, "<pre"), strings.LastIndex(lower, "</pre>"); open > closed {
+			inPre = true
+		} else if closed >= 0 {
+			inPre = false
+		}
+	}
+	return strings.Join(chunks, "\n\n")
+}
+
+func firstString(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
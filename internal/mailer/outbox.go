@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryOutbox keeps sent messages in memory, meant for tests and local development.
+type MemoryOutbox struct {
+	from     string
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryOutbox(from string) *MemoryOutbox {
+	return &MemoryOutbox{from: from}
+}
+
+func (o *MemoryOutbox) Send(_ context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = o.from
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages returns a copy of every message sent so far.
+func (o *MemoryOutbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
+
+// FileOutbox writes every message as an .eml file into a directory instead of delivering it.
+type FileOutbox struct {
+	dir  string
+	from string
+}
+
+func NewFileOutbox(dir, from string) *FileOutbox {
+	return &FileOutbox{dir: dir, from: from}
+}
+
+func (o *FileOutbox) Send(_ context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = o.from
+	}
+	if err := os.MkdirAll(o.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(o.dir, name), msg.Bytes(), 0o600)
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '@':
+			return r
+		}
+		return '_'
+	}, s)
+}
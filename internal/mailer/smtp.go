@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+
+	"blog-platform/config"
+)
+
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(cfg config.MailConfig) *SMTP {
+	s := &SMTP{
+		addr: net.JoinHostPort(cfg.SMTP.Host, strconv.Itoa(cfg.SMTP.Port)),
+		from: cfg.From,
+	}
+	if cfg.SMTP.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Host)
+	}
+	return s
+}
+
+func (s *SMTP) Send(_ context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	return smtp.SendMail(s.addr, s.auth, from.Address, []string{to.Address}, msg.Bytes())
+}
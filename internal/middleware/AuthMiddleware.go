@@ -1,9 +1,21 @@
 package middleware
 
 import (
+	"blog-platform/config"
+	apiKeyRepo "blog-platform/internal/app/repositories/apikey"
 	repoModels "blog-platform/internal/app/repositories/models"
+	sessionRepo "blog-platform/internal/app/repositories/session"
+	"blog-platform/internal/app/repositories/settings"
+	userRepo "blog-platform/internal/app/repositories/user"
+	srvAPIKey "blog-platform/internal/app/service/apikey"
+	srvSession "blog-platform/internal/app/service/session"
+	srvTwoFactor "blog-platform/internal/app/service/twofactor"
 	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,10 +23,41 @@ import (
 	_ "go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func AuthMiddleware(db *mongo.Database) gin.HandlerFunc {
+const (
+	// PasswordChangePath is the only route a user flagged with must_change_password may call.
+	PasswordChangePath = "/api/v1/user/password"
+	// TwoFactorPathPrefix covers the enrollment routes, reachable by users whose role requires two-factor
+	// authentication but who did not enroll yet.
+	TwoFactorPathPrefix = "/api/v1/user/2fa"
+	// OTPHeader carries the TOTP or recovery code of users with two-factor authentication enabled.
+	OTPHeader = "X-OTP"
+	// APIKeyHeader is an alternative to sending an API key as bearer token.
+	APIKeyHeader = "X-API-Key"
+	// CSRFHeader carries the CSRF token of the session on state changing requests authenticated by cookie.
+	CSRFHeader = "X-CSRF-Token"
+	// SessionIDKey holds the id of the session that authenticated the request, it is not set for other credentials.
+	SessionIDKey = "SessionID"
+)
+
+// AuthMiddleware accepts a personal API key, Basic-auth credentials followed by the second factor when
+// enrolled, or a session cookie. Requests authenticated by API key carry the key's scopes, see RequireScope.
+func AuthMiddleware(db *mongo.Database, sessionCfg config.SessionConfig) gin.HandlerFunc {
+	twoFactor := srvTwoFactor.New(userRepo.New(db), settings.New(db), sessionRepo.New(db))
+	apiKeys := srvAPIKey.New(apiKeyRepo.New(db), userRepo.New(db))
+	sessions := srvSession.New(sessionRepo.New(db), userRepo.New(db), twoFactor, sessionCfg)
+
 	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, apiKeys, key)
+			return
+		}
+
 		username, password, hasAuth := c.Request.BasicAuth()
 		if !hasAuth {
+			if token, err := c.Cookie(sessionCfg.CookieName); err == nil && token != "" {
+				authenticateSession(c, sessions, twoFactor, token)
+				return
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
 			return
@@ -24,16 +67,172 @@ func AuthMiddleware(db *mongo.Database) gin.HandlerFunc {
 		var user repoModels.User
 		filter := bson.M{"username": username}
 		err := db.Collection("users").FindOne(context.Background(), filter).Decode(&user)
-		if err != nil || user.Password != password {
+		// single sign-on users have no password and can not use Basic auth
+		if err != nil || user.Password == "" || user.Password != password || user.DeletedAt != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			c.Abort()
 			return
 		}
 
+		if !user.CanAuthenticate(time.Now()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account is " + user.Status})
+			c.Abort()
+			return
+		}
+
+		if user.HasTwoFactor() && !checkSecondFactor(c, twoFactor, user) {
+			c.Abort()
+			return
+		}
+
+		if !checkAccountState(c, twoFactor, user) {
+			c.Abort()
+			return
+		}
+
 		// User authenticated
-		c.Set("Username", user.Username)
-		c.Set("ID", user.ID.Hex())
-		c.Set("Role", user.Role)
+		setUser(c, user)
 		c.Next()
 	}
 }
+
+// authenticateSession skips the second factor, it was checked when the session started. Browsers send the
+// cookie on cross-site requests too, so state changing requests also need the session's CSRF token.
+func authenticateSession(c *gin.Context, sessions *srvSession.Service, twoFactor *srvTwoFactor.Service, token string) {
+	user, session, err := sessions.Authenticate(token)
+	if errors.Is(err, srvSession.ErrInvalidSession) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		log.Printf("authenticating session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify session"})
+		c.Abort()
+		return
+	}
+
+	if user.DeletedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	if !user.CanAuthenticate(time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is " + user.Status})
+		c.Abort()
+		return
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		if !sessions.CheckCSRF(session, c.GetHeader(CSRFHeader)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "missing or invalid CSRF token"})
+			c.Abort()
+			return
+		}
+	}
+
+	if !checkAccountState(c, twoFactor, user) {
+		c.Abort()
+		return
+	}
+
+	setUser(c, user)
+	c.Set(SessionIDKey, session.ID.Hex())
+	c.Next()
+}
+
+func setUser(c *gin.Context, user repoModels.User) {
+	c.Set("Username", user.Username)
+	c.Set("ID", user.ID.Hex())
+	c.Set("Role", user.Role)
+}
+
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && srvAPIKey.IsAPIKey(token) {
+		return token
+	}
+	return ""
+}
+
+// authenticateAPIKey skips the second factor, keys are created by users who already passed it and are meant for
+// automation. A forced password change still applies: the keys stop working until the user changed it.
+func authenticateAPIKey(c *gin.Context, apiKeys *srvAPIKey.Service, raw string) {
+	user, key, err := apiKeys.Authenticate(raw)
+	if errors.Is(err, srvAPIKey.ErrInvalidKey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		log.Printf("authenticating API key: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify API key"})
+		c.Abort()
+		return
+	}
+
+	if !user.CanAuthenticate(time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "account is " + user.Status})
+		c.Abort()
+		return
+	}
+	if !checkPasswordChange(c, user) {
+		c.Abort()
+		return
+	}
+
+	setUser(c, user)
+	c.Set(ScopesKey, key.Scopes)
+	c.Set("APIKeyID", key.ID.Hex())
+	c.Next()
+}
+
+// checkSecondFactor verifies the code sent along with the request, it writes the error response and returns
+// false when the code is missing or wrong.
+func checkSecondFactor(c *gin.Context, twoFactor *srvTwoFactor.Service, user repoModels.User) bool {
+	err := twoFactor.Verify(user, c.GetHeader(OTPHeader))
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, srvTwoFactor.ErrCodeRequired), errors.Is(err, srvTwoFactor.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		log.Printf("verifying two-factor code of user %s: %v", user.ID.Hex(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not verify two-factor code"})
+	}
+	return false
+}
+
+// checkAccountState keeps users who must enroll in two-factor authentication on the enrollment routes and
+// users who must change their password on the password route, it writes the error response and returns false
+// when the request can not continue.
+func checkAccountState(c *gin.Context, twoFactor *srvTwoFactor.Service, user repoModels.User) bool {
+	if !user.HasTwoFactor() {
+		required, err := twoFactor.EnrollmentRequired(user)
+		if err != nil {
+			log.Printf("loading security settings: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "could not load security settings"})
+			return false
+		}
+		if required && !strings.HasPrefix(c.FullPath(), TwoFactorPathPrefix) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor enrollment required"})
+			return false
+		}
+	}
+
+	return checkPasswordChange(c, user)
+}
+
+// checkPasswordChange keeps users who must change their password on the password route, it writes the error
+// response and returns false for any other route.
+func checkPasswordChange(c *gin.Context, user repoModels.User) bool {
+	if user.MustChangePassword && c.FullPath() != PasswordChangePath {
+		c.JSON(http.StatusForbidden, gin.H{"error": "password change required"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScopesKey holds the scopes of the API key that authenticated the request, it is not set for other credentials.
+const ScopesKey = "Scopes"
+
+// RequireScope has to run after AuthMiddleware, requests authenticated by API key need the scope while
+// other credentials are not restricted.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isAPIKey := c.Get(ScopesKey)
+		if !isAPIKey {
+			c.Next()
+			return
+		}
+
+		for _, s := range scopes.([]string) {
+			if s == scope {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"err": "API key is missing scope " + scope})
+		c.Abort()
+	}
+}
+
+// RequireScopeByMethod requires read for safe methods and write for everything else.
+func RequireScopeByMethod(read, write string) gin.HandlerFunc {
+	readScope, writeScope := RequireScope(read), RequireScope(write)
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			readScope(c)
+		default:
+			writeScope(c)
+		}
+	}
+}
+
+// RejectAPIKeys keeps API keys away from account management, such as creating new keys.
+func RejectAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIKey := c.Get(ScopesKey); isAPIKey {
+			c.JSON(http.StatusForbidden, gin.H{"err": "not allowed with an API key"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
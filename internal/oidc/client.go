@@ -0,0 +1,259 @@
+// Package oidc is a small OpenID Connect relying party supporting the authorization code flow with PKCE
+// and RS256 signed ID tokens, which is what common identity providers issue by default.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Discovery is the subset of the provider metadata the client uses.
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the ID token claims the blog needs, Groups is read from the claim configured on the client.
+type Claims struct {
+	Issuer            string   `json:"iss"`
+	Subject           string   `json:"sub"`
+	Audience          Audience `json:"aud"`
+	Expiry            int64    `json:"exp"`
+	IssuedAt          int64    `json:"iat"`
+	Nonce             string   `json:"nonce"`
+	Email             string   `json:"email"`
+	EmailVerified     bool     `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	Name              string   `json:"name"`
+	Groups            []string `json:"-"`
+}
+
+// Audience accepts both the single string and the array form of the aud claim.
+type Audience []string
+
+func (a *Audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a Audience) Contains(aud string) bool {
+	for _, v := range a {
+		if v == aud {
+			return true
+		}
+	}
+	return false
+}
+
+type Client struct {
+	cfg         Config
+	groupsClaim string
+	http        *http.Client
+
+	mu        sync.Mutex
+	discovery *Discovery
+	keys      *keySet
+}
+
+// NewClient does not contact the provider, discovery happens on first use so the server can start while the
+// provider is unavailable.
+func NewClient(cfg Config, groupsClaim string, httpClient *http.Client) *Client {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	return &Client{cfg: cfg, groupsClaim: groupsClaim, http: httpClient}
+}
+
+func (c *Client) Discover(ctx context.Context) (*Discovery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	var d Discovery
+	wellKnown := strings.TrimRight(c.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, wellKnown, &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if d.Issuer != c.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match configured %q", d.Issuer, c.cfg.IssuerURL)
+	}
+
+	c.discovery = &d
+	c.keys = newKeySet(d.JWKSURI, c.getJSON)
+	return c.discovery, nil
+}
+
+// AuthCodeURL builds the URL the user is redirected to, verifier is the PKCE code verifier kept by the caller.
+// loginHint is optional and passed on to the provider.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier, loginHint string) (string, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", c.cfg.ClientID)
+	v.Set("redirect_uri", c.cfg.RedirectURL)
+	v.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", CodeChallenge(verifier))
+	v.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		v.Set("login_hint", loginHint)
+	}
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// Exchange redeems the authorization code and returns the verified claims of the ID token.
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (Claims, error) {
+	d, err := c.Discover(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", c.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Claims{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return Claims{}, err
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return Claims{}, fmt.Errorf("oidc token response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK || body.Error != "" {
+		return Claims{}, fmt.Errorf("oidc token exchange failed: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return Claims{}, errors.New("oidc token response has no id_token")
+	}
+
+	return c.Verify(ctx, body.IDToken, nonce)
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID token.
+func (c *Client) Verify(ctx context.Context, idToken, nonce string) (Claims, error) {
+	if _, err := c.Discover(ctx); err != nil {
+		return Claims{}, err
+	}
+
+	payload, err := c.keys.verify(ctx, idToken)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	var claims Claims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return Claims{}, fmt.Errorf("oidc id token claims: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err = json.Unmarshal(payload, &raw); err != nil {
+		return Claims{}, err
+	}
+	if groups, ok := raw[c.groupsClaim]; ok {
+		if err = json.Unmarshal(groups, &claims.Groups); err != nil {
+			return Claims{}, fmt.Errorf("oidc %s claim: %w", c.groupsClaim, err)
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case claims.Issuer != c.cfg.IssuerURL:
+		return Claims{}, errors.New("oidc id token has the wrong issuer")
+	case !claims.Audience.Contains(c.cfg.ClientID):
+		return Claims{}, errors.New("oidc id token has the wrong audience")
+	case now.After(time.Unix(claims.Expiry, 0).Add(time.Minute)):
+		return Claims{}, errors.New("oidc id token expired")
+	case claims.Subject == "":
+		return Claims{}, errors.New("oidc id token has no subject")
+	case claims.Nonce != nonce:
+		return Claims{}, errors.New("oidc id token nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"blog-platform/internal/oidc"
+	"blog-platform/internal/oidc/oidctest"
+)
+
+const redirectURL = "https://blog.example.com/api/v1/auth/oidc/callback"
+
+var alice = oidctest.User{Subject: "sub-alice", PreferredUsername: "alice", Email: "alice@example.com",
+	EmailVerified: true, Name: "Alice", Groups: []string{"blog-admins"}}
+
+// newProvider serves an oidctest provider with alice as its only user.
+func newProvider(t *testing.T) (*oidctest.Provider, *httptest.Server) {
+	t.Helper()
+	var handler http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := oidctest.New(server.URL, "blog", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.AddUser(alice)
+	handler = provider.Handler()
+	return provider, server
+}
+
+func newClient(server *httptest.Server, clientSecret string) *oidc.Client {
+	return oidc.NewClient(oidc.Config{
+		IssuerURL:    server.URL,
+		ClientID:     "blog",
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+	}, "", server.Client())
+}
+
+// authorize follows the authorization URL to the provider and returns the code and state it redirects back with.
+func authorize(t *testing.T, client *oidc.Client, server *httptest.Server, state, nonce, verifier string) (code, returnedState string) {
+	t.Helper()
+	authURL, err := client.AuthCodeURL(context.Background(), state, nonce, verifier, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	browser := server.Client()
+	browser.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	res, err := browser.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize answered %s", res.Status)
+	}
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(location.String(), redirectURL+"?") {
+		t.Fatalf("redirected to %s instead of the callback", location)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestExchangeVerifiesIDToken(t *testing.T) {
+	_, server := newProvider(t)
+	client := newClient(server, "secret")
+
+	code, state := authorize(t, client, server, "state-1", "nonce-1", "verifier-1")
+	if code == "" || state != "state-1" {
+		t.Fatalf("got code %q and state %q", code, state)
+	}
+	claims, err := client.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != server.URL || claims.Subject != alice.Subject || claims.Email != alice.Email || !claims.EmailVerified {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if claims.PreferredUsername != "alice" || len(claims.Groups) != 1 || claims.Groups[0] != "blog-admins" {
+		t.Errorf("unexpected profile claims %+v", claims)
+	}
+	if !claims.Audience.Contains("blog") {
+		t.Errorf("audience %v does not contain the client", claims.Audience)
+	}
+
+	if _, err = client.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("a code was redeemed twice")
+	}
+}
+
+func TestExchangeRequiresPKCEVerifier(t *testing.T) {
+	_, server := newProvider(t)
+	client := newClient(server, "secret")
+
+	code, _ := authorize(t, client, server, "state", "nonce", "verifier")
+	_, err := client.Exchange(context.Background(), code, "another-verifier", "nonce")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Fatalf("expected invalid_grant for a wrong verifier, got %v", err)
+	}
+}
+
+func TestExchangeRequiresClientSecret(t *testing.T) {
+	_, server := newProvider(t)
+	client := newClient(server, "wrong")
+
+	code, _ := authorize(t, client, server, "state", "nonce", "verifier")
+	_, err := client.Exchange(context.Background(), code, "verifier", "nonce")
+	if err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Fatalf("expected invalid_client for a wrong secret, got %v", err)
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	_, server := newProvider(t)
+	client := newClient(server, "secret")
+
+	code, _ := authorize(t, client, server, "state", "nonce", "verifier")
+	_, err := client.Exchange(context.Background(), code, "verifier", "another-nonce")
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("expected a nonce mismatch, got %v", err)
+	}
+}
+
+func TestVerifyChecksSignatureAgainstJWKS(t *testing.T) {
+	provider, server := newProvider(t)
+	client := newClient(server, "secret")
+
+	token, err := provider.SignIDToken(alice, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Verify(context.Background(), token, "nonce"); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	// same issuer and key id, but signed with another key than the one published in the JWKS
+	impostor, err := oidctest.New(server.URL, "blog", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := impostor.SignIDToken(alice, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.Verify(context.Background(), forged, "nonce"); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("expected an invalid signature for a token signed with another key, got %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload = []byte(strings.Replace(string(payload), alice.Subject, "sub-mallory", 1))
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+	if _, err = client.Verify(context.Background(), tampered, "nonce"); err == nil {
+		t.Error("a token with a changed payload was accepted")
+	}
+
+	unsigned := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0." + parts[1] + "."
+	if _, err = client.Verify(context.Background(), unsigned, "nonce"); err == nil || !strings.Contains(err.Error(), "algorithm") {
+		t.Errorf("expected alg none to be rejected, got %v", err)
+	}
+}
+
+func TestDiscoveryRequiresMatchingIssuer(t *testing.T) {
+	_, server := newProvider(t)
+	client := oidc.NewClient(oidc.Config{IssuerURL: server.URL + "/other", ClientID: "blog", RedirectURL: redirectURL}, "", server.Client())
+	if _, err := client.Discover(context.Background()); err == nil {
+		t.Error("discovery accepted a provider with another issuer")
+	}
+}
@@ -0,0 +1,174 @@
+// Package pagination pages through MongoDB listings by key instead of by offset. A page ends at the sort key of
+// its last document, the next page starts right after it, so pages neither slow down deeper in a collection nor
+// repeat documents inserted in between. Positions are handed to clients as opaque, signed cursor tokens.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"blog-platform/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrInvalidCursor = fmt.Errorf("%w: invalid cursor", utils.ErrBadRequest)
+
+// Key is a document path a listing is sorted by. The last key of a listing has to be unique, usually _id.
+type Key struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor is a position in a listing, Values holds the value of every sort key of the document at that position.
+// Before selects the page ending right before the position instead of the one starting after it.
+type Cursor struct {
+	Values []interface{}
+	Before bool
+}
+
+type payload struct {
+	Scope  string `bson:"s"`
+	Values bson.A `bson:"v"`
+	Before bool   `bson:"b,omitempty"`
+}
+
+// Codec signs cursors so clients can not forge positions or reuse them in a listing with another order.
+type Codec struct {
+	secret []byte
+}
+
+// NewCodec creates a codec signing with secret. Without a secret a random one is used, cursors then stop
+// working when the server restarts.
+func NewCodec(secret string) *Codec {
+	if secret == "" {
+		key := make([]byte, 32)
+		_, _ = rand.Read(key)
+		return &Codec{secret: key}
+	}
+	return &Codec{secret: []byte(secret)}
+}
+
+// Encode turns the cursor into a URL safe token bound to scope, it returns an empty token for a nil cursor.
+func (c *Codec) Encode(scope string, cursor *Cursor) (string, error) {
+	if cursor == nil {
+		return "", nil
+	}
+	raw, err := bson.Marshal(payload{Scope: scope, Values: cursor.Values, Before: cursor.Before})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw) + "." + base64.RawURLEncoding.EncodeToString(c.sign(raw)), nil
+}
+
+// Decode verifies a token created by Encode with the same scope, it returns nil for an empty token.
+func (c *Codec) Decode(scope, token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, c.sign(raw)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var p payload
+	if err = bson.Unmarshal(raw, &p); err != nil || p.Scope != scope {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{Values: p.Values, Before: p.Before}, nil
+}
+
+func (c *Codec) sign(raw []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(raw)
+	return mac.Sum(nil)
+}
+
+// Scope names a listing in a particular order, for example "posts:-created_at,_id".
+func Scope(listing string, keys []Key) string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Field
+		if key.Desc {
+			names[i] = "-" + key.Field
+		}
+	}
+	return listing + ":" + strings.Join(names, ",")
+}
+
+// Seek returns the condition selecting the documents after the cursor, or before it, and the order to read them
+// in. Read one document more than the page size so Page can tell whether there are more. Without a cursor the
+// condition is nil.
+func Seek(keys []Key, cursor *Cursor) (bson.M, bson.D, error) {
+	before := cursor != nil && cursor.Before
+	sort := bson.D{}
+	for _, key := range keys {
+		direction := 1
+		if key.Desc != before {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: key.Field, Value: direction})
+	}
+	if cursor == nil {
+		return nil, sort, nil
+	}
+	if len(cursor.Values) != len(keys) {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	// (a > x) or (a = x and b > y) or (a = x and b = y and _id > z), each comparison following its key's direction
+	or := bson.A{}
+	for i, key := range keys {
+		clause := bson.M{}
+		for j := 0; j < i; j++ {
+			clause[keys[j].Field] = cursor.Values[j]
+		}
+		op := "$gt"
+		if key.Desc != before {
+			op = "$lt"
+		}
+		clause[key.Field] = bson.M{op: cursor.Values[i]}
+		or = append(or, clause)
+	}
+	return bson.M{"$or": or}, sort, nil
+}
+
+// Page trims the documents read after Seek to limit, puts them back in listing order when reading backwards and
+// returns the cursors of the neighbouring pages, nil where there is none. values returns the sort key values of
+// a document in the order of the keys passed to Seek.
+func Page[T any](docs []T, limit int, cursor *Cursor, values func(T) []interface{}) ([]T, *Cursor, *Cursor) {
+	before := cursor != nil && cursor.Before
+	more := len(docs) > limit
+	if more {
+		docs = docs[:limit]
+	}
+	if before {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+	}
+	if len(docs) == 0 {
+		return docs, nil, nil
+	}
+
+	var next, prev *Cursor
+	// coming from a cursor there is always a page on the side it was taken from
+	if more || before {
+		next = &Cursor{Values: values(docs[len(docs)-1])}
+	}
+	if more && before || cursor != nil && !before {
+		prev = &Cursor{Values: values(docs[0]), Before: true}
+	}
+	return docs, next, prev
+}
@@ -0,0 +1,31 @@
+package render
+
+import (
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+var (
+	// classes are limited to plain names, enough for the highlighter, footnotes and code languages
+	classPattern = regexp.MustCompile(`^[a-zA-Z0-9_\- ]+$`)
+	rolePattern  = regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)
+	// heading ids are slugs, which keep the letters of every script
+	headingIDPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+)
+
+// newPolicy allows the elements user generated content needs and nothing that runs code: no scripts, styles,
+// event handlers, frames or forms. Links can only point to http, https and mailto URLs and get rel=nofollow.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(classPattern).OnElements("code", "pre", "span", "div", "a", "sup", "li")
+	p.AllowAttrs("role").Matching(rolePattern).OnElements("a", "div", "sup", "section")
+	p.AllowAttrs("id").Matching(headingIDPattern).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	// the read-only checkboxes of task lists
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").Matching(regexp.MustCompile(`^$`)).OnElements("input")
+	p.AllowElements("section")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireNoFollowOnLinks(true)
+	return p
+}
@@ -0,0 +1,138 @@
+// Package render turns post content into safe HTML. Markdown follows CommonMark with the GitHub extensions,
+// footnotes, heading anchors and highlighted code blocks, and every format goes through the same allow-list
+// sanitizer so stored content can never inject scripts.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"blog-platform/internal/utils"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// Content formats.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPlain    = "plain"
+)
+
+// Version changes whenever the rendered HTML of the same source, its Summary or its PlainText changes, posts
+// rendered by an older version are rendered again by the reindex command.
+const Version = 4
+
+// HighlightStyle is the chroma style of the stylesheet returned by HighlightCSS.
+const HighlightStyle = "github"
+
+// IsValidFormat reports whether format is one of the content formats.
+func IsValidFormat(format string) bool {
+	return format == FormatMarkdown || format == FormatHTML || format == FormatPlain
+}
+
+var blankLines = regexp.MustCompile(`\n\s*\n`)
+
+// Renderer renders and sanitizes content, it is safe for concurrent use.
+type Renderer struct {
+	markdown goldmark.Markdown
+	policy   *bluemonday.Policy
+}
+
+func New() *Renderer {
+	markdown := goldmark.New(
+		goldmark.WithExtensions(
+			extension.GFM,
+			extension.Footnote,
+			highlighting.NewHighlighting(
+				highlighting.WithStyle(HighlightStyle),
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		// raw HTML is kept here and cleaned by the sanitizer like every other format
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+	return &Renderer{markdown: markdown, policy: newPolicy()}
+}
+
+// Render returns the sanitized HTML of the source written in the format.
+func (r *Renderer) Render(format, source string) (string, error) {
+	var out string
+	switch format {
+	case FormatMarkdown:
+		var buf bytes.Buffer
+		ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+		if err := r.markdown.Convert([]byte(source), &buf, parser.WithContext(ctx)); err != nil {
+			return "", err
+		}
+		out = buf.String()
+	case FormatHTML:
+		out = source
+	case FormatPlain:
+		out = plainToHTML(source)
+	default:
+		return "", fmt.Errorf("unknown content format %q", format)
+	}
+	return r.policy.Sanitize(out), nil
+}
+
+// headingIDs gives the headings of a document the slug of their text as id, so headings in any script get a
+// readable anchor. A heading without letters or digits gets "heading", a repeated id a number.
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: map[string]bool{}}
+}
+
+func (h *headingIDs) Generate(value []byte, _ ast.NodeKind) []byte {
+	base := utils.Slugify(string(value))
+	if base == "" {
+		base = "heading"
+	}
+	id := base
+	for n := 1; h.used[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	h.used[id] = true
+	return []byte(id)
+}
+
+func (h *headingIDs) Put(value []byte) {
+	h.used[string(value)] = true
+}
+
+// plainToHTML escapes the text, blank lines separate paragraphs and single line breaks are kept.
+func plainToHTML(text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), "\r\n", "\n")
+	var b strings.Builder
+	for _, paragraph := range blankLines.Split(text, -1) {
+		if paragraph = strings.TrimSpace(paragraph); paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// HighlightCSS returns the stylesheet for the classes of highlighted code blocks.
+func HighlightCSS() (string, error) {
+	var buf bytes.Buffer
+	err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&buf, styles.Get(HighlightStyle))
+	return buf.String(), err
+}
@@ -0,0 +1,164 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// WordsPerMinute is the reading speed behind Summary.ReadingTime.
+	WordsPerMinute = 200
+	// ExcerptLength limits generated excerpts in characters.
+	ExcerptLength = 280
+)
+
+// Heading is an entry of the table of contents, ID is the anchor of the heading.
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// Summary describes rendered content without its body.
+type Summary struct {
+	Excerpt     string
+	WordCount   int
+	ReadingTime int // minutes, rounded up
+	Headings    []Heading
+}
+
+// Summarize derives the summary from HTML returned by Render. The excerpt is the text of the first paragraph,
+// shortened to ExcerptLength at a word boundary.
+func Summarize(contentHTML string) Summary {
+	var summary Summary
+	doc, err := html.Parse(strings.NewReader(contentHTML))
+	if err != nil {
+		return summary
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			summary.WordCount += len(strings.FieldsFunc(n.Data, isSeparator))
+		case n.Type == html.ElementNode && headingLevel(n.DataAtom) > 0:
+			if id := attr(n, "id"); id != "" {
+				summary.Headings = append(summary.Headings, Heading{Level: headingLevel(n.DataAtom), ID: id, Text: text(n)})
+			}
+		case n.Type == html.ElementNode && n.DataAtom == atom.P && summary.Excerpt == "":
+			summary.Excerpt = shorten(text(n), ExcerptLength)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if summary.WordCount > 0 {
+		summary.ReadingTime = (summary.WordCount + WordsPerMinute - 1) / WordsPerMinute
+	}
+	return summary
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) && r != '\'' && r != '-'
+}
+
+func headingLevel(a atom.Atom) int {
+	switch a {
+	case atom.H1:
+		return 1
+	case atom.H2:
+		return 2
+	case atom.H3:
+		return 3
+	case atom.H4:
+		return 4
+	case atom.H5:
+		return 5
+	case atom.H6:
+		return 6
+	}
+	return 0
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// text returns the text of the node with its white space collapsed, footnote references are left out.
+func text(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.DataAtom == atom.Sup:
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// PlainText returns the text of HTML returned by Render, as searched and highlighted in search results. Block
+// elements are separated by spaces, white space is collapsed and footnote references and back links are left out.
+func PlainText(contentHTML string) string {
+	doc, err := html.Parse(strings.NewReader(contentHTML))
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && (n.DataAtom == atom.Sup || attr(n, "role") == "doc-backlink"):
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+		if n.Type == html.ElementNode && isBlock(n.DataAtom) {
+			b.WriteByte(' ')
+		}
+	}
+	collect(doc)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// isBlock reports whether the element ends a run of text, so the words before and after it are not joined.
+func isBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Br, atom.Hr, atom.Li, atom.Dt, atom.Dd, atom.Blockquote, atom.Pre, atom.Figcaption,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Td, atom.Th, atom.Tr, atom.Img:
+		return true
+	}
+	return false
+}
+
+// shorten cuts s to at most limit characters at a word boundary and marks the cut with an ellipsis.
+func shorten(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	cut := string([]rune(s)[:limit-1])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
@@ -0,0 +1,229 @@
+// Package blevesearch keeps an embedded Bleve index of the posts on disk, documents have to be indexed
+// on every post write.
+package blevesearch
+
+import (
+	"blog-platform/internal/search"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/mapping"
+	bleveSearch "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+type Index struct {
+	index  bleve.Index
+	boosts search.Boosts
+	// Created is set when the index did not exist yet and has to be filled.
+	Created bool
+}
+
+// Open opens the index at path, creating it when missing.
+func Open(path string, boosts search.Boosts) (*Index, error) {
+	index, err := bleve.Open(path)
+	if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+		index, err = bleve.New(path, newMapping())
+		return &Index{index: index, boosts: boosts, Created: true}, err
+	}
+	return &Index{index: index, boosts: boosts}, err
+}
+
+func newMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = en.AnalyzerName
+	text.Store = false
+	text.IncludeTermVectors = true
+
+	keywordField := bleve.NewKeywordFieldMapping()
+	keywordField.Analyzer = keyword.Name
+
+	date := bleve.NewDateTimeFieldMapping()
+
+	post := bleve.NewDocumentStaticMapping()
+	post.AddFieldMappingsAt("title", storedText(text))
+	post.AddFieldMappingsAt("content", storedText(text))
+	post.AddFieldMappingsAt("tags", keywordField)
+	post.AddFieldMappingsAt("author", keywordField)
+	post.AddFieldMappingsAt("month", keywordField)
+	post.AddFieldMappingsAt("created_at", date)
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = post
+	return m
+}
+
+// storedText stores the field as well, the highlighter works on stored values.
+func storedText(text *mapping.FieldMapping) *mapping.FieldMapping {
+	stored := *text
+	stored.Store = true
+	return &stored
+}
+
+func (i *Index) Index(_ context.Context, doc search.Document) error {
+	return i.index.Index(doc.ID, map[string]interface{}{
+		"title":      doc.Title,
+		"content":    doc.Content,
+		"tags":       doc.Tags,
+		"author":     doc.Author,
+		"month":      doc.Month(),
+		"created_at": doc.CreatedAt,
+	})
+}
+
+func (i *Index) Delete(_ context.Context, id string) error {
+	return i.index.Delete(id)
+}
+
+// Reset deletes every document, the index itself is kept open.
+func (i *Index) Reset(ctx context.Context) error {
+	for {
+		req := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), 1000, 0, false)
+		res, err := i.index.SearchInContext(ctx, req)
+		if err != nil {
+			return err
+		}
+		if len(res.Hits) == 0 {
+			return nil
+		}
+		batch := i.index.NewBatch()
+		for _, hit := range res.Hits {
+			batch.Delete(hit.ID)
+		}
+		if err = i.index.Batch(batch); err != nil {
+			return err
+		}
+	}
+}
+
+func (i *Index) Close() error {
+	return i.index.Close()
+}
+
+func (i *Index) Search(ctx context.Context, q search.Query) (search.Result, error) {
+	var must, should []query.Query
+	for _, t := range q.Terms {
+		switch {
+		case t.Phrase:
+			must = append(must, i.anyField(func(field string) query.Query {
+				phrase := bleve.NewMatchPhraseQuery(t.Text)
+				phrase.SetField(field)
+				return phrase
+			}))
+		case t.Prefix:
+			must = append(must, i.anyField(func(field string) query.Query {
+				prefix := bleve.NewPrefixQuery(t.Text)
+				prefix.SetField(field)
+				return prefix
+			}))
+		default:
+			should = append(should, i.anyField(func(field string) query.Query {
+				match := bleve.NewMatchQuery(t.Text)
+				match.SetField(field)
+				return match
+			}))
+		}
+	}
+	for _, tag := range q.Tags {
+		must = append(must, termQuery("tags", tag))
+	}
+	if q.Author != "" {
+		must = append(must, termQuery("author", q.Author))
+	}
+	if q.From != nil || q.To != nil {
+		inclusive, exclusive := true, false
+		dates := bleve.NewDateRangeInclusiveQuery(timeOrZero(q.From), timeOrZero(q.To), &inclusive, &exclusive)
+		dates.SetField("created_at")
+		must = append(must, dates)
+	}
+
+	var root query.Query = bleve.NewMatchAllQuery()
+	if len(must) > 0 || len(should) > 0 {
+		boolean := bleve.NewBooleanQuery()
+		boolean.AddMust(must...)
+		boolean.AddShould(should...)
+		if len(should) > 0 {
+			boolean.SetMinShould(1)
+		}
+		root = boolean
+	}
+
+	req := bleve.NewSearchRequestOptions(root, q.Limit, q.Offset, false)
+	if len(q.Terms) == 0 {
+		req.SortBy([]string{"-created_at"})
+	} else {
+		req.SortBy([]string{"-_score", "-created_at"})
+		req.Highlight = bleve.NewHighlightWithStyle(html.Name)
+		req.Highlight.AddField("title")
+		req.Highlight.AddField("content")
+	}
+	req.AddFacet("tags", bleve.NewFacetRequest("tags", search.FacetSize))
+	req.AddFacet("authors", bleve.NewFacetRequest("author", search.FacetSize))
+	req.AddFacet("months", bleve.NewFacetRequest("month", search.FacetSize))
+
+	res, err := i.index.SearchInContext(ctx, req)
+	if err != nil {
+		return search.Result{}, err
+	}
+
+	out := search.Result{
+		Total: int64(res.Total),
+		Facets: search.Facets{
+			Tags:    facetCounts(res.Facets["tags"]),
+			Authors: facetCounts(res.Facets["authors"]),
+			Months:  facetCounts(res.Facets["months"]),
+		},
+	}
+	for _, h := range res.Hits {
+		hit := search.Hit{ID: h.ID, Score: h.Score, Highlights: map[string][]string{}}
+		for field, fragments := range h.Fragments {
+			// fields without a match come back whole and unmarked
+			for _, f := range fragments {
+				if strings.Contains(f, "<mark>") {
+					hit.Highlights[field] = append(hit.Highlights[field], f)
+				}
+			}
+		}
+		out.Hits = append(out.Hits, hit)
+	}
+	return out, nil
+}
+
+// anyField matches the query in title, content or tags, boosting title and tag matches.
+func (i *Index) anyField(build func(field string) query.Query) query.Query {
+	title := build("title")
+	title.(query.BoostableQuery).SetBoost(i.boosts.Title)
+	tags := build("tags")
+	tags.(query.BoostableQuery).SetBoost(i.boosts.Tags)
+	return bleve.NewDisjunctionQuery(title, build("content"), tags)
+}
+
+func termQuery(field, term string) query.Query {
+	q := bleve.NewTermQuery(term)
+	q.SetField(field)
+	return q
+}
+
+func facetCounts(facet *bleveSearch.FacetResult) []search.FacetCount {
+	counts := []search.FacetCount{}
+	if facet == nil || facet.Terms == nil {
+		return counts
+	}
+	for _, t := range facet.Terms.Terms() {
+		counts = append(counts, search.FacetCount{Value: t.Term, Count: int64(t.Count)})
+	}
+	return counts
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
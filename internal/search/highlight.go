@@ -0,0 +1,122 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	fragmentContext = 60
+	maxFragments    = 3
+)
+
+type span struct{ start, end int }
+
+// Highlight returns up to three HTML escaped fragments of text around the words matching the terms, the
+// matches wrapped in <mark>. Plain words also match longer words they start, a rough stand-in for the
+// stemming of the backends.
+func Highlight(text string, terms []Term) []string {
+	matches := matchSpans(text, terms)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var fragments []string
+	for i := 0; i < len(matches) && len(fragments) < maxFragments; {
+		start := wordBoundary(text, matches[i].start-fragmentContext, false)
+		end := wordBoundary(text, matches[i].end+fragmentContext, true)
+		// matches close to each other share a fragment
+		j := i
+		for j+1 < len(matches) && matches[j+1].start < end {
+			j++
+			end = max(end, wordBoundary(text, matches[j].end+fragmentContext, true))
+		}
+
+		var b strings.Builder
+		if start > 0 {
+			b.WriteString("…")
+		}
+		pos := start
+		for _, m := range matches[i : j+1] {
+			b.WriteString(html.EscapeString(text[pos:m.start]))
+			b.WriteString("<mark>" + html.EscapeString(text[m.start:m.end]) + "</mark>")
+			pos = m.end
+		}
+		b.WriteString(html.EscapeString(text[pos:end]))
+		if end < len(text) {
+			b.WriteString("…")
+		}
+		fragments = append(fragments, strings.TrimSpace(b.String()))
+		i = j + 1
+	}
+	return fragments
+}
+
+// matchSpans finds the byte ranges of text matching a term, in order and without overlaps.
+func matchSpans(text string, terms []Term) []span {
+	words := wordSpans(text)
+	lower := make([]string, len(words))
+	for i, w := range words {
+		lower[i] = strings.ToLower(text[w.start:w.end])
+	}
+
+	var matches []span
+	for i := 0; i < len(words); i++ {
+		for _, t := range terms {
+			n := 1
+			if t.Phrase {
+				phrase := strings.Fields(t.Text)
+				n = len(phrase)
+				if i+n > len(words) || strings.Join(lower[i:i+n], " ") != strings.Join(phrase, " ") {
+					continue
+				}
+			} else if !strings.HasPrefix(lower[i], t.Text) {
+				continue
+			}
+			matches = append(matches, span{words[i].start, words[i+n-1].end})
+			i += n - 1
+			break
+		}
+	}
+	return matches
+}
+
+func wordSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// wordBoundary moves pos to the closest space in the given direction so fragments do not cut words.
+func wordBoundary(text string, pos int, forward bool) int {
+	if pos <= 0 {
+		return 0
+	}
+	if pos >= len(text) {
+		return len(text)
+	}
+	if forward {
+		if i := strings.IndexAny(text[pos:], " \n\t"); i >= 0 {
+			return pos + i
+		}
+		return len(text)
+	}
+	if i := strings.LastIndexAny(text[:pos], " \n\t"); i >= 0 {
+		return i + 1
+	}
+	return 0
+}
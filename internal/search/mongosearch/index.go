@@ -0,0 +1,202 @@
+// Package mongosearch searches the posts collection through a MongoDB text index. The collection is the
+// index, so documents do not have to be indexed separately, the content is searched in the content_text field
+// the post service stores with every post.
+package mongosearch
+
+import (
+	"blog-platform/internal/search"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	collectionName = "posts"
+	textIndexName  = "posts_text"
+)
+
+type Index struct {
+	db     *mongo.Collection
+	boosts search.Boosts
+}
+
+func New(db *mongo.Database, boosts search.Boosts) *Index {
+	return &Index{db: db.Collection(collectionName), boosts: boosts}
+}
+
+func (i *Index) Index(context.Context, search.Document) error { return nil }
+
+func (i *Index) Delete(context.Context, string) error { return nil }
+
+func (i *Index) Close() error { return nil }
+
+// Reset recreates the text index, weights can only be changed that way.
+func (i *Index) Reset(ctx context.Context) error {
+	if _, err := i.db.Indexes().DropOne(ctx, textIndexName); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	return i.EnsureIndexes(ctx)
+}
+
+func (i *Index) EnsureIndexes(ctx context.Context) error {
+	_, err := i.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "title", Value: "text"}, {Key: "content_text", Value: "text"}, {Key: "tags", Value: "text"}},
+		Options: options.Index().SetName(textIndexName).SetWeights(bson.M{
+			"title":        weight(i.boosts.Title),
+			"content_text": 1,
+			"tags":         weight(i.boosts.Tags),
+		}),
+	})
+	return err
+}
+
+func (i *Index) Search(ctx context.Context, q search.Query) (search.Result, error) {
+	filter := bson.M{"deleted_at": bson.M{"$exists": false}, "published_at": bson.M{"$exists": true}}
+	var and bson.A
+	var textSearch []string
+	for _, t := range q.Terms {
+		switch {
+		case t.Phrase:
+			textSearch = append(textSearch, `"`+strings.ReplaceAll(t.Text, `"`, "")+`"`)
+		case t.Prefix:
+			// the text index has no prefix queries, the words have to start with the prefix
+			pattern := primitive.Regex{Pattern: `(^|[^\p{L}\p{N}])` + regexp.QuoteMeta(t.Text), Options: "i"}
+			and = append(and, bson.M{"$or": bson.A{
+				bson.M{"title": pattern}, bson.M{"content_text": pattern}, bson.M{"tags": pattern},
+			}})
+		default:
+			textSearch = append(textSearch, t.Text)
+		}
+	}
+	hasText := len(textSearch) > 0
+	if hasText {
+		filter["$text"] = bson.M{"$search": strings.Join(textSearch, " ")}
+	}
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+	if len(q.Tags) > 0 {
+		filter["tags"] = bson.M{"$all": q.Tags}
+	}
+	if q.Author != "" {
+		filter["author.username"] = q.Author
+	}
+	if q.From != nil || q.To != nil {
+		created := bson.M{}
+		if q.From != nil {
+			created["$gte"] = q.From
+		}
+		if q.To != nil {
+			created["$lt"] = q.To
+		}
+		filter["created_at"] = created
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	sort := bson.D{{Key: "created_at", Value: -1}}
+	if hasText {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"score": bson.M{"$meta": "textScore"}}}})
+		sort = bson.D{{Key: "score", Value: -1}, {Key: "created_at", Value: -1}}
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.M{
+		"hits": bson.A{
+			bson.M{"$sort": sort},
+			bson.M{"$skip": q.Offset},
+			bson.M{"$limit": q.Limit},
+			bson.M{"$project": bson.M{"title": 1, "content_text": 1, "score": 1}},
+		},
+		"total":   bson.A{bson.M{"$count": "n"}},
+		"tags":    facet("$tags", true),
+		"authors": facet("$author.username", false),
+		"months":  facet(bson.M{"$dateToString": bson.M{"format": "%Y-%m", "date": "$created_at"}}, false),
+	}}})
+
+	cursor, err := i.db.Aggregate(ctx, pipeline)
+	if err != nil {
+		return search.Result{}, err
+	}
+	var out []struct {
+		Hits []struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			Title   string             `bson:"title"`
+			Content string             `bson:"content_text"`
+			Score   float64            `bson:"score"`
+		} `bson:"hits"`
+		Total   []struct{ N int64 } `bson:"total"`
+		Tags    []facetRow          `bson:"tags"`
+		Authors []facetRow          `bson:"authors"`
+		Months  []facetRow          `bson:"months"`
+	}
+	if err = cursor.All(ctx, &out); err != nil {
+		return search.Result{}, err
+	}
+	if len(out) == 0 {
+		return search.Result{}, fmt.Errorf("search: empty facet result")
+	}
+
+	res := search.Result{Facets: search.Facets{
+		Tags:    facetCounts(out[0].Tags),
+		Authors: facetCounts(out[0].Authors),
+		Months:  facetCounts(out[0].Months),
+	}}
+	if len(out[0].Total) > 0 {
+		res.Total = out[0].Total[0].N
+	}
+	for _, h := range out[0].Hits {
+		hit := search.Hit{ID: h.ID.Hex(), Score: h.Score, Highlights: map[string][]string{}}
+		if fragments := search.Highlight(h.Title, q.Terms); fragments != nil {
+			hit.Highlights["title"] = fragments
+		}
+		if fragments := search.Highlight(h.Content, q.Terms); fragments != nil {
+			hit.Highlights["content"] = fragments
+		}
+		res.Hits = append(res.Hits, hit)
+	}
+	return res, nil
+}
+
+type facetRow struct {
+	Value string `bson:"_id"`
+	Count int64  `bson:"count"`
+}
+
+// facet counts the matching posts per value of the expression, unwinding arrays first.
+func facet(expr interface{}, unwind bool) bson.A {
+	stages := bson.A{}
+	if unwind {
+		stages = append(stages, bson.M{"$unwind": expr})
+	}
+	return append(stages,
+		bson.M{"$group": bson.M{"_id": expr, "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": search.FacetSize},
+	)
+}
+
+func facetCounts(rows []facetRow) []search.FacetCount {
+	counts := make([]search.FacetCount, 0, len(rows))
+	for _, r := range rows {
+		counts = append(counts, search.FacetCount{Value: r.Value, Count: r.Count})
+	}
+	return counts
+}
+
+// weight converts a boost into a text index weight, which has to be a positive integer.
+func weight(boost float64) int {
+	if boost < 1 {
+		return 1
+	}
+	return int(boost + 0.5)
+}
+
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Code == 27
+}
@@ -0,0 +1,124 @@
+// Package search defines the full-text index of posts and the query syntax shared by its backends.
+package search
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Index is a full-text index of posts. Backends that search the posts collection itself may treat
+// Index and Delete as no-ops.
+type Index interface {
+	Index(ctx context.Context, doc Document) error
+	Delete(ctx context.Context, id string) error
+	Search(ctx context.Context, q Query) (Result, error)
+	// Reset prepares an empty index before every document is indexed again.
+	Reset(ctx context.Context) error
+	Close() error
+}
+
+// Document is the searchable part of a post.
+type Document struct {
+	ID        string
+	Title     string
+	Content   string
+	Tags      []string
+	Author    string
+	CreatedAt time.Time
+}
+
+// Month is the value of the date facet a document counts towards.
+func (d Document) Month() string {
+	return d.CreatedAt.UTC().Format("2006-01")
+}
+
+// Boosts weigh matches in the title and tags against matches in the content, which have a weight of 1.
+type Boosts struct {
+	Title float64
+	Tags  float64
+}
+
+type Query struct {
+	Terms  []Term
+	Tags   []string
+	Author string
+	From   *time.Time
+	To     *time.Time
+	Offset int
+	Limit  int
+}
+
+// Term is a word, a quoted phrase or a word ending in * matching every word it starts.
+// Phrases and prefixes are required, of the plain words at least one has to match.
+type Term struct {
+	Text   string
+	Phrase bool
+	Prefix bool
+}
+
+type Result struct {
+	Total  int64
+	Hits   []Hit
+	Facets Facets
+}
+
+// Hit is a matching post, Highlights holds HTML escaped fragments per field with the matches wrapped in <mark>.
+type Hit struct {
+	ID         string
+	Score      float64
+	Highlights map[string][]string
+}
+
+type Facets struct {
+	Tags    []FacetCount `json:"tags"`
+	Authors []FacetCount `json:"authors"`
+	Months  []FacetCount `json:"months"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// FacetSize is the number of values returned per facet.
+const FacetSize = 20
+
+// ParseQuery splits a query into terms: "quoted text" is a phrase and a trailing * makes a prefix.
+// Terms are lower cased and an unterminated quote runs to the end of the query.
+func ParseQuery(q string) []Term {
+	var terms []Term
+	q = strings.ToLower(q)
+	for q != "" {
+		q = strings.TrimLeft(q, " \t\r\n")
+		if q == "" {
+			break
+		}
+
+		if q[0] == '"' {
+			phrase, rest, _ := strings.Cut(q[1:], `"`)
+			if words := strings.Fields(phrase); len(words) > 1 {
+				terms = append(terms, Term{Text: strings.Join(words, " "), Phrase: true})
+			} else if len(words) == 1 {
+				terms = append(terms, Term{Text: words[0]})
+			}
+			q = rest
+			continue
+		}
+
+		end := strings.IndexAny(q, " \t\r\n\"")
+		if end < 0 {
+			end = len(q)
+		}
+		word := q[:end]
+		q = q[end:]
+		if text, prefix := strings.CutSuffix(word, "*"); prefix {
+			if text = strings.TrimRight(text, "*"); text != "" {
+				terms = append(terms, Term{Text: text, Prefix: true})
+			}
+		} else if word != "" {
+			terms = append(terms, Term{Text: word})
+		}
+	}
+	return terms
+}
@@ -0,0 +1,67 @@
+// Package site knows the paths of the public pages of the blog, the reading pages, feeds and sitemaps, so that
+// links to them agree wherever they are made.
+package site
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HomePath is the front page of the blog.
+const HomePath = "/"
+
+// PostPath is the page of a post.
+func PostPath(username, slug string) string {
+	return AuthorPath(username) + "/" + url.PathEscape(slug)
+}
+
+// AuthorPath is the page listing the posts of an author.
+func AuthorPath(username string) string {
+	return "/authors/" + url.PathEscape(username)
+}
+
+// TagPath is the page listing the posts with a tag.
+func TagPath(tag string) string {
+	return "/tags/" + url.PathEscape(tag)
+}
+
+// SearchPath is the search page, the query goes into the q parameter.
+const SearchPath = "/search"
+
+// AssetPath is a file of the static assets of the theme, such as its stylesheet.
+func AssetPath(name string) string {
+	return "/theme/" + strings.TrimPrefix(name, "/")
+}
+
+// FeedPath is the feed of all posts in a format, rss, atom or json.
+func FeedPath(format string) string {
+	return "/feeds/posts." + format
+}
+
+// AuthorFeedPath is the feed of the posts of an author.
+func AuthorFeedPath(username, format string) string {
+	return "/feeds" + AuthorPath(username) + "/posts." + format
+}
+
+// TagFeedPath is the feed of the posts with a tag.
+func TagFeedPath(tag, format string) string {
+	return "/feeds" + TagPath(tag) + "/posts." + format
+}
+
+// SitemapIndexPath is the sitemap index, listing the sitemaps.
+const SitemapIndexPath = "/sitemap.xml"
+
+// SitemapPath is a page of the sitemap of a kind of page, posts or authors, counting from 1.
+func SitemapPath(kind string, page int) string {
+	return "/sitemaps/" + kind + "-" + strconv.Itoa(page) + ".xml"
+}
+
+// PagedPath is a page, counting from 1, of the list of posts at path in the static export, which can not page
+// with cursors. The first page is the list itself.
+func PagedPath(path string, page int) string {
+	if page <= 1 {
+		return path
+	}
+	return strings.TrimSuffix(path, "/") + "/page/" + strconv.Itoa(page)
+}
@@ -0,0 +1,70 @@
+// Package sitemap writes sitemaps and sitemap indexes as described at https://www.sitemaps.org/protocol.html.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// MaxURLs is the most URLs a sitemap, or sitemaps an index, may list.
+const MaxURLs = 50000
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL is a page listed in a sitemap, or a sitemap listed in an index. LastModified is left out when zero.
+type URL struct {
+	Loc          string
+	LastModified time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	URLs    []entry  `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	XMLNS    string   `xml:"xmlns,attr"`
+	Sitemaps []entry  `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// WriteSitemap writes a sitemap listing the pages.
+func WriteSitemap(w io.Writer, urls []URL) error {
+	return write(w, urlSet{XMLNS: namespace, URLs: entries(urls)})
+}
+
+// WriteIndex writes a sitemap index listing the sitemaps.
+func WriteIndex(w io.Writer, sitemaps []URL) error {
+	return write(w, sitemapIndex{XMLNS: namespace, Sitemaps: entries(sitemaps)})
+}
+
+func entries(urls []URL) []entry {
+	out := make([]entry, len(urls))
+	for i, u := range urls {
+		out[i].Loc = u.Loc
+		if !u.LastModified.IsZero() {
+			out[i].LastMod = u.LastModified.UTC().Format(time.RFC3339)
+		}
+	}
+	return out
+}
+
+func write(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
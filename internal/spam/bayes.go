@@ -0,0 +1,108 @@
+package spam
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// MinTraining is the number of spam and of ham texts Bayes needs before it scores anything but 0.5.
+const MinTraining = 5
+
+// Counts is the training state of Bayes: how many spam and ham texts were learned and in how many of
+// each class every token occurred.
+type Counts struct {
+	SpamDocs int64
+	HamDocs  int64
+	Tokens   map[string]TokenCount
+}
+
+type TokenCount struct {
+	Spam int64
+	Ham  int64
+}
+
+// Store persists the training state, AddCounts adds delta to the document count of the class and to the
+// count of every token in it.
+type Store interface {
+	LoadCounts(ctx context.Context) (Counts, error)
+	AddCounts(ctx context.Context, tokens []string, spam bool, delta int) error
+}
+
+// Bayes is a naive Bayes classifier over the tokens of a text. It keeps the counts in memory and writes
+// every change through to the store.
+type Bayes struct {
+	store Store
+
+	mu     sync.RWMutex
+	counts Counts
+}
+
+// NewBayes loads the training state from the store.
+func NewBayes(ctx context.Context, store Store) (*Bayes, error) {
+	counts, err := store.LoadCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if counts.Tokens == nil {
+		counts.Tokens = map[string]TokenCount{}
+	}
+	return &Bayes{store: store, counts: counts}, nil
+}
+
+// Score combines the spam probabilities of the tokens, tokens never seen during training are ignored.
+func (b *Bayes) Score(_ context.Context, text string) (float64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	spamDocs, hamDocs := float64(b.counts.SpamDocs), float64(b.counts.HamDocs)
+	if spamDocs < MinTraining || hamDocs < MinTraining {
+		return 0.5, nil
+	}
+
+	// log odds of spam, every probability is smoothed so a single token can not decide on its own
+	logOdds := math.Log((spamDocs + 1) / (hamDocs + 1))
+	for _, token := range Tokenize(text) {
+		count, ok := b.counts.Tokens[token]
+		if !ok || count.Spam+count.Ham == 0 {
+			continue
+		}
+		pSpam := (float64(count.Spam) + 1) / (spamDocs + 2)
+		pHam := (float64(count.Ham) + 1) / (hamDocs + 2)
+		logOdds += math.Log(pSpam / pHam)
+	}
+	return 1 / (1 + math.Exp(-logOdds)), nil
+}
+
+func (b *Bayes) Train(ctx context.Context, text string, spam bool) error {
+	return b.add(ctx, text, spam, 1)
+}
+
+func (b *Bayes) Untrain(ctx context.Context, text string, spam bool) error {
+	return b.add(ctx, text, spam, -1)
+}
+
+func (b *Bayes) add(ctx context.Context, text string, spam bool, delta int) error {
+	tokens := Tokenize(text)
+	if err := b.store.AddCounts(ctx, tokens, spam, delta); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if spam {
+		b.counts.SpamDocs = max(b.counts.SpamDocs+int64(delta), 0)
+	} else {
+		b.counts.HamDocs = max(b.counts.HamDocs+int64(delta), 0)
+	}
+	for _, token := range tokens {
+		count := b.counts.Tokens[token]
+		if spam {
+			count.Spam = max(count.Spam+int64(delta), 0)
+		} else {
+			count.Ham = max(count.Ham+int64(delta), 0)
+		}
+		b.counts.Tokens[token] = count
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package spam scores comments for spam. Classifier is the extension point, Bayes is the built-in naive Bayes
+// implementation learning from moderator decisions. The rule helpers count links and match blocklists.
+package spam
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Classifier scores texts from 0, certainly ham, to 1, certainly spam. Train feeds a moderator decision back,
+// Untrain takes back an earlier Train call with the same arguments when the decision is changed.
+type Classifier interface {
+	Score(ctx context.Context, text string) (float64, error)
+	Train(ctx context.Context, text string, spam bool) error
+	Untrain(ctx context.Context, text string, spam bool) error
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// CountLinks counts the URLs in the text.
+func CountLinks(text string) int {
+	return len(linkPattern.FindAllString(text, -1))
+}
+
+// MatchBlocklist returns the first blocklist term found in the text, terms match whole words ignoring case
+// and may span several words.
+func MatchBlocklist(text string, blocklist []string) (string, bool) {
+	haystack := " " + strings.Join(words(text), " ") + " "
+	for _, term := range blocklist {
+		normalized := strings.Join(words(term), " ")
+		if normalized != "" && strings.Contains(haystack, " "+normalized+" ") {
+			return term, true
+		}
+	}
+	return "", false
+}
+
+// Tokenize returns the distinct features of the text: its lower case words and the hosts it links to.
+func Tokenize(text string) []string {
+	seen := map[string]bool{}
+	var tokens []string
+	add := func(token string) {
+		if !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	for _, link := range linkPattern.FindAllString(text, -1) {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		if u, err := url.Parse(link); err == nil && u.Host != "" {
+			add("host:" + strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."))
+		}
+	}
+	for _, word := range words(linkPattern.ReplaceAllString(text, " ")) {
+		if n := len([]rune(word)); n >= 2 && n <= 30 {
+			add(word)
+		}
+	}
+	return tokens
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,122 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"blog-platform/internal/storage"
+)
+
+func newLocal(t *testing.T) (*storage.Local, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "media")
+	store, err := storage.NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestLocalPutGetDelete(t *testing.T) {
+	store, dir := newLocal(t)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "user/photo.png", strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "user", "photo.png")); err != nil {
+		t.Errorf("the blob is not stored below the directory: %v", err)
+	}
+	obj, err := store.Get(ctx, "user/photo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "png" || obj.Size != 3 || obj.ContentType != "image/png" {
+		t.Errorf("got %q, size %d, type %q", data, obj.Size, obj.ContentType)
+	}
+
+	if err = store.Delete(ctx, "user/photo.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Get(ctx, "user/photo.png"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a deleted key, got %v", err)
+	}
+	if err = store.Delete(ctx, "user/photo.png"); err != nil {
+		t.Errorf("deleting a missing key failed: %v", err)
+	}
+}
+
+func TestLocalKeysStayInsideDirectory(t *testing.T) {
+	store, dir := newLocal(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"../escape.txt", "../../escape.txt", "/etc/../escape.txt", "user/../../escape.txt"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "escape.txt")); err != nil {
+			t.Errorf("%s was not stored below the directory: %v", key, err)
+		}
+	}
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(dir), "*.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) > 0 {
+		t.Errorf("keys escaped the directory: %v", matches)
+	}
+
+	for _, key := range []string{"", "/", "..", `user\..\..\escape.txt`} {
+		if err = store.Put(ctx, key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("the key %q was accepted", key)
+		}
+		if _, err = store.Get(ctx, key); err == nil || errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("expected an invalid key error for %q, got %v", key, err)
+		}
+	}
+}
+
+func TestLocalPutChecksSize(t *testing.T) {
+	store, dir := newLocal(t)
+
+	err := store.Put(context.Background(), "user/short.pdf", strings.NewReader("abc"), 10, "application/pdf")
+	if err == nil {
+		t.Fatal("a blob shorter than its size was stored")
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "user"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("the failed upload left %d files behind", len(entries))
+	}
+}
+
+func TestLocalContentType(t *testing.T) {
+	store, _ := newLocal(t)
+	ctx := context.Background()
+
+	for key, want := range map[string]string{"a.pdf": "application/pdf", "a.webp": "image/webp", "a": "application/octet-stream"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, ""); err != nil {
+			t.Fatal(err)
+		}
+		obj, err := store.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj.Body.Close()
+		if obj.ContentType != want {
+			t.Errorf("%s has type %q, expected %q", key, obj.ContentType, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package s3test is an in-memory stand-in for an S3 compatible service, in the spirit of a local MinIO, for
+// tests and local development. It serves path style requests for the objects of a single bucket and checks
+// their signatures, both Authorization headers and presigned URLs. Nothing else of the S3 API is supported.
+package s3test
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-platform/internal/storage"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	modified    time.Time
+}
+
+type Server struct {
+	prefix string
+	bucket string
+	creds  storage.Credentials
+
+	mu      sync.RWMutex
+	objects map[string]object
+}
+
+// New creates a bucket accepting requests signed with creds. The prefix is the path the handler is mounted
+// at, empty when it is served at the root of an httptest.Server.
+func New(prefix, bucket string, creds storage.Credentials) *Server {
+	return &Server{
+		prefix:  strings.TrimRight(prefix, "/"),
+		bucket:  bucket,
+		creds:   creds,
+		objects: map[string]object{},
+	}
+}
+
+// Len returns the number of stored objects.
+func (s *Server) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.objects)
+}
+
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serve)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, s.prefix), "/"), "/")
+	if bucket != s.bucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+	if key == "" {
+		writeError(w, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+	if err := s.creds.Verify(r, time.Now()); err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.mu.Lock()
+		s.objects[key] = object{data: data, contentType: r.Header.Get("Content-Type"), modified: time.Now()}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		s.mu.RLock()
+		obj, ok := s.objects[key]
+		s.mu.RUnlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if obj.contentType != "" {
+			w.Header().Set("Content-Type", obj.contentType)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.Header().Set("Last-Modified", obj.modified.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>`+code+`</Code></Error>`)
+}
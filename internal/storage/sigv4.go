@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigAlgorithm    = "AWS4-HMAC-SHA256"
+	sigService      = "s3"
+	amzDateFormat   = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	maxPresignTTL   = 7 * 24 * time.Hour
+)
+
+// Credentials sign requests with AWS Signature Version 4, the scheme every S3 compatible service accepts.
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+}
+
+// Sign adds the Authorization header to the request. The payload is not part of the signature, the
+// connection to the service is expected to use TLS.
+func (c Credentials) Sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	signed := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	scope := c.scope(now)
+	signature := c.signature(now, scope, canonicalRequest(req, signed, req.URL.Query(), unsignedPayload))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, c.AccessKey, scope, strings.Join(signed, ";"), signature))
+}
+
+// Presign returns the URL of the request with the signature in its query, valid for ttl. Only the host is
+// signed, anyone holding the URL can send the request.
+func (c Credentials) Presign(req *http.Request, ttl time.Duration, now time.Time) string {
+	if ttl > maxPresignTTL {
+		ttl = maxPresignTTL
+	}
+	now = now.UTC()
+	scope := c.scope(now)
+
+	q := req.URL.Query()
+	q.Set("X-Amz-Algorithm", sigAlgorithm)
+	q.Set("X-Amz-Credential", c.AccessKey+"/"+scope)
+	q.Set("X-Amz-Date", now.Format(amzDateFormat))
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+	q.Set("X-Amz-Signature", c.signature(now, scope, canonicalRequest(req, []string{"host"}, q, unsignedPayload)))
+
+	u := *req.URL
+	u.RawQuery = canonicalQuery(q)
+	return u.String()
+}
+
+// Verify checks the signature of a request signed by Sign or Presign with the same credentials, it is what
+// a service does on receiving the request.
+func (c Credentials) Verify(req *http.Request, now time.Time) error {
+	q := req.URL.Query()
+	if q.Get("X-Amz-Signature") != "" {
+		return c.verifyPresigned(req, q, now)
+	}
+
+	auth := strings.TrimPrefix(req.Header.Get("Authorization"), sigAlgorithm+" ")
+	fields := map[string]string{}
+	for _, part := range strings.Split(auth, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[k] = v
+		}
+	}
+	date, err := time.Parse(amzDateFormat, req.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return errors.New("sigv4: missing or invalid X-Amz-Date")
+	}
+	if d := now.Sub(date); d > 15*time.Minute || d < -15*time.Minute {
+		return errors.New("sigv4: the request time is too skewed")
+	}
+	if fields["Credential"] != c.AccessKey+"/"+c.scope(date) {
+		return errors.New("sigv4: unknown credential")
+	}
+
+	payload := req.Header.Get("X-Amz-Content-Sha256")
+	if payload == "" {
+		payload = unsignedPayload
+	}
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	want := c.signature(date, c.scope(date), canonicalRequest(req, signed, q, payload))
+	if !hmac.Equal([]byte(want), []byte(fields["Signature"])) {
+		return errors.New("sigv4: signature mismatch")
+	}
+	return nil
+}
+
+func (c Credentials) verifyPresigned(req *http.Request, q url.Values, now time.Time) error {
+	date, err := time.Parse(amzDateFormat, q.Get("X-Amz-Date"))
+	if err != nil {
+		return errors.New("sigv4: missing or invalid X-Amz-Date")
+	}
+	expires, err := strconv.Atoi(q.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 {
+		return errors.New("sigv4: missing or invalid X-Amz-Expires")
+	}
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return errors.New("sigv4: the request has expired")
+	}
+	if q.Get("X-Amz-Credential") != c.AccessKey+"/"+c.scope(date) {
+		return errors.New("sigv4: unknown credential")
+	}
+
+	got := q.Get("X-Amz-Signature")
+	q.Del("X-Amz-Signature")
+	signed := strings.Split(q.Get("X-Amz-SignedHeaders"), ";")
+	want := c.signature(date, c.scope(date), canonicalRequest(req, signed, q, unsignedPayload))
+	if !hmac.Equal([]byte(want), []byte(got)) {
+		return errors.New("sigv4: signature mismatch")
+	}
+	return nil
+}
+
+func (c Credentials) scope(t time.Time) string {
+	return t.UTC().Format("20060102") + "/" + c.Region + "/" + sigService + "/aws4_request"
+}
+
+func (c Credentials) signature(t time.Time, scope, canonical string) string {
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := sigAlgorithm + "\n" + t.UTC().Format(amzDateFormat) + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretKey), t.UTC().Format("20060102"))
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, sigService)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func canonicalRequest(req *http.Request, signed []string, q url.Values, payload string) string {
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.Host
+			if value == "" {
+				value = req.URL.Host
+			}
+		}
+		headers.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	return strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(q),
+		headers.String(),
+		strings.Join(signed, ";"),
+		payload,
+	}, "\n")
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode escapes everything but the unreserved characters, and slashes unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case 'A' <= ch && ch <= 'Z', 'a' <= ch && ch <= 'z', '0' <= ch && ch <= '9',
+			ch == '-', ch == '.', ch == '_', ch == '~':
+			b.WriteByte(ch)
+		case ch == '/' && !encodeSlash:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
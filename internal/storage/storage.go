@@ -0,0 +1,48 @@
+// Package storage keeps uploaded blobs, on the local filesystem or in the bucket of an S3 compatible service.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"blog-platform/config"
+)
+
+// ErrNotFound is returned for keys that hold no blob.
+var ErrNotFound = errors.New("blob not found")
+
+// Object is a stored blob, the caller closes Body.
+type Object struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+}
+
+//go:generate mockery --name=Storage --case underscore
+type Storage interface {
+	// Put stores size bytes of r at key, replacing a blob stored there before.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (Object, error)
+	// Delete removes the blob, deleting a key that holds none is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by backends that can hand out links downloading a blob directly from them, so the
+// server does not need to pass the bytes through.
+type Presigner interface {
+	PresignGet(key string, ttl time.Duration) (string, error)
+}
+
+// New returns the storage selected by cfg.Driver.
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "local", "":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
@@ -0,0 +1,236 @@
+// Package theme renders the pages of the reading site with html/template. The default theme is embedded in the
+// binary, a theme directory overrides it file by file, so a theme only has to carry the files it changes:
+//
+//	templates/layout.html    the document around every page, defines "layout" and calls "content"
+//	templates/partials.html  blocks shared by the pages, such as the post list and the pager
+//	templates/<page>.html    one per page, defines "content"
+//	static/                  stylesheets, scripts and images, served at site.AssetPath, highlight.css is generated
+//	                         from render.HighlightCSS unless the theme has one
+package theme
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"blog-platform/config"
+	repoModels "blog-platform/internal/app/repositories/models"
+	"blog-platform/internal/render"
+	"blog-platform/internal/site"
+)
+
+// Pages rendered by a theme.
+const (
+	PageHome   = "home"
+	PagePost   = "post"
+	PageAuthor = "author"
+	PageTag    = "tag"
+	PageSearch = "search"
+	PageError  = "error"
+)
+
+var pages = []string{PageHome, PagePost, PageAuthor, PageTag, PageSearch, PageError}
+
+//go:embed default
+var embedded embed.FS
+
+// Default is the embedded default theme.
+var Default, _ = fs.Sub(embedded, "default")
+
+// Theme holds the parsed templates of every page.
+type Theme struct {
+	fsys   fs.FS
+	funcs  template.FuncMap
+	reload bool
+
+	templates map[string]*template.Template
+}
+
+// Options select the theme and how it renders. Dir overrides the default theme when set, with Reload the
+// templates are parsed again for every page, so changes show without a restart. Static pages are written by
+// the static export, templates can tell with the static function and leave out what needs the server, such as
+// the search.
+type Options struct {
+	Dir    string
+	Reload bool
+	Static bool
+	Site   config.SiteConfig
+}
+
+// New loads the theme, templates that do not parse are reported right away.
+func New(opts Options) (*Theme, error) {
+	fsys := Default
+	if opts.Dir != "" {
+		info, err := os.Stat(opts.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("theme directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("theme directory: %s is not a directory", opts.Dir)
+		}
+		fsys = overlay{upper: os.DirFS(opts.Dir), lower: Default}
+	}
+	t := &Theme{fsys: fsys, funcs: funcs(opts.Site, opts.Static), reload: opts.Reload}
+	templates, err := t.parse()
+	if err != nil {
+		return nil, err
+	}
+	t.templates = templates
+	return t, nil
+}
+
+func (t *Theme) parse() (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		tmpl, err := template.New(page).Funcs(t.funcs).ParseFS(t.fsys,
+			"templates/layout.html", "templates/partials.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("theme: %w", err)
+		}
+		templates[page] = tmpl
+	}
+	return templates, nil
+}
+
+// Render writes the page with its data. Nothing is written when the template fails.
+func (t *Theme) Render(w io.Writer, page string, data interface{}) error {
+	templates := t.templates
+	if t.reload {
+		var err error
+		if templates, err = t.parse(); err != nil {
+			return err
+		}
+	}
+	tmpl, ok := templates[page]
+	if !ok {
+		return fmt.Errorf("theme: unknown page %q", page)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// highlightCSS is the asset name of the stylesheet of highlighted code, generated unless the theme brings it.
+const highlightCSS = "highlight.css"
+
+// Asset reads a static file of the theme, directories and names outside of static/ do not exist.
+func (t *Theme) Asset(name string) ([]byte, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, fs.ErrNotExist
+	}
+	name = path.Join("static", name)
+	info, err := fs.Stat(t.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) && name == path.Join("static", highlightCSS) {
+		css, err := render.HighlightCSS()
+		return []byte(css), err
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fs.ErrNotExist
+	}
+	return fs.ReadFile(t.fsys, name)
+}
+
+// Assets lists the names of the static files of the theme, sorted.
+func (t *Theme) Assets() ([]string, error) {
+	names := []string{highlightCSS}
+	err := fs.WalkDir(t.fsys, "static", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if name = strings.TrimPrefix(name, "static/"); name != highlightCSS {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// Fingerprint identifies the content of the theme, it changes whenever one of its files does.
+func (t *Theme) Fingerprint() (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(t.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(t.fsys, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil)), err
+}
+
+func funcs(siteCfg config.SiteConfig, static bool) template.FuncMap {
+	return template.FuncMap{
+		"site":      func() config.SiteConfig { return siteCfg },
+		"static":    func() bool { return static },
+		"postURL":   func(post repoModels.Post) string { return site.PostPath(post.Author.Username, post.Slug) },
+		"authorURL": site.AuthorPath,
+		"tagURL":    site.TagPath,
+		"feedURL":   site.FeedPath,
+		"searchURL": func() string { return site.SearchPath },
+		"homeURL":   func() string { return site.HomePath },
+		"asset":     site.AssetPath,
+		// safeHTML marks HTML the server sanitized, the rendered content of posts and search highlights. It leaves
+		// the html builtin of the templates, which escapes, as it is.
+		"safeHTML": func(s string) template.HTML { return template.HTML(s) },
+		"date":     func(t time.Time) string { return t.Format("January 2, 2006") },
+		"isoTime":  func(t time.Time) string { return t.UTC().Format(time.RFC3339) },
+	}
+}
+
+// overlay serves the files of upper, and the files of lower that upper does not have.
+type overlay struct {
+	upper, lower fs.FS
+}
+
+func (o overlay) Open(name string) (fs.File, error) {
+	f, err := o.upper.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.lower.Open(name)
+	}
+	return f, err
+}
+
+// ReadDir merges the entries of both, so walking the overlay finds the files of either.
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	upper, upperErr := fs.ReadDir(o.upper, name)
+	lower, lowerErr := fs.ReadDir(o.lower, name)
+	if upperErr != nil && lowerErr != nil {
+		return nil, upperErr
+	}
+	entries := map[string]fs.DirEntry{}
+	for _, e := range lower {
+		entries[e.Name()] = e
+	}
+	for _, e := range upper {
+		entries[e.Name()] = e
+	}
+	merged := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		merged = append(merged, e)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
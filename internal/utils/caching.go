@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentETag is a weak ETag derived from the bytes of a response.
+func ContentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// CachePublic sets the validators of a public response and lets caches keep it for maxAge. It reports whether
+// the request already holds this version, 304 Not Modified has been written then and nothing else should be.
+// lastModified is left out when zero.
+func CachePublic(ctx *gin.Context, etag string, lastModified time.Time, maxAge time.Duration) bool {
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	if !lastModified.IsZero() {
+		ctx.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(ctx.Request, etag, lastModified) {
+		ctx.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// notModified evaluates the conditional headers of the request, If-None-Match takes precedence over
+// If-Modified-Since as RFC 9110 asks.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ims)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"blog-platform/config"
+	"github.com/gin-gonic/gin"
+)
+
+// SetSessionCookie stores the session token in a HttpOnly cookie that lives as long as the absolute timeout.
+func SetSessionCookie(ctx *gin.Context, cfg config.SessionConfig, token string) {
+	ctx.SetSameSite(sameSite(cfg.SameSite))
+	ctx.SetCookie(cfg.CookieName, token, int(cfg.AbsoluteTimeout.Seconds()), "/", "", cfg.Secure, true)
+}
+
+func ClearSessionCookie(ctx *gin.Context, cfg config.SessionConfig) {
+	ctx.SetSameSite(sameSite(cfg.SameSite))
+	ctx.SetCookie(cfg.CookieName, "", -1, "/", "", cfg.Secure, true)
+}
+
+// OIDCLoginCookie holds the browser token of a started single sign-on login.
+const OIDCLoginCookie = "oidc_login"
+
+// SetOIDCLoginCookie stores the browser token of a single sign-on login until the login expires. It is always
+// SameSite=Lax, the provider redirects back with a cross-site navigation that a strict cookie would not survive.
+func SetOIDCLoginCookie(ctx *gin.Context, cfg config.SessionConfig, token string, expires time.Time) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(OIDCLoginCookie, token, int(time.Until(expires).Seconds()), "/", "", cfg.Secure, true)
+}
+
+func ClearOIDCLoginCookie(ctx *gin.Context, cfg config.SessionConfig) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(OIDCLoginCookie, "", -1, "/", "", cfg.Secure, true)
+}
+
+func sameSite(mode string) http.SameSite {
+	switch strings.ToLower(mode) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
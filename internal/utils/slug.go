@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	maxSlugLength = 80
+	maxTagLength  = 40
+)
+
+// transliterations spells letters in ASCII that do not decompose into a base letter and accents.
+// Scripts missing here, such as CJK, are kept as they are.
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i", 'ħ': "h",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i",
+	'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+	'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+	'э': "e", 'ю': "iu", 'я': "ia", 'є': "ie", 'і': "i", 'ї': "i", 'ґ': "g",
+	// Greek, accents are already split off by the decomposition
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i", 'κ': "k",
+	'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t",
+	'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Slugify turns a title or name into a lower case, hyphen separated URL segment. Accents are dropped,
+// Latin special letters, Cyrillic and Greek are transliterated and every other character that is not
+// a letter or digit separates words.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			ascii, ok := transliterations[r]
+			if ok && ascii == "" {
+				continue
+			}
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			if ok {
+				b.WriteString(ascii)
+			} else {
+				b.WriteRune(r)
+			}
+		default:
+			hyphen = true
+		}
+	}
+	return truncateAtHyphen(b.String(), maxSlugLength)
+}
+
+// NormalizeTag lower cases a free-form tag and joins its words with hyphens, so "Go  Lang" and "go-lang"
+// end up as the same tag. Punctuation such as the plus signs of "c++" is kept.
+func NormalizeTag(tag string) string {
+	return truncateAtHyphen(strings.Join(strings.Fields(strings.ToLower(tag)), "-"), maxTagLength)
+}
+
+// NormalizeTags normalizes the tags, dropping empty ones and duplicates while keeping the order.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+func truncateAtHyphen(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	s = string(runes[:max])
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, "-")
+}
@@ -3,12 +3,40 @@ package utils
 import (
 	"errors"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
 )
 
+var (
+	ErrNotAllowed   = errors.New("not allowed")
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
 func HandleError(ctx *gin.Context, err error) {
-	if errors.Is(err, errors.New("not allowed")) {
-		ctx.JSON(http.StatusForbidden, gin.H{"err": err.Error()})
+	status := ErrorStatus(err)
+	switch status {
+	case http.StatusForbidden:
+		ctx.JSON(status, gin.H{"err": err.Error()})
+	case http.StatusNotFound:
+		ctx.JSON(status, gin.H{"error": "not found"})
+	default:
+		ctx.JSON(status, gin.H{"error": err.Error()})
+	}
+}
+
+// ErrorStatus is the HTTP status an error is answered with.
+func ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
 	}
-	ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 }